@@ -0,0 +1,248 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// nodeParser parses the nodes of a single values file into schemas.
+//
+// YAML aliases ("*name") are parsed as the anchored node ("&name") they refer
+// to, and merge keys ("<<: *name") are expanded using the YAML 1.1 semantics.
+// When using the "anchorRefs" config, each anchored node that is referenced
+// by an alias is instead added once to "$defs", and referenced using "$ref".
+type nodeParser struct {
+	useHelmDocs bool
+	anchorRefs  bool
+	// aliased holds the anchored nodes that are referenced by an alias,
+	// other than by merge keys, when using anchorRefs.
+	aliased map[*yaml.Node]bool
+	// defs holds the "$defs" of the anchored nodes, by name, and defNames
+	// holds the name of each anchored node in defs.
+	defs     map[string]*Schema
+	defNames map[*yaml.Node]string
+	// visiting holds the anchored nodes that are being parsed, to detect
+	// aliases inside the node they refer to.
+	visiting map[*yaml.Node]bool
+	// mappings holds the mappings that are being parsed, to detect merge
+	// keys inside the mapping they refer to.
+	mappings map[*yaml.Node]bool
+	// placeholders is nil unless using the "placeholders" config.
+	placeholders *placeholderRules
+	// itemsMode is the "itemsMode" config, and draft is the "draft" config,
+	// or 0 when unknown.
+	itemsMode string
+	draft     int
+	// refAliases are used to report invalid "$ref: $name/..." annotations
+	// together with their location, or nil when unknown.
+	refAliases refAliases
+}
+
+// newNodeParser returns a parser for the values file with the given root
+// node, which is only used to find the aliased anchors when using anchorRefs.
+func newNodeParser(root *yaml.Node, useHelmDocs, anchorRefs bool) *nodeParser {
+	p := &nodeParser{
+		useHelmDocs: useHelmDocs,
+		anchorRefs:  anchorRefs,
+		aliased:     map[*yaml.Node]bool{},
+		defs:        map[string]*Schema{},
+		defNames:    map[*yaml.Node]string{},
+		visiting:    map[*yaml.Node]bool{},
+		mappings:    map[*yaml.Node]bool{},
+	}
+	if anchorRefs && root != nil {
+		collectAliased(root, p.aliased)
+	}
+	return p
+}
+
+// parseAlias parses an alias node as the anchored node it refers to, using
+// the annotations from both the anchored node and the alias.
+func (p *nodeParser) parseAlias(ptr Ptr, keyNode, aliasNode *yaml.Node) (*Schema, error) {
+	target := aliasNode.Alias
+	if p.anchorRefs {
+		return p.parseRef(ptr, keyNode, aliasNode, target)
+	}
+
+	if p.visiting[target] {
+		return nil, newNodeError(ptr, keyNode, aliasNode, fmt.Errorf("alias *%s refers to an anchor that contains it", aliasNode.Value))
+	}
+
+	schema, err := p.parse(ptr, keyNode, target)
+	if err != nil {
+		return nil, err
+	}
+	if aliasNode.LineComment != "" {
+		if err := processComment(schema, []string{aliasNode.LineComment}); err != nil {
+			return nil, newNodeError(ptr, keyNode, aliasNode, fmt.Errorf("parse @schema comments: %w", err))
+		}
+		if _, err := p.refAliases.expand(schema.Ref); err != nil {
+			return nil, newNodeError(ptr, keyNode, aliasNode, fmt.Errorf("parse @schema comments: $ref: %w", err))
+		}
+	}
+	return schema, nil
+}
+
+// parseAnchor parses an anchored node that is referenced by an alias, when
+// using anchorRefs. The annotations on the anchored value are part of its
+// "$defs" entry, while the annotations on the key only apply to the key.
+func (p *nodeParser) parseAnchor(ptr Ptr, keyNode, valNode *yaml.Node) (*Schema, error) {
+	return p.parseRef(ptr, keyNode, &yaml.Node{}, valNode)
+}
+
+// parseRef returns a schema with a "$ref" to the "$defs" entry of the
+// anchored node, using the annotations from the key and valNode.
+func (p *nodeParser) parseRef(ptr Ptr, keyNode, valNode, target *yaml.Node) (*Schema, error) {
+	name, err := p.anchorDef(ptr, target)
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{Ref: "#" + NewPtr("$defs", name).String()}
+	if err := p.processComments(ptr, keyNode, valNode, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// anchorDef returns the name of the "$defs" entry of the anchored node,
+// which is added on first use. The name is the same as the anchor, with a
+// suffix when the same anchor name is used for multiple nodes.
+func (p *nodeParser) anchorDef(ptr Ptr, node *yaml.Node) (string, error) {
+	if name, ok := p.defNames[node]; ok {
+		return name, nil
+	}
+	name := node.Anchor
+	for i := 2; p.defNameTaken(name); i++ {
+		name = node.Anchor + "-" + strconv.Itoa(i)
+	}
+	// Registered before parsing, so aliases inside the anchored node
+	// refer back to it, instead of recursing forever.
+	p.defNames[node] = name
+
+	def, err := p.parseValue(ptr, nil, node)
+	if err != nil {
+		return "", err
+	}
+	p.defs[name] = def
+	return name, nil
+}
+
+func (p *nodeParser) defNameTaken(name string) bool {
+	for _, taken := range p.defNames {
+		if taken == name {
+			return true
+		}
+	}
+	return false
+}
+
+// collectAliased adds every anchored node that is referenced by an alias to
+// the aliased set, except for aliases used as merge keys, as their keys are
+// merged into the mapping instead.
+func collectAliased(node *yaml.Node, aliased map[*yaml.Node]bool) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		aliased[node.Alias] = true
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !isMergeKey(node.Content[i]) {
+				collectAliased(node.Content[i], aliased)
+				collectAliased(node.Content[i+1], aliased)
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			collectAliased(child, aliased)
+		}
+	}
+}
+
+// yamlPair is a key and value in a YAML mapping.
+type yamlPair struct {
+	Key, Value *yaml.Node
+}
+
+// mappingPairs returns the keys and values of the mapping, where merge keys
+// ("<<") are expanded using the YAML 1.1 semantics: keys in the mapping take
+// precedence over merged keys, and when merging a list of mappings then the
+// earlier mappings take precedence over the later ones.
+func mappingPairs(node *yaml.Node) ([]yamlPair, error) {
+	return expandMergeKeys(node, map[*yaml.Node]bool{})
+}
+
+func expandMergeKeys(node *yaml.Node, visiting map[*yaml.Node]bool) ([]yamlPair, error) {
+	if visiting[node] {
+		return nil, errors.New(`merge key "<<" refers to a mapping that contains it`)
+	}
+	visiting[node] = true
+	defer delete(visiting, node)
+
+	explicit := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !isMergeKey(node.Content[i]) {
+			explicit[node.Content[i].Value] = true
+		}
+	}
+
+	pairs := make([]yamlPair, 0, len(node.Content)/2)
+	merged := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valNode := node.Content[i], node.Content[i+1]
+		if !isMergeKey(keyNode) {
+			pairs = append(pairs, yamlPair{Key: keyNode, Value: valNode})
+			continue
+		}
+
+		sources := []*yaml.Node{valNode}
+		if resolved := resolveAlias(valNode); resolved.Kind == yaml.SequenceNode {
+			sources = resolved.Content
+		}
+		for _, source := range sources {
+			source = resolveAlias(source)
+			if source.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf(`merge key "<<" must be a mapping or a list of mappings, got %s`, formatNodeKind(source))
+			}
+			sourcePairs, err := expandMergeKeys(source, visiting)
+			if err != nil {
+				return nil, err
+			}
+			for _, pair := range sourcePairs {
+				if explicit[pair.Key.Value] || merged[pair.Key.Value] {
+					continue
+				}
+				merged[pair.Key.Value] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// isMergeKey reports whether the node is a merge key ("<<"), which when
+// quoted is a regular key instead.
+func isMergeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!merge"
+}
+
+// resolveAlias returns the anchored node that an alias refers to,
+// or the node itself when it is not an alias.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func formatNodeKind(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		return "a scalar"
+	default:
+		return "an empty value"
+	}
+}
@@ -0,0 +1,322 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestBuildJSONSchema_Anchors(t *testing.T) {
+	config := &Config{Values: []string{"../testdata/anchors/values.yaml"}, Draft: 2020, Indent: 4}
+
+	t.Run("inline", func(t *testing.T) {
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), config)
+		require.NoError(t, err)
+
+		image := &Schema{Type: "object", Properties: map[string]*Schema{
+			"repository": {Type: "string"},
+			"tag":        {Type: "string"},
+		}}
+		replicas := &Schema{Type: "integer", Minimum: float64Ptr(1)}
+
+		assert.Equal(t, &Schema{Type: "object", Properties: map[string]*Schema{
+			"replicas": replicas,
+			"image":    image,
+		}}, schema.Properties["defaults"])
+
+		// Root merge key
+		assert.Equal(t, replicas, schema.Properties["replicas"])
+		assert.Equal(t, image, schema.Properties["image"])
+		assert.NotContains(t, schema.Properties, "<<")
+
+		assert.Equal(t, &Schema{Type: "object", Properties: map[string]*Schema{
+			"replicas": {Type: "integer"},
+			"image":    image,
+			"port":     {Type: "integer"},
+		}}, schema.Properties["web"])
+
+		assert.Equal(t, &Schema{Type: "object", Properties: map[string]*Schema{
+			"replicas": replicas,
+			"image":    image,
+			"queue":    {Type: "string"},
+		}}, schema.Properties["worker"])
+
+		assert.Equal(t, &Schema{
+			Type:        "object",
+			Description: "Sidecar image",
+			Properties:  image.Properties,
+		}, schema.Properties["sidecar"].Properties["image"])
+		assert.Nil(t, schema.Defs)
+	})
+
+	t.Run("anchorRefs", func(t *testing.T) {
+		config := *config
+		config.AnchorRefs = true
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		require.NoError(t, err)
+
+		// Only "image" is used by an alias, while "defaults" is only merged
+		dir, err := filepath.Abs("../testdata/anchors")
+		require.NoError(t, err)
+		referrer := ReferrerDir(dir)
+		assert.Equal(t, map[string]*Schema{
+			"image": {Type: "object", Properties: map[string]*Schema{
+				"repository": {Type: "string"},
+				"tag":        {Type: "string"},
+			}},
+		}, schema.Defs)
+
+		assert.Equal(t, &Schema{Ref: "#/$defs/image", RefReferrer: referrer}, schema.Properties["image"])
+		assert.Equal(t, &Schema{Ref: "#/$defs/image", RefReferrer: referrer}, schema.Properties["defaults"].Properties["image"])
+		assert.Equal(t, &Schema{Ref: "#/$defs/image", RefReferrer: referrer, Description: "Sidecar image"}, schema.Properties["sidecar"].Properties["image"])
+	})
+}
+
+// parseTestValues builds the schema from the values file content, and returns
+// the referrer used for its "$ref".
+func parseTestValues(t *testing.T, content string, anchorRefs bool) (*Schema, Referrer, error) {
+	t.Helper()
+	dir := t.TempDir()
+	valuesPath := filepath.Join(dir, "values.yaml")
+	require.NoError(t, os.WriteFile(valuesPath, []byte(content), 0o644))
+	schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &Config{
+		Values:     []string{valuesPath},
+		Draft:      2020,
+		Indent:     4,
+		AnchorRefs: anchorRefs,
+	})
+	return schema, ReferrerDir(dir), err
+}
+
+func TestNodeParser_Aliases(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     string
+		anchorRefs bool
+		want       map[string]*Schema
+		wantDefs   map[string]*Schema
+	}{
+		{
+			name:   "scalar",
+			values: "a: &x 1\nb: *x\n",
+			want: map[string]*Schema{
+				"a": {Type: "integer"},
+				"b": {Type: "integer"},
+			},
+		},
+		{
+			name:   "annotations from anchor and alias",
+			values: "a: &x 1 # @schema minimum: 0\nb: *x # @schema maximum: 10\n",
+			want: map[string]*Schema{
+				"a": {Type: "integer", Minimum: float64Ptr(0)},
+				"b": {Type: "integer", Minimum: float64Ptr(0), Maximum: float64Ptr(10)},
+			},
+		},
+		{
+			name:   "hidden alias",
+			values: "a: &x 1\nb: *x # @schema hidden\n",
+			want: map[string]*Schema{
+				"a": {Type: "integer"},
+			},
+		},
+		{
+			name:   "array items",
+			values: "a: &x {name: foo}\nlist: [*x, *x]\n",
+			want: map[string]*Schema{
+				"a":    {Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}},
+				"list": {Type: "array", Items: &Schema{Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}}},
+			},
+		},
+		{
+			name:       "anchorRefs",
+			values:     "a: &x 1 # @schema minimum: 0\nb: *x # @schema maximum: 10\nc: &unused 2\n",
+			anchorRefs: true,
+			want: map[string]*Schema{
+				"a": {Ref: "#/$defs/x"},
+				"b": {Ref: "#/$defs/x", Maximum: float64Ptr(10)},
+				"c": {Type: "integer"},
+			},
+			wantDefs: map[string]*Schema{
+				"x": {Type: "integer", Minimum: float64Ptr(0)},
+			},
+		},
+		{
+			name:       "anchorRefs with recursive alias",
+			values:     "a: &x [*x]\n",
+			anchorRefs: true,
+			want: map[string]*Schema{
+				"a": {Ref: "#/$defs/x"},
+			},
+			wantDefs: map[string]*Schema{
+				"x": {Type: "array", Items: &Schema{Ref: "#/$defs/x"}},
+			},
+		},
+		{
+			name:       "anchorRefs with reused anchor name",
+			values:     "a: &x 1\nb: *x\nc: &x foo\nd: *x\n",
+			anchorRefs: true,
+			want: map[string]*Schema{
+				"a": {Ref: "#/$defs/x"},
+				"b": {Ref: "#/$defs/x"},
+				"c": {Ref: "#/$defs/x-2"},
+				"d": {Ref: "#/$defs/x-2"},
+			},
+			wantDefs: map[string]*Schema{
+				"x":   {Type: "integer"},
+				"x-2": {Type: "string"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, referrer, err := parseTestValues(t, tt.values, tt.anchorRefs)
+			require.NoError(t, err)
+			for _, want := range tt.want {
+				want.SetReferrer(referrer)
+			}
+			for _, want := range tt.wantDefs {
+				want.SetReferrer(referrer)
+			}
+			assert.Equal(t, tt.want, schema.Properties)
+			assert.Equal(t, tt.wantDefs, schema.Defs)
+		})
+	}
+}
+
+func TestNodeParser_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		wantErr  string
+		wantLine int
+	}{
+		{
+			name:     "alias cycle",
+			values:   "a: &x\n  b: *x\n",
+			wantErr:  "/a/b: alias *x refers to an anchor that contains it",
+			wantLine: 2,
+		},
+		{
+			name:     "alias cycle in items",
+			values:   "a: &x [*x]\n",
+			wantErr:  "/a/0: alias *x refers to an anchor that contains it",
+			wantLine: 1,
+		},
+		{
+			name:     "merge cycle",
+			values:   "a: &x\n  b:\n    <<: *x\n",
+			wantErr:  `/a/b/b: merge key "<<" refers to a mapping that contains it`,
+			wantLine: 2,
+		},
+		{
+			name:     "merge self",
+			values:   "a: &x\n  <<: *x\n",
+			wantErr:  `/a: merge key "<<" refers to a mapping that contains it`,
+			wantLine: 1,
+		},
+		{
+			name:     "merge scalar",
+			values:   "a:\n  <<: foo\n",
+			wantErr:  `/a: merge key "<<" must be a mapping or a list of mappings, got a scalar`,
+			wantLine: 1,
+		},
+		{
+			name:     "merge list of scalars",
+			values:   "a:\n  <<: [foo]\n",
+			wantErr:  `merge key "<<" must be a mapping or a list of mappings, got a scalar`,
+			wantLine: 1,
+		},
+		{
+			name:     "root merge",
+			values:   "<<: [[]]\n",
+			wantErr:  `merge key "<<" must be a mapping or a list of mappings, got a list`,
+			wantLine: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseTestValues(t, tt.values, false)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErr)
+			var nodeErr *NodeError
+			require.ErrorAs(t, err, &nodeErr)
+			assert.Equal(t, tt.wantLine, nodeErr.Line)
+			assert.NotEmpty(t, nodeErr.File)
+		})
+	}
+}
+
+func TestMappingPairs(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		want   []string
+	}{
+		{
+			name:   "no merge keys",
+			values: "a: 1\nb: 2\n",
+			want:   []string{"a=1", "b=2"},
+		},
+		{
+			name:   "explicit keys take precedence",
+			values: "base: &base {a: 1, b: 2}\nm:\n  b: 3\n  <<: *base\n  c: 4\n",
+			want:   []string{"b=3", "a=1", "c=4"},
+		},
+		{
+			name:   "earlier mappings take precedence",
+			values: "one: &one {a: 1}\ntwo: &two {a: 2, b: 2}\nm:\n  <<: [*one, *two]\n",
+			want:   []string{"a=1", "b=2"},
+		},
+		{
+			name:   "nested merge keys",
+			values: "one: &one {a: 1}\ntwo: &two {<<: *one, b: 2}\nm:\n  <<: *two\n",
+			want:   []string{"a=1", "b=2"},
+		},
+		{
+			name:   "inline mapping",
+			values: "m:\n  <<: {a: 1}\n",
+			want:   []string{"a=1"},
+		},
+		{
+			name:   "quoted key is not a merge key",
+			values: "m:\n  \"<<\": {a: 1}\n",
+			want:   []string{"<<=map"},
+		},
+		{
+			name:   "aliased list",
+			values: "list: &list [{a: 1}]\nm:\n  <<: *list\n",
+			want:   []string{"a=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(tt.values), &node))
+			root := node.Content[0]
+			mapping := root
+			if last := root.Content[len(root.Content)-2]; last.Value == "m" {
+				mapping = root.Content[len(root.Content)-1]
+			}
+
+			pairs, err := mappingPairs(mapping)
+			require.NoError(t, err)
+			var got []string
+			for _, pair := range pairs {
+				value := resolveAlias(pair.Value)
+				if value.Kind == yaml.MappingNode {
+					got = append(got, pair.Key.Value+"=map")
+				} else {
+					got = append(got, pair.Key.Value+"="+value.Value)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
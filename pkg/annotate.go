@@ -0,0 +1,244 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// valuesInsertions holds the lines to insert into a values file, by the
+// index of the line that they are inserted before.
+type valuesInsertions map[int][]string
+
+// annotateValues returns the lines to insert into the content of a values
+// file, so that each key found in the schema's properties gets the
+// "# @schema" annotations that reproduce its subschema, as returned by
+// [ValuesFromSchema]. Annotations that a key already has are not added again,
+// where a warning is logged when the existing value differs from the schema,
+// and keys that are hidden, or that can't have a comment on the line above,
+// such as keys in flow mappings, are left as-is.
+//
+// The annotations are inserted above any helm-docs comments, as helm-docs
+// comments must come last. When useHelmDocs is set, keys with a helm-docs
+// comment only get a "description" annotation when the helm-docs description
+// differs from the schema, as the annotation then takes precedence.
+func annotateValues(ctx context.Context, schema *Schema, content []byte, useHelmDocs bool) (valuesInsertions, error) {
+	docs, err := decodeYAMLDocuments(content)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling YAML: %w", err)
+	}
+
+	w := valuesWriter{ctx: ctx, root: schema, descriptions: true}
+	root, refs, err := w.flatten(nil, schema, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	a := valuesAnnotator{
+		writer:      w,
+		lines:       strings.Split(string(content), "\n"),
+		useHelmDocs: useHelmDocs,
+		insertions:  valuesInsertions{},
+	}
+	for _, doc := range docs {
+		if err := a.mapping(nil, doc.Content[0], root, refs); err != nil {
+			return nil, err
+		}
+	}
+	return a.insertions, nil
+}
+
+// valuesAnnotator collects the insertions of [annotateValues].
+type valuesAnnotator struct {
+	writer      valuesWriter
+	lines       []string
+	useHelmDocs bool
+	insertions  valuesInsertions
+}
+
+// mapping adds annotations to the keys of the mapping node that are found in
+// the properties of the parent schema, and its nested mappings.
+func (a valuesAnnotator) mapping(ptr Ptr, node *yaml.Node, parent *Schema, refs []string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valNode := node.Content[i], node.Content[i+1]
+		prop, ok := parent.Properties[keyNode.Value]
+		if isMergeKey(keyNode) || !ok || prop.Kind() == SchemaKindFalse {
+			continue
+		}
+		propPtr := ptr.Prop(keyNode.Value)
+		schema, propRefs, err := a.writer.flatten(propPtr, prop, refs)
+		if err != nil {
+			return err
+		}
+
+		comments, helmDocsComments := getComments(keyNode, valNode, true)
+		existing := map[string]string{}
+		for name, value := range splitCommentsByParts(comments) {
+			existing[name] = value
+		}
+		if _, ok := existing["hidden"]; ok {
+			continue
+		}
+		if a.useHelmDocs && len(helmDocsComments) > 0 {
+			helmDocs, err := ParseHelmDocsComment(helmDocsComments)
+			if err != nil {
+				return newNodeError(propPtr, keyNode, valNode, fmt.Errorf("parse helm-docs comment: %w", err))
+			}
+			if helmDocs.Description == schema.Description && (len(helmDocs.Path) == 0 || propPtr.Equals(NewPtr(helmDocs.Path...))) {
+				existing["description"] = schema.Description
+			}
+		}
+
+		resolved := resolveAlias(valNode)
+		annotations, err := a.writer.annotations(propPtr, schema, inferredNodeType(resolved), slices.Contains(parent.Required, keyNode.Value), propRefs)
+		if err != nil {
+			return err
+		}
+		annotations = slices.DeleteFunc(annotations, func(annotation string) bool {
+			name, value, _ := strings.Cut(annotation, ":")
+			existingValue, ok := existing[name]
+			if ok && !sameAnnotationValue(name, existingValue, value) {
+				LoggerFromContext(a.writer.ctx).Logf("Warning: %s: keeping the existing %q annotation, which differs from %q in the schema",
+					propPtr, strings.TrimSpace(name+": "+existingValue), annotation)
+			}
+			return ok
+		})
+		if len(annotations) > 0 {
+			a.insert(keyNode, "# @schema "+strings.Join(annotations, "; "))
+		}
+
+		// Keys inside aliases are annotated at their anchor instead
+		if valNode.Kind == yaml.MappingNode {
+			if err := a.mapping(propPtr, valNode, schema, propRefs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sameAnnotationValue returns true if both values of the "# @schema"
+// annotation result in the same schema, such as "[a, b]" and `["a","b"]`.
+func sameAnnotationValue(name, a, b string) bool {
+	var schemaA, schemaB Schema
+	errA := processComment(&schemaA, []string{"# @schema " + name + ": " + a})
+	errB := processComment(&schemaB, []string{"# @schema " + name + ": " + b})
+	return errA == nil && errB == nil && reflect.DeepEqual(schemaA, schemaB)
+}
+
+// insert adds the comment on its own line above the key, and above any
+// helm-docs comments of the key, using the same indentation as the key.
+func (a valuesAnnotator) insert(keyNode *yaml.Node, comment string) {
+	index := keyNode.Line - 1
+	line := a.lines[index]
+	indent := line[:min(keyNode.Column-1, len(line))]
+	if strings.TrimSpace(indent) != "" {
+		// Such as "- key: value" or "{key: value}"
+		return
+	}
+
+	// Only the last group of comment lines belongs to the key, and helm-docs
+	// comments start at the first helm-docs line of that group.
+	for i := keyNode.Line - 2; i >= 0; i-- {
+		trimmed := strings.TrimSpace(a.lines[i])
+		if !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		if helmDocsCommentRegexp.MatchString(trimmed) {
+			index = i
+		}
+	}
+
+	if strings.HasSuffix(line, "\r") {
+		comment += "\r"
+	}
+	a.insertions[index] = append(a.insertions[index], indent+comment)
+}
+
+// inferredNodeType returns the type that generating a schema infers from
+// the value node.
+func inferredNodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.ScalarNode:
+		return getScalarType(node.ShortTag())
+	default:
+		return ""
+	}
+}
+
+// apply returns the content with the lines inserted.
+func (ins valuesInsertions) apply(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	result := make([]string, 0, len(lines))
+	for i, line := range lines {
+		result = append(result, ins[i]...)
+		result = append(result, line)
+	}
+	return []byte(strings.Join(result, "\n"))
+}
+
+// diff returns the insertions as a unified diff of the file, with 3 lines
+// of context around each insertion.
+func (ins valuesInsertions) diff(fileName string, content []byte) string {
+	const contextLines = 3
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fileName, fileName)
+
+	indexes := slices.Sorted(maps.Keys(ins))
+	inserted := 0 // lines inserted before the current hunk
+	for len(indexes) > 0 {
+		start := max(0, indexes[0]-contextLines)
+		end := min(len(lines), indexes[0]+contextLines)
+		count := 1
+		for _, index := range indexes[1:] {
+			if index-contextLines > end {
+				break
+			}
+			end = min(len(lines), index+contextLines)
+			count++
+		}
+		hunkIndexes := indexes[:count]
+		indexes = indexes[count:]
+
+		hunkInserted := 0
+		for _, index := range hunkIndexes {
+			hunkInserted += len(ins[index])
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(start, end-start),
+			hunkRange(start+inserted, end-start+hunkInserted))
+		for i := start; i <= end; i++ {
+			for _, line := range ins[i] {
+				sb.WriteString("+" + strings.TrimSuffix(line, "\r") + "\n")
+			}
+			if i < end {
+				sb.WriteString(" " + strings.TrimSuffix(lines[i], "\r") + "\n")
+			}
+		}
+		inserted += hunkInserted
+	}
+	return sb.String()
+}
+
+// hunkRange formats the 0-based start line and line count of a unified diff
+// hunk, where an empty range refers to the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,416 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotateValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      string
+		values      string
+		useHelmDocs bool
+		want        string
+		wantErr     string
+	}{
+		{
+			name: "annotations above keys",
+			schema: `{
+				"required": ["replicaCount"],
+				"properties": {
+					"replicaCount": {"type": "integer", "minimum": 1},
+					"image": {
+						"type": "object",
+						"properties": {
+							"tag": {"type": ["string", "null"], "description": "Image tag"}
+						}
+					}
+				}
+			}`,
+			values: `replicaCount: 1
+image:
+  tag: ""   # keep this comment
+`,
+			want: `# @schema minimum: 1; required
+replicaCount: 1
+image:
+  # @schema type: [string, null]; description: Image tag
+  tag: ""   # keep this comment
+`,
+		},
+		{
+			name: "existing comments are kept",
+			schema: `{
+				"properties": {
+					"a": {"type": "string", "minLength": 1}
+				}
+			}`,
+			values: `# Some comment
+
+# The a key
+a: foo
+`,
+			want: `# Some comment
+
+# The a key
+# @schema minLength: 1
+a: foo
+`,
+		},
+		{
+			name: "above helm-docs comments",
+			schema: `{
+				"properties": {
+					"a": {"type": "string", "minLength": 1, "description": "From schema"}
+				}
+			}`,
+			values: `# Some comment
+# -- From helm-docs
+# @default -- foo
+a: foo
+`,
+			useHelmDocs: true,
+			want: `# Some comment
+# @schema description: From schema; minLength: 1
+# -- From helm-docs
+# @default -- foo
+a: foo
+`,
+		},
+		{
+			name: "same description as helm-docs",
+			schema: `{
+				"properties": {
+					"a": {"type": "string", "description": "Same"},
+					"b": {"type": "string", "description": "Same"}
+				}
+			}`,
+			values: `# -- Same
+a: foo
+# other -- Same
+b: foo
+`,
+			useHelmDocs: true,
+			want: `# -- Same
+a: foo
+# @schema description: Same
+# other -- Same
+b: foo
+`,
+		},
+		{
+			name: "description without use-helm-docs",
+			schema: `{
+				"properties": {
+					"a": {"type": "string", "description": "From schema"}
+				}
+			}`,
+			values: `# -- From helm-docs
+a: foo
+`,
+			want: `# @schema description: From schema
+# -- From helm-docs
+a: foo
+`,
+		},
+		{
+			name: "existing annotations are not added again",
+			schema: `{
+				"properties": {
+					"a": {"type": "string", "enum": ["foo", "bar"], "minLength": 1},
+					"b": {"type": "string", "enum": ["foo"]}
+				}
+			}`,
+			values: `# @schema enum: [foo]
+a: foo
+b: foo # @schema enum: [foo]
+`,
+			want: `# @schema enum: [foo]
+# @schema minLength: 1
+a: foo
+b: foo # @schema enum: [foo]
+`,
+		},
+		{
+			name: "hidden keys are skipped",
+			schema: `{
+				"properties": {
+					"a": {"type": "object", "minProperties": 1, "properties": {"b": {"type": "string", "minLength": 1}}}
+				}
+			}`,
+			values: `# @schema hidden
+a:
+  b: foo
+`,
+			want: `# @schema hidden
+a:
+  b: foo
+`,
+		},
+		{
+			name: "unknown keys, flow mappings and sequences are skipped",
+			schema: `{
+				"properties": {
+					"a": {"type": "object", "properties": {"b": {"type": "integer", "maximum": 3}}},
+					"list": {
+						"type": "array",
+						"items": {"type": "object", "properties": {"name": {"type": "string", "minLength": 1}}}
+					}
+				}
+			}`,
+			values: `a: {b: 1}
+list:
+  - name: foo
+unknown: 1
+`,
+			want: `a: {b: 1}
+# @schema item: object; itemProperties: {"name":{"type":"string","minLength":1}}
+list:
+  - name: foo
+unknown: 1
+`,
+		},
+		{
+			name: "refs",
+			schema: `{
+				"properties": {
+					"a": {"$ref": "#/$defs/str"},
+					"resources": {"$ref": "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.ResourceRequirements"}
+				},
+				"$defs": {
+					"str": {"type": "string", "minLength": 1}
+				}
+			}`,
+			values: `a: foo
+resources: {}
+`,
+			want: `# @schema minLength: 1
+a: foo
+# @schema $ref: $k8s/_definitions.json#/definitions/io.k8s.api.core.v1.ResourceRequirements
+resources: {}
+`,
+		},
+		{
+			name: "aliases and merge keys",
+			schema: `{
+				"properties": {
+					"base": {"type": "object", "properties": {"a": {"type": "integer", "maximum": 3}}},
+					"copy": {"type": "object", "minProperties": 1, "properties": {"a": {"type": "integer", "maximum": 3}}},
+					"merged": {"type": "object", "properties": {"a": {"type": "integer", "maximum": 3}}}
+				}
+			}`,
+			values: `base: &base
+  a: 1
+copy: *base
+merged:
+  <<: *base
+`,
+			want: `base: &base
+  # @schema maximum: 3
+  a: 1
+# @schema minProperties: 1
+copy: *base
+merged:
+  <<: *base
+`,
+		},
+		{
+			name: "multiple documents",
+			schema: `{
+				"properties": {
+					"a": {"type": "string", "minLength": 1}
+				}
+			}`,
+			values: `a: foo
+---
+a: bar
+`,
+			want: `# @schema minLength: 1
+a: foo
+---
+# @schema minLength: 1
+a: bar
+`,
+		},
+		{
+			name: "CRLF line endings",
+			schema: `{
+				"properties": {
+					"a": {"type": "string", "minLength": 1}
+				}
+			}`,
+			values: "  a: foo\r\n",
+			want:   "  # @schema minLength: 1\r\n  a: foo\r\n",
+		},
+		{
+			name:    "invalid YAML",
+			schema:  `{"properties": {}}`,
+			values:  "a: [",
+			wantErr: "error unmarshalling YAML",
+		},
+		{
+			name:    "missing ref",
+			schema:  `{"properties": {"a": {"$ref": "#/$defs/foo"}}}`,
+			values:  "a: foo\n",
+			wantErr: `/a/$ref: no schema found at $ref="#/$defs/foo"`,
+		},
+		{
+			name:    "missing ref in root",
+			schema:  `{"$ref": "#/$defs/foo"}`,
+			values:  "a: foo\n",
+			wantErr: `/$ref: no schema found at $ref="#/$defs/foo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema Schema
+			require.NoError(t, json.Unmarshal([]byte(tt.schema), &schema))
+
+			insertions, err := annotateValues(ContextWithLogger(t.Context(), t), &schema, []byte(tt.values), tt.useHelmDocs)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(insertions.apply([]byte(tt.values))))
+		})
+	}
+}
+
+func TestAnnotateValues_Conflicts(t *testing.T) {
+	var schema Schema
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"properties": {
+			"a": {"type": "string", "enum": ["foo", "bar"], "minLength": 1},
+			"b": {"type": "string", "enum": ["foo"]}
+		}
+	}`), &schema))
+	values := `# @schema enum: [foo]; minLength: 2
+a: foo
+b: foo # @schema enum: ["foo"]
+`
+
+	var buf bytes.Buffer
+	insertions, err := annotateValues(ContextWithLogger(t.Context(), NewLogger(&buf)), &schema, []byte(values), false)
+	require.NoError(t, err)
+	assert.Empty(t, insertions)
+	assert.Equal(t, `Warning: /a: keeping the existing "enum: [foo]" annotation, which differs from "enum: [\"foo\",\"bar\"]" in the schema
+Warning: /a: keeping the existing "minLength: 2" annotation, which differs from "minLength: 1" in the schema
+`, buf.String())
+}
+
+func TestAnnotateValues_RoundTrip(t *testing.T) {
+	schemaContent, err := os.ReadFile("../testdata/annotate/values.schema.json")
+	require.NoError(t, err)
+	values, err := os.ReadFile("../testdata/annotate/values.yaml")
+	require.NoError(t, err)
+	values = bytes.ReplaceAll(values, []byte("\r\n"), []byte("\n"))
+
+	var want Schema
+	require.NoError(t, json.Unmarshal(schemaContent, &want))
+
+	for _, useHelmDocs := range []bool{false, true} {
+		t.Run(fmt.Sprintf("useHelmDocs=%t", useHelmDocs), func(t *testing.T) {
+			ctx := ContextWithLogger(t.Context(), t)
+			insertions, err := annotateValues(ctx, &want, values, useHelmDocs)
+			require.NoError(t, err)
+
+			valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+			require.NoError(t, os.WriteFile(valuesFile, insertions.apply(values), 0600))
+			got, err := buildJSONSchema(ctx, &Config{Values: []string{valuesFile}, Draft: 2020, Indent: 4, UseHelmDocs: useHelmDocs})
+			require.NoError(t, err)
+
+			wantJSON, err := json.Marshal(&want)
+			require.NoError(t, err)
+			gotJSON, err := json.Marshal(got)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(wantJSON), string(gotJSON))
+		})
+	}
+}
+
+func TestValuesInsertionsDiff(t *testing.T) {
+	content := []byte("a: 1\nb: 2\nc: 3\nd: 4\ne: 5\nf: 6\ng: 7\nh: 8\ni: 9\nj: 10\nk: 11\nl: 12\n")
+
+	tests := []struct {
+		name       string
+		insertions valuesInsertions
+		want       string
+	}{
+		{
+			name:       "first line",
+			insertions: valuesInsertions{0: {"# x"}},
+			want: `--- values.yaml
++++ values.yaml
+@@ -1,3 +1,4 @@
++# x
+ a: 1
+ b: 2
+ c: 3
+`,
+		},
+		{
+			name:       "merged hunk",
+			insertions: valuesInsertions{3: {"# x"}, 8: {"# y", "# z"}},
+			want: `--- values.yaml
++++ values.yaml
+@@ -1,11 +1,14 @@
+ a: 1
+ b: 2
+ c: 3
++# x
+ d: 4
+ e: 5
+ f: 6
+ g: 7
+ h: 8
++# y
++# z
+ i: 9
+ j: 10
+ k: 11
+`,
+		},
+		{
+			name:       "separate hunks",
+			insertions: valuesInsertions{1: {"# x"}, 10: {"# y"}},
+			want: `--- values.yaml
++++ values.yaml
+@@ -1,4 +1,5 @@
+ a: 1
++# x
+ b: 2
+ c: 3
+ d: 4
+@@ -8,5 +9,6 @@
+ h: 8
+ i: 9
+ j: 10
++# y
+ k: 11
+ l: 12
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.insertions.diff("values.yaml", content))
+		})
+	}
+}
+
+func TestHunkRange(t *testing.T) {
+	assert.Equal(t, "1,3", hunkRange(0, 3))
+	assert.Equal(t, "5,1", hunkRange(4, 1))
+	assert.Equal(t, "4,0", hunkRange(4, 0))
+}
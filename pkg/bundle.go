@@ -4,67 +4,138 @@ import (
 	"cmp"
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
 
+// BundleOptions configures [Bundle].
+type BundleOptions struct {
+	// BundleRoot is the directory that local files are allowed to be loaded
+	// from. Defaults to the current working directory.
+	BundleRoot string
+	// WithoutIDs removes the "$id" from bundled schemas using [BundleRemoveIDs].
+	WithoutIDs bool
+	// K8sSchemaURL and K8sSchemaVersion are used to expand any
+	// "$ref: $k8s/..." aliases found in loaded schemas.
+	K8sSchemaURL     string
+	K8sSchemaVersion string
+	// RefAliases are the user-defined "$ref: $name/..." aliases that are
+	// expanded in loaded schemas, alongside "$k8s".
+	RefAliases map[string]RefAlias
+	// CacheMinDuration raises the minimum cache duration of downloaded schemas.
+	// See [HTTPFileCache.MinCacheDuration].
+	CacheMinDuration time.Duration
+	// CacheDir is the directory of the HTTP cache. See [HTTPCacheDir].
+	CacheDir string
+	// CacheMaxSize limits the total size of the HTTP cache in bytes.
+	// See [HTTPFileCache.MaxSize].
+	CacheMaxSize int64
+	// RefMirrors maps URL prefixes to the mirror URL prefixes that schemas
+	// are loaded from instead. See [mirrorLoader].
+	RefMirrors map[string]string
+
+	// wrapLoader, when not nil, wraps the default loader, such as to not
+	// download any schemas when linting.
+	wrapLoader func(Loader) Loader
+}
+
 // Bundle will use default loader settings to bundle all $ref into $defs
 // using [BundleSchema] and optionally remove any IDs using [BundleRemoveIDs].
 //
 // This function will update the schema in-place.
 //
-// The paths, outputDir & bundleRoot, are only used to change absolute paths
+// The paths, outputDir & opts.BundleRoot, are only used to change absolute paths
 // into relative paths in a solely cosmetic way.
-func Bundle(ctx context.Context, schema *Schema, outputDir, bundleRoot string, withoutIDs bool, k8sSchemaURL, k8sSchemaVersion string, cacheMinDuration time.Duration) error {
+func Bundle(ctx context.Context, schema *Schema, outputDir string, opts BundleOptions) error {
 	absOutputDir, err := filepath.Abs(filepath.Dir(filepath.FromSlash(outputDir)))
 	if err != nil {
 		return fmt.Errorf("output %s: get absolute path: %w", outputDir, err)
 	}
 
-	bundleRootAbs, err := filepath.Abs(cmp.Or(filepath.FromSlash(bundleRoot), "."))
+	bundleRootAbs, err := filepath.Abs(cmp.Or(filepath.FromSlash(opts.BundleRoot), "."))
 	if err != nil {
-		return fmt.Errorf("bundle root %s: get absolute path: %w", bundleRoot, err)
+		return fmt.Errorf("bundle root %s: get absolute path: %w", opts.BundleRoot, err)
 	}
 
 	root, err := os.OpenRoot(bundleRootAbs)
 	if err != nil {
-		return fmt.Errorf("bundle root %s: %w", bundleRoot, err)
+		return fmt.Errorf("bundle root %s: %w", opts.BundleRoot, err)
 	}
 	defer closeIgnoreError(root)
 
-	loader := k8sAliasLoader{
-		inner:       NewDefaultLoader(http.DefaultClient, (*RootFS)(root), bundleRootAbs, cacheMinDuration),
-		urlTemplate: k8sSchemaURL,
-		version:     k8sSchemaVersion,
+	cache := NewHTTPCache(opts.CacheDir, opts.CacheMinDuration)
+	cache.MaxSize = opts.CacheMaxSize
+	var loader Loader = refAliasLoader{
+		inner: mirrorLoader{
+			inner:   NewDefaultLoader(http.DefaultClient, (*RootFS)(root), bundleRootAbs, cache),
+			mirrors: opts.RefMirrors,
+		},
+		aliases: newRefAliases(opts.K8sSchemaURL, opts.K8sSchemaVersion, opts.RefAliases),
+	}
+	if opts.wrapLoader != nil {
+		loader = opts.wrapLoader(loader)
 	}
-	return bundleWithLoader(ctx, loader, schema, absOutputDir, withoutIDs)
+	return bundleWithLoader(ctx, loader, schema, absOutputDir, opts.WithoutIDs)
 }
 
-// k8sAliasLoader wraps a Loader and expands any "$ref: $k8s/..." aliases in
-// each loaded schema. This ensures external schema files that reference k8s
-// schemas via the "$k8s/" alias have those references expanded before bundling.
-type k8sAliasLoader struct {
-	inner       Loader
-	urlTemplate string
-	version     string
+// refAliasLoader wraps a Loader and expands any "$ref: $k8s/..." and other
+// [RefAlias] aliases in each loaded schema. This ensures external schema files
+// that reference schemas via an alias, such as "$k8s/", have those references
+// expanded before bundling.
+type refAliasLoader struct {
+	inner   Loader
+	aliases refAliases
 }
 
-func (l k8sAliasLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
+func (l refAliasLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
 	schema, err := l.inner.Load(ctx, ref)
 	if err != nil || schema == nil {
 		return schema, err
 	}
-	if err := updateRefK8sAlias(schema, l.urlTemplate, l.version); err != nil {
+	if err := l.aliases.update(schema); err != nil {
 		return nil, err
 	}
 	return schema, nil
 }
 
+// mirrorLoader wraps a Loader and rewrites the URL of each loaded "$ref"
+// using prefix-based rules, e.g to download schemas from an internal mirror
+// instead of from the internet. When multiple prefixes match, then the
+// longest one is used.
+//
+// Only the URL used for loading is rewritten. The "$id" is assigned by [Load]
+// from the original "$ref", so the bundled schema keeps the original URLs.
+type mirrorLoader struct {
+	inner   Loader
+	mirrors map[string]string
+}
+
+func (l mirrorLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
+	refString := ref.String()
+	var prefix string
+	for p := range l.mirrors {
+		if strings.HasPrefix(refString, p) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return l.inner.Load(ctx, ref)
+	}
+	mirrored, err := url.Parse(l.mirrors[prefix] + strings.TrimPrefix(refString, prefix))
+	if err != nil {
+		return nil, fmt.Errorf("rewrite $ref=%q using mirror %q: %w", ref.Redacted(), prefix, err)
+	}
+	LoggerFromContext(ctx).Logf("Using mirror %s for %s", mirrored.Redacted(), ref.Redacted())
+	return l.inner.Load(ctx, mirrored)
+}
+
 func bundleWithLoader(ctx context.Context, loader Loader, schema *Schema, absOutputDir string, withoutIDs bool) error {
 	if err := BundleSchema(ctx, loader, schema, absOutputDir); err != nil {
 		return fmt.Errorf("bundle schemas: %w", err)
@@ -107,33 +178,43 @@ func bundleSchemaRec(ctx context.Context, ptr Ptr, loader Loader, root, schema *
 		}
 	}
 
-	if schema.Ref == "" || strings.HasPrefix(schema.Ref, "#") {
+	if schema.ID != "" {
+		ctx = ContextWithLoaderReferrer(ctx, schema.ID)
+	}
+	if err := bundleRef(ctx, ptr, "$ref", loader, root, &schema.Ref, schema.ParseRef, basePathForIDs); err != nil {
+		return err
+	}
+	return bundleRef(ctx, ptr, "$dynamicRef", loader, root, &schema.DynamicRef, schema.ParseDynamicRef, basePathForIDs)
+}
+
+// bundleRef bundles the schema referenced by a single "$ref" or "$dynamicRef"
+// field, and updates the field to point to the bundled schema.
+func bundleRef(ctx context.Context, ptr Ptr, keyword string, loader Loader, root *Schema, refField *string, parseRef func() (*url.URL, error), basePathForIDs string) error {
+	if *refField == "" || strings.HasPrefix(*refField, "#") {
 		// Nothing to bundle
 		return nil
 	}
 	for _, def := range root.Defs {
-		if def.ID == trimFragment(schema.Ref) {
+		if def.ID == trimFragment(*refField) {
 			// Already bundled
 			return nil
 		}
 	}
-	if schema.ID != "" {
-		ctx = ContextWithLoaderReferrer(ctx, schema.ID)
-	}
-	ref, err := schema.ParseRef()
+	refPtr := ptr.Prop(keyword)
+	ref, err := parseRef()
 	if err != nil {
-		return fmt.Errorf("%s: %w", ptr.Prop("$ref"), err)
+		return fmt.Errorf("%s: %w", refPtr, err)
 	}
 
 	// Make sure schema $ref corresponds with the corrected path
 	//
 	// It's fine to modify the $ref here, as it is not used any more times
 	// after this. So changing it is solely a cosmetic change.
-	schema.Ref = refRelativeToBasePath(ref, basePathForIDs).String()
+	*refField = refRelativeToBasePath(ref, basePathForIDs).String()
 
 	loaded, err := Load(ctx, loader, ref, basePathForIDs)
 	if err != nil {
-		return fmt.Errorf("%s: %w", ptr.Prop("$ref"), err)
+		return fmt.Errorf("%s: %w", refPtr, err)
 	}
 	if loaded == nil {
 		return nil
@@ -142,8 +223,14 @@ func bundleSchemaRec(ctx context.Context, ptr Ptr, loader Loader, root, schema *
 		root.Defs = map[string]*Schema{}
 	}
 
-	if newRef, ok := refRelativeToNearestID(ParsePtr(ref.Fragment), loaded); ok {
-		schema.Ref = newRef
+	if isAnchorFragment(ref.Fragment) {
+		// Plain-name fragments are resolved relative to the loaded schema's $id,
+		// so the $ref is already correct as long as the anchor exists.
+		if _, _, ok := findAnchor(loaded, ref.Fragment); !ok {
+			return fmt.Errorf("%s: no $anchor or $dynamicAnchor named %q found in $ref=%q", refPtr, ref.Fragment, *refField)
+		}
+	} else if newRef, ok := refRelativeToNearestID(ParsePtr(ref.Fragment), loaded); ok {
+		*refField = newRef
 	}
 
 	// Copy over $defs
@@ -156,6 +243,30 @@ func bundleSchemaRec(ctx context.Context, ptr Ptr, loader Loader, root, schema *
 	return bundleSchemaRec(ctx, ptr, loader, root, loaded, basePathForIDs)
 }
 
+// isAnchorFragment returns true if the URL fragment is a plain-name fragment,
+// such as "foo.json#myAnchor", as opposed to a JSON pointer fragment.
+func isAnchorFragment(fragment string) bool {
+	return fragment != "" && !strings.HasPrefix(fragment, "/")
+}
+
+// findAnchor looks up a subschema with a matching "$anchor" or "$dynamicAnchor"
+// and returns its pointer relative to the given schema. Subschemas with their
+// own "$id" are not searched, as anchors are scoped to their schema resource.
+func findAnchor(schema *Schema, name string) (Ptr, *Schema, bool) {
+	if schema.Anchor == name || schema.DynamicAnchor == name {
+		return nil, schema, true
+	}
+	for path, subSchema := range schema.Subschemas() {
+		if subSchema.ID != "" {
+			continue
+		}
+		if subPtr, match, ok := findAnchor(subSchema, name); ok {
+			return path.Add(subPtr), match, true
+		}
+	}
+	return nil, nil, false
+}
+
 // refRelativeToNearestID takes in a [Ptr], tries to resolve it on the target schema,
 // and outputs a $ref that points to the same target but from the nearest subschema
 // that has an $id.
@@ -190,6 +301,18 @@ func refRelativeToNearestID(ptr Ptr, loaded *Schema) (string, bool) {
 }
 
 func refRelativeToBasePath(ref *url.URL, basePathForIDs string) *url.URL {
+	if refPath, ok := localSchemeRefPath(ref); ok {
+		rel, err := filepath.Rel(basePathForIDs, filepath.FromSlash(refPath))
+		if err != nil || !filepath.IsAbs(filepath.FromSlash(refPath)) {
+			return ref
+		}
+		return &url.URL{
+			Scheme:   ref.Scheme,
+			Opaque:   filepath.ToSlash(filepath.Clean(rel)),
+			RawQuery: ref.RawQuery,
+			Fragment: ref.Fragment,
+		}
+	}
 	refFile, err := ParseRefFileURLAllowAbs(ref)
 	pathFromSlash := filepath.FromSlash(refFile.Path)
 	if err != nil || refFile.Path == "" || !filepath.IsAbs(pathFromSlash) {
@@ -280,54 +403,139 @@ func BundleRemoveIDs(schema *Schema) error {
 	if schema == nil {
 		return fmt.Errorf("nil schema")
 	}
-	if err := bundleChangeRefsRec(nil, nil, schema, schema); err != nil {
+	if err := bundleChangeRefsRec(nil, nil, nil, schema, schema); err != nil {
 		return err
 	}
-	for _, def := range schema.Defs {
+	// Anchors of the root schema resource stay as-is
+	dynamicAnchors := map[string]bool{}
+	collectAnchorsRec(schema, dynamicAnchors)
+	for _, name := range slices.Sorted(maps.Keys(schema.Defs)) {
+		def := schema.Defs[name]
+		if def.ID != "" {
+			// All refs to anchors have been replaced with JSON pointers, and
+			// leaving them would risk duplicate anchors in the bundled schema.
+			removeAnchorsRec(def, dynamicAnchors)
+		}
 		def.ID = ""
 	}
 	return nil
 }
 
-func bundleChangeRefsRec(parentDefPtr, ptr Ptr, root, schema *Schema) error {
+func bundleChangeRefsRec(parentDefPtr, ptr Ptr, parentDef, root, schema *Schema) error {
 	if schema.ID != "" {
 		parentDefPtr = ptr
+		parentDef = schema
 	}
 
 	for subPath, subSchema := range schema.Subschemas() {
-		if err := bundleChangeRefsRec(parentDefPtr, ptr.Add(subPath), root, subSchema); err != nil {
+		if err := bundleChangeRefsRec(parentDefPtr, ptr.Add(subPath), parentDef, root, subSchema); err != nil {
 			return err
 		}
 	}
 
-	if schema.Ref == "" || strings.HasPrefix(schema.Ref, "#") {
-		if schema.Ref != "" && len(parentDefPtr) > 0 {
-			// Update inline refs
-			schema.Ref = fmt.Sprintf("#%s%s", parentDefPtr, strings.TrimPrefix(schema.Ref, "#"))
-		}
+	if err := bundleChangeRef(parentDefPtr, ptr.Prop("$ref"), parentDef, root, &schema.Ref); err != nil {
+		return err
+	}
+	return bundleChangeRef(parentDefPtr, ptr.Prop("$dynamicRef"), parentDef, root, &schema.DynamicRef)
+}
+
+func bundleChangeRef(parentDefPtr, refPtr Ptr, parentDef, root *Schema, refField *string) error {
+	if *refField == "" {
+		return nil
+	}
 
+	if strings.HasPrefix(*refField, "#") {
+		if len(parentDefPtr) == 0 {
+			return nil
+		}
+		// Update inline refs
+		fragment := strings.TrimPrefix(*refField, "#")
+		if isAnchorFragment(fragment) {
+			anchorPtr, _, ok := findAnchor(parentDef, fragment)
+			if !ok {
+				return fmt.Errorf("%s: no $anchor or $dynamicAnchor named %q found in $ref=%q", refPtr, fragment, *refField)
+			}
+			fragment = anchorPtrSuffix(anchorPtr)
+		}
+		*refField = fmt.Sprintf("#%s%s", parentDefPtr, fragment)
 		return nil
 	}
 
-	ref, err := url.Parse(schema.Ref)
+	ref, err := url.Parse(*refField)
 	if err != nil {
-		return fmt.Errorf("%s: parse $ref as URL: %w", ptr.Prop("$ref"), err)
+		return fmt.Errorf("%s: parse $ref as URL: %w", refPtr, err)
 	}
 
 	name, ok := findDefNameByRef(root.Defs, ref)
 	if !ok {
-		return fmt.Errorf("%s: no $defs found that matches $ref=%q", ptr.Prop("$ref"), ref.Redacted())
+		return fmt.Errorf("%s: no $defs found that matches $ref=%q", refPtr, ref.Redacted())
 	}
 
-	if ref.Fragment != "" {
-		schema.Ref = fmt.Sprintf("#%s/%s", NewPtr("$defs", name), strings.TrimPrefix(ref.Fragment, "/"))
-	} else {
-		schema.Ref = fmt.Sprintf("#%s", NewPtr("$defs", name))
+	switch {
+	case isAnchorFragment(ref.Fragment):
+		anchorPtr, _, ok := findAnchor(root.Defs[name], ref.Fragment)
+		if !ok {
+			return fmt.Errorf("%s: no $anchor or $dynamicAnchor named %q found in $ref=%q", refPtr, ref.Fragment, ref.Redacted())
+		}
+		*refField = fmt.Sprintf("#%s%s", NewPtr("$defs", name), anchorPtrSuffix(anchorPtr))
+	case ref.Fragment != "":
+		*refField = fmt.Sprintf("#%s/%s", NewPtr("$defs", name), strings.TrimPrefix(ref.Fragment, "/"))
+	default:
+		*refField = fmt.Sprintf("#%s", NewPtr("$defs", name))
 	}
 
 	return nil
 }
 
+// anchorPtrSuffix returns the pointer as a suffix to append to another
+// pointer, where an empty pointer results in an empty string.
+func anchorPtrSuffix(ptr Ptr) string {
+	if len(ptr) == 0 {
+		return ""
+	}
+	return ptr.String()
+}
+
+// collectAnchorsRec adds the names of all "$anchor" and "$dynamicAnchor" of
+// the schema resource, without the subschemas that have their own "$id".
+func collectAnchorsRec(schema *Schema, anchors map[string]bool) {
+	for _, anchor := range []string{schema.Anchor, schema.DynamicAnchor} {
+		if anchor != "" {
+			anchors[anchor] = true
+		}
+	}
+	for _, subSchema := range schema.Subschemas() {
+		if subSchema.ID != "" {
+			continue
+		}
+		collectAnchorsRec(subSchema, anchors)
+	}
+}
+
+// removeAnchorsRec removes all "$anchor" from the schema resource, without
+// touching subschemas that have their own "$id".
+//
+// The "$dynamicAnchor" are kept, as they also mark the extension points of a
+// "$dynamicRef" from the dynamic scope, unless the name is already in the
+// anchors, which would be a duplicate anchor in the bundled schema. Kept
+// names are added to the anchors.
+func removeAnchorsRec(schema *Schema, anchors map[string]bool) {
+	schema.Anchor = ""
+	if schema.DynamicAnchor != "" {
+		if anchors[schema.DynamicAnchor] {
+			schema.DynamicAnchor = ""
+		} else {
+			anchors[schema.DynamicAnchor] = true
+		}
+	}
+	for _, subSchema := range schema.Subschemas() {
+		if subSchema.ID != "" {
+			continue
+		}
+		removeAnchorsRec(subSchema, anchors)
+	}
+}
+
 func findDefNameByRef(defs map[string]*Schema, ref *url.URL) (string, bool) {
 	for name, def := range defs {
 		if def.ID == trimFragmentURL(ref) {
@@ -385,12 +593,17 @@ func findUnusedDefs(ptr Ptr, root, schema *Schema, refCounts map[*Schema]int) {
 		findUnusedDefs(ptr.Add(path), root, def, refCounts)
 	}
 
-	if schema.Ref == "" {
+	countRefToDefs(ptr, root, schema.Ref, refCounts)
+	countRefToDefs(ptr, root, schema.DynamicRef, refCounts)
+}
+
+func countRefToDefs(ptr Ptr, root *Schema, refString string, refCounts map[*Schema]int) {
+	if refString == "" {
 		return
 	}
 
-	if strings.HasPrefix(schema.Ref, "#/") {
-		refPtr := ParsePtr(schema.Ref)
+	if strings.HasPrefix(refString, "#/") {
+		refPtr := ParsePtr(refString)
 		if len(refPtr) > 0 && ptr.HasPrefix(refPtr) {
 			// Ignore self-referential
 			// E.g "#/$defs/foo.json/properties/moo" has $ref to "#/$defs/foo.json"
@@ -402,13 +615,20 @@ func findUnusedDefs(ptr Ptr, root, schema *Schema, refCounts map[*Schema]int) {
 		return
 	}
 
-	ref, err := url.Parse(schema.Ref)
+	ref, err := url.Parse(refString)
 	if err != nil {
 		return
 	}
 
 	if name, ok := findDefNameByRef(root.Defs, ref); ok {
 		refCounts[root.Defs[name]]++
+		if ref.Fragment != "" && !isAnchorFragment(ref.Fragment) {
+			// Also count the definitions within the bundled schema,
+			// e.g "foo.json#/definitions/bar" uses "bar"
+			for _, match := range ParsePtr(ref.Fragment).Resolve(root.Defs[name]) {
+				refCounts[match.Schema]++
+			}
+		}
 	}
 }
 
@@ -178,6 +178,42 @@ func TestRefRelativeToBasePath(t *testing.T) {
 			}.String(),
 			want: mustParseURL("../foo"),
 		},
+		{
+			name: "crd ignore already relative",
+			ref:  mustParseURL("crd:foo/bar.yaml?version=v1"),
+			basePathForIDs: testutil.PerGOOS{
+				Default: `/home/user`,
+				Windows: `C:\User`,
+			}.String(),
+			want: mustParseURL("crd:foo/bar.yaml?version=v1"),
+		},
+		{
+			name: "crd ignore when cant make relative path",
+			ref: mustParseURL(testutil.PerGOOS{
+				Default: `crd:///foo/bar.yaml`,
+				Windows: `crd://C:/foo/bar.yaml`,
+			}.String()),
+			basePathForIDs: testutil.PerGOOS{
+				Default: `home/user`,
+				Windows: `User`,
+			}.String(),
+			want: mustParseURL(testutil.PerGOOS{
+				Default: `crd:///foo/bar.yaml`,
+				Windows: `crd://C:/foo/bar.yaml`,
+			}.String()),
+		},
+		{
+			name: "crd change to parent dir",
+			ref: mustParseURL(testutil.PerGOOS{
+				Default: `crd:///home/foo/bar.yaml?version=v1#/properties/spec`,
+				Windows: `crd://C:/foo/bar.yaml?version=v1#/properties/spec`,
+			}.String()),
+			basePathForIDs: testutil.PerGOOS{
+				Default: `/home/user`,
+				Windows: `C:\User`,
+			}.String(),
+			want: mustParseURL("crd:../foo/bar.yaml?version=v1#/properties/spec"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -519,6 +555,66 @@ func TestBundle(t *testing.T) {
 				},
 			},
 		},
+
+		{
+			name: "ref to anchor",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "foo.json#myAnchor"},
+				},
+			},
+			loader: DummyLoader{
+				LoadFunc: func(ctx context.Context, ref *url.URL) (*Schema, error) {
+					return &Schema{
+						Defs: map[string]*Schema{
+							"bar": {Anchor: "myAnchor", Type: "string"},
+						},
+					}, nil
+				},
+			},
+			want: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "foo.json#myAnchor"},
+				},
+				Defs: map[string]*Schema{
+					"foo.json": {
+						ID: "foo.json",
+						Defs: map[string]*Schema{
+							"bar": {Anchor: "myAnchor", Type: "string"},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			name: "dynamic ref to dynamic anchor",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {DynamicRef: "tree.json#node"},
+				},
+			},
+			loader: DummyLoader{
+				LoadFunc: func(ctx context.Context, ref *url.URL) (*Schema, error) {
+					return &Schema{
+						DynamicAnchor: "node",
+						Items:         &Schema{DynamicRef: "#node"},
+					}, nil
+				},
+			},
+			want: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {DynamicRef: "tree.json#node"},
+				},
+				Defs: map[string]*Schema{
+					"tree.json": {
+						ID:            "tree.json",
+						DynamicAnchor: "node",
+						Items:         &Schema{DynamicRef: "#node"},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -572,6 +668,34 @@ func TestBundle_Errors(t *testing.T) {
 			loader:  DummyLoader{},
 			wantErr: `/properties/foo/$ref: parse "::": missing protocol scheme`,
 		},
+		{
+			name: "anchor not found",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "foo.json#myAnchor"},
+				},
+			},
+			loader: DummyLoader{
+				LoadFunc: func(ctx context.Context, ref *url.URL) (*Schema, error) {
+					return &Schema{
+						Defs: map[string]*Schema{
+							"bar": {ID: "bar.json", Anchor: "myAnchor"},
+						},
+					}, nil
+				},
+			},
+			wantErr: `/properties/foo/$ref: no $anchor or $dynamicAnchor named "myAnchor" found in $ref="foo.json#myAnchor"`,
+		},
+		{
+			name: "invalid dynamic ref URL",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {DynamicRef: "::"},
+				},
+			},
+			loader:  DummyLoader{},
+			wantErr: `/properties/foo/$dynamicRef: parse "::": missing protocol scheme`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -659,6 +783,107 @@ func TestBundleRemoveIDs(t *testing.T) {
 				},
 			},
 		},
+
+		{
+			name: "refs to anchors",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "foo.json#myAnchor"},
+					"bar": {Ref: "foo.json#rootAnchor"},
+				},
+				Defs: map[string]*Schema{
+					"foo": {
+						ID:     "foo.json",
+						Anchor: "rootAnchor",
+						Items:  &Schema{Ref: "#myAnchor"},
+						Defs: map[string]*Schema{
+							"bar": {Anchor: "myAnchor", Type: "string"},
+						},
+					},
+				},
+			},
+			want: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "#/$defs/foo/$defs/bar"},
+					"bar": {Ref: "#/$defs/foo"},
+				},
+				Defs: map[string]*Schema{
+					"foo": {
+						Items: &Schema{Ref: "#/$defs/foo/$defs/bar"},
+						Defs: map[string]*Schema{
+							"bar": {Type: "string"},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			name: "dynamic refs to dynamic anchors",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {DynamicRef: "tree.json#node"},
+				},
+				Defs: map[string]*Schema{
+					"tree": {
+						ID:            "tree.json",
+						DynamicAnchor: "node",
+						Items:         &Schema{DynamicRef: "#node"},
+					},
+				},
+			},
+			want: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {DynamicRef: "#/$defs/tree"},
+				},
+				Defs: map[string]*Schema{
+					"tree": {
+						DynamicAnchor: "node",
+						Items:         &Schema{DynamicRef: "#/$defs/tree"},
+					},
+				},
+			},
+		},
+
+		{
+			name: "removes duplicate dynamic anchors",
+			schema: &Schema{
+				DynamicAnchor: "root",
+				Properties: map[string]*Schema{
+					"foo": {DynamicRef: "a.json#node"},
+					"bar": {DynamicRef: "b.json#node"},
+				},
+				Defs: map[string]*Schema{
+					"a": {ID: "a.json", DynamicAnchor: "node"},
+					"b": {ID: "b.json", DynamicAnchor: "node"},
+					"c": {ID: "c.json", DynamicAnchor: "root"},
+				},
+			},
+			want: &Schema{
+				DynamicAnchor: "root",
+				Properties: map[string]*Schema{
+					"foo": {DynamicRef: "#/$defs/a"},
+					"bar": {DynamicRef: "#/$defs/b"},
+				},
+				Defs: map[string]*Schema{
+					"a": {DynamicAnchor: "node"},
+					"b": {},
+					"c": {},
+				},
+			},
+		},
+
+		{
+			name: "keeps anchors in root schema",
+			schema: &Schema{
+				Anchor: "root",
+				Items:  &Schema{Ref: "#root"},
+			},
+			want: &Schema{
+				Anchor: "root",
+				Items:  &Schema{Ref: "#root"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -679,6 +904,40 @@ func TestBundleRemoveIDs(t *testing.T) {
 	}
 }
 
+func TestBundleRemoveIDs_DynamicRef(t *testing.T) {
+	schema := &Schema{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Properties: map[string]*Schema{
+			"tree": {Ref: "tree.json"},
+		},
+	}
+	loader := DummyLoader{
+		LoadFunc: func(ctx context.Context, ref *url.URL) (*Schema, error) {
+			return &Schema{
+				DynamicAnchor: "node",
+				Type:          "object",
+				Properties: map[string]*Schema{
+					"children": {Type: "array", Items: &Schema{DynamicRef: "#node"}},
+				},
+			}, nil
+		},
+	}
+
+	ctx := ContextWithLogger(t.Context(), t)
+	require.NoError(t, bundleWithLoader(ctx, loader, schema, "/", true))
+	assert.Equal(t, "node", schema.Defs["tree.json"].DynamicAnchor)
+	assert.Equal(t, "#/$defs/tree.json", schema.Defs["tree.json"].Properties["children"].Items.DynamicRef)
+
+	compiled, err := compileValuesSchema(schema, "")
+	require.NoError(t, err)
+	assert.NoError(t, compiled.Validate(map[string]any{
+		"tree": map[string]any{"children": []any{map[string]any{"children": []any{}}}},
+	}))
+	assert.Error(t, compiled.Validate(map[string]any{
+		"tree": map[string]any{"children": []any{map[string]any{"children": "foo"}}},
+	}))
+}
+
 func TestBundleRemoveIDs_Errors(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -713,6 +972,30 @@ func TestBundleRemoveIDs_Errors(t *testing.T) {
 			},
 			wantErr: `/properties/foo/$ref: no $defs found that matches $ref="./no/$defs/with/this/ref"`,
 		},
+		{
+			name: "anchor not found",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "foo.json#myAnchor"},
+				},
+				Defs: map[string]*Schema{
+					"foo": {ID: "foo.json"},
+				},
+			},
+			wantErr: `/properties/foo/$ref: no $anchor or $dynamicAnchor named "myAnchor" found in $ref="foo.json#myAnchor"`,
+		},
+		{
+			name: "inline anchor not found",
+			schema: &Schema{
+				Defs: map[string]*Schema{
+					"foo": {
+						ID:    "foo.json",
+						Items: &Schema{DynamicRef: "#node"},
+					},
+				},
+			},
+			wantErr: `/$defs/foo/items/$dynamicRef: no $anchor or $dynamicAnchor named "node" found in $ref="#node"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -763,6 +1046,27 @@ func TestRemoveUnusedDefs(t *testing.T) {
 			},
 		},
 
+		{
+			name: "keep definitions referenced by id and pointer",
+			schema: &Schema{
+				Items: &Schema{Ref: "foo.json#/definitions/bar"},
+				Defs: map[string]*Schema{
+					"foo.json": {ID: "foo.json", Definitions: map[string]*Schema{
+						"bar": {Type: "string"},
+						"moo": {Type: "string"},
+					}},
+				},
+			},
+			want: &Schema{
+				Items: &Schema{Ref: "foo.json#/definitions/bar"},
+				Defs: map[string]*Schema{
+					"foo.json": {ID: "foo.json", Definitions: map[string]*Schema{
+						"bar": {Type: "string"},
+					}},
+				},
+			},
+		},
+
 		{
 			name: "keep some remove some",
 			schema: &Schema{
@@ -905,6 +1209,22 @@ func TestRemoveUnusedDefs(t *testing.T) {
 			},
 			want: &Schema{},
 		},
+
+		{
+			name: "keep def used by dynamic ref",
+			schema: &Schema{
+				Items: &Schema{DynamicRef: "#/$defs/foo.json"},
+				Defs: map[string]*Schema{
+					"foo.json": {DynamicAnchor: "node"},
+				},
+			},
+			want: &Schema{
+				Items: &Schema{DynamicRef: "#/$defs/foo.json"},
+				Defs: map[string]*Schema{
+					"foo.json": {DynamicAnchor: "node"},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -924,7 +1244,7 @@ func TestRemoveUnusedDefs(t *testing.T) {
 	}
 }
 
-func TestK8sAliasLoader(t *testing.T) {
+func TestRefAliasLoader(t *testing.T) {
 	t.Parallel()
 
 	const (
@@ -943,7 +1263,7 @@ func TestK8sAliasLoader(t *testing.T) {
 				}, nil
 			},
 		}
-		loader := k8sAliasLoader{inner: inner, urlTemplate: urlTemplate, version: version}
+		loader := refAliasLoader{inner: inner, aliases: newRefAliases(urlTemplate, version, nil)}
 
 		ref, _ := url.Parse("local.json")
 		schema, err := loader.Load(t.Context(), ref)
@@ -961,7 +1281,7 @@ func TestK8sAliasLoader(t *testing.T) {
 				return nil, fmt.Errorf("load failed")
 			},
 		}
-		loader := k8sAliasLoader{inner: inner, urlTemplate: urlTemplate, version: version}
+		loader := refAliasLoader{inner: inner, aliases: newRefAliases(urlTemplate, version, nil)}
 
 		ref, _ := url.Parse("local.json")
 		_, err := loader.Load(t.Context(), ref)
@@ -975,7 +1295,7 @@ func TestK8sAliasLoader(t *testing.T) {
 				return nil, nil
 			},
 		}
-		loader := k8sAliasLoader{inner: inner, urlTemplate: urlTemplate, version: version}
+		loader := refAliasLoader{inner: inner, aliases: newRefAliases(urlTemplate, version, nil)}
 
 		ref, _ := url.Parse("local.json")
 		schema, err := loader.Load(t.Context(), ref)
@@ -987,7 +1307,7 @@ func TestK8sAliasLoader(t *testing.T) {
 		t.Parallel()
 		// Simulate: values.yaml has @schema $ref: local.json
 		// local.json contains $ref: $k8s/io.k8s.api.core.v1.Probe.json
-		// After bundling with k8sAliasLoader, the k8s schema should be loaded and bundled.
+		// After bundling with refAliasLoader, the k8s schema should be loaded and bundled.
 		k8sSchemaURL := "https://example.com/k8s/v1.33.1/io.k8s.api.core.v1.Probe.json"
 		inner := DummyLoader{
 			LoadFunc: func(_ context.Context, ref *url.URL) (*Schema, error) {
@@ -1002,10 +1322,9 @@ func TestK8sAliasLoader(t *testing.T) {
 				}
 			},
 		}
-		loader := k8sAliasLoader{
-			inner:       inner,
-			urlTemplate: "https://example.com/k8s/{{ .K8sSchemaVersion }}/",
-			version:     version,
+		loader := refAliasLoader{
+			inner:   inner,
+			aliases: newRefAliases("https://example.com/k8s/{{ .K8sSchemaVersion }}/", version, nil),
 		}
 
 		root := &Schema{
@@ -1023,3 +1342,95 @@ func TestK8sAliasLoader(t *testing.T) {
 		assert.NotNil(t, root.Defs)
 	})
 }
+
+func TestMirrorLoader(t *testing.T) {
+	t.Parallel()
+
+	mirrors := map[string]string{
+		"https://json.schemastore.org/":        "https://mirror.corp/schemastore/",
+		"https://json.schemastore.org/github/": "https://mirror.corp/github/",
+	}
+
+	tests := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{
+			name: "no match",
+			ref:  "https://example.com/schema.json",
+			want: "https://example.com/schema.json",
+		},
+		{
+			name: "prefix match",
+			ref:  "https://json.schemastore.org/chart.json",
+			want: "https://mirror.corp/schemastore/chart.json",
+		},
+		{
+			name: "longest prefix wins",
+			ref:  "https://json.schemastore.org/github/workflow.json",
+			want: "https://mirror.corp/github/workflow.json",
+		},
+		{
+			name: "keeps fragment",
+			ref:  "https://json.schemastore.org/chart.json#/properties/name",
+			want: "https://mirror.corp/schemastore/chart.json#/properties/name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var loaded string
+			loader := mirrorLoader{
+				inner: DummyLoader{
+					LoadFunc: func(_ context.Context, ref *url.URL) (*Schema, error) {
+						loaded = ref.String()
+						return &Schema{}, nil
+					},
+				},
+				mirrors: mirrors,
+			}
+			ctx := ContextWithLogger(t.Context(), t)
+			_, err := loader.Load(ctx, mustParseURL(tt.ref))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, loaded)
+		})
+	}
+}
+
+func TestMirrorLoader_InvalidMirror(t *testing.T) {
+	t.Parallel()
+	loader := mirrorLoader{
+		inner:   DummyLoader{LoadFunc: func(context.Context, *url.URL) (*Schema, error) { return &Schema{}, nil }},
+		mirrors: map[string]string{"https://example.com/": "::"},
+	}
+	_, err := loader.Load(t.Context(), mustParseURL("https://example.com/schema.json"))
+	assert.ErrorContains(t, err, `rewrite $ref="https://example.com/schema.json" using mirror "https://example.com/"`)
+}
+
+func TestMirrorLoader_KeepsOriginalID(t *testing.T) {
+	t.Parallel()
+	loader := mirrorLoader{
+		inner: DummyLoader{
+			LoadFunc: func(_ context.Context, ref *url.URL) (*Schema, error) {
+				if ref.String() != "https://mirror.corp/schemastore/chart.json" {
+					return nil, fmt.Errorf("unexpected ref: %s", ref)
+				}
+				return &Schema{Type: "object"}, nil
+			},
+		},
+		mirrors: map[string]string{"https://json.schemastore.org/": "https://mirror.corp/schemastore/"},
+	}
+	root := &Schema{
+		Properties: map[string]*Schema{
+			"chart": {Ref: "https://json.schemastore.org/chart.json"},
+		},
+	}
+
+	ctx := ContextWithLogger(t.Context(), t)
+	require.NoError(t, bundleWithLoader(ctx, loader, root, "/", false))
+	assert.Equal(t, "https://json.schemastore.org/chart.json", root.Properties["chart"].Ref)
+	require.Contains(t, root.Defs, "chart.json")
+	assert.Equal(t, "https://json.schemastore.org/chart.json", root.Defs["chart.json"].ID)
+}
@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// chartScheme is the URL scheme used in "$ref: chart:path/to/chart.tgz" references.
+const chartScheme = "chart"
+
+// chartValuesSchemaFile is the name of the schema file inside a Helm chart.
+const chartValuesSchemaFile = "values.schema.json"
+
+// ChartLoader loads the "values.schema.json" file from a packaged Helm chart
+// archive, from a "$ref: chart:path/to/chart.tgz" reference on the local
+// file-system.
+type ChartLoader struct {
+	fileLoader FileLoader
+}
+
+// NewChartLoader returns a new chart loader that reads files using the given [FileLoader].
+func NewChartLoader(fileLoader FileLoader) ChartLoader {
+	return ChartLoader{fileLoader: fileLoader}
+}
+
+var _ Loader = ChartLoader{}
+
+// Load implements [Loader].
+func (loader ChartLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
+	if ref.Scheme != chartScheme {
+		return nil, fmt.Errorf(`chart url in $ref=%q must start with "chart:"`, ref)
+	}
+	if ref.User != nil {
+		return nil, fmt.Errorf("chart url in $ref=%q: user info not supported", ref)
+	}
+	if ref.RawQuery != "" {
+		return nil, fmt.Errorf("chart url in $ref=%q: query parameters not supported", ref)
+	}
+	chartPath, _ := localSchemeRefPath(ref)
+	if chartPath == "" {
+		return nil, fmt.Errorf("chart url in $ref=%q must contain a path", ref)
+	}
+
+	path, b, err := loader.fileLoader.readFile(ctx, filepath.FromSlash(chartPath))
+	if err != nil {
+		return nil, err
+	}
+	schema, err := loadChartSchema(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// loadChartSchema reads the "values.schema.json" file from the chart's root
+// directory inside a gzipped Helm chart archive. The schema files of any
+// subcharts inside the "charts/" directory are ignored.
+func loadChartSchema(archive []byte) (*Schema, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("read chart archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("chart archive does not contain a %s file", chartValuesSchemaFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read chart archive: %w", err)
+		}
+		chartDir, file, _ := strings.Cut(strings.TrimPrefix(header.Name, "./"), "/")
+		if chartDir == "" || file != chartValuesSchemaFile || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", header.Name, err)
+		}
+		var schema Schema
+		if err := json.Unmarshal(b, &schema); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", header.Name, err)
+		}
+		return &schema, nil
+	}
+}
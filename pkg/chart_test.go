@@ -0,0 +1,202 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createChartArchive returns a gzipped tar archive with the given files,
+// in the order of the name and content pairs.
+func createChartArchive(t *testing.T, nameAndContent ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i+1 < len(nameAndContent); i += 2 {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     nameAndContent[i],
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(nameAndContent[i+1])),
+		}))
+		_, err := tw.Write([]byte(nameAndContent[i+1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestChartLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"charts/mylib-1.0.0.tgz": {Data: createChartArchive(t,
+			"mylib/Chart.yaml", "name: mylib",
+			"mylib/charts/sub/values.schema.json", `{"type": "string"}`,
+			"mylib/values.schema.json", `{"type": "object"}`,
+		)},
+	}
+
+	tests := []struct {
+		name string
+		url  *url.URL
+	}{
+		{name: "opaque", url: mustParseURL("chart:charts/mylib-1.0.0.tgz")},
+		{name: "with slashes", url: mustParseURL("chart://charts/mylib-1.0.0.tgz")},
+		{name: "with fragment", url: mustParseURL("chart:charts/mylib-1.0.0.tgz#/properties/foo")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewChartLoader(NewFileLoader(fsys, ""))
+			ctx := ContextWithLogger(t.Context(), t)
+			schema, err := loader.Load(ctx, tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, &Schema{Type: "object"}, schema)
+		})
+	}
+}
+
+func TestChartLoader_Testdata(t *testing.T) {
+	root, err := os.OpenRoot(filepath.FromSlash("../testdata/bundle"))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, root.Close())
+	}()
+
+	loader := NewChartLoader(NewFileLoader((*RootFS)(root), ""))
+	ctx := ContextWithLogger(t.Context(), t)
+	schema, err := loader.Load(ctx, mustParseURL("chart:charts/mylib-1.0.0.tgz"))
+	require.NoError(t, err)
+	assert.Contains(t, schema.Properties, "image")
+}
+
+func TestChartLoader_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     *url.URL
+		wantErr string
+	}{
+		{
+			name:    "invalid scheme",
+			url:     mustParseURL("file:chart.tgz"),
+			wantErr: `chart url in $ref="file:chart.tgz" must start with "chart:"`,
+		},
+		{
+			name:    "user info",
+			url:     mustParseURL("chart://user@chart.tgz"),
+			wantErr: `chart url in $ref="chart://user@chart.tgz": user info not supported`,
+		},
+		{
+			name:    "query",
+			url:     mustParseURL("chart:chart.tgz?version=1.0.0"),
+			wantErr: `chart url in $ref="chart:chart.tgz?version=1.0.0": query parameters not supported`,
+		},
+		{
+			name:    "empty path",
+			url:     mustParseURL("chart://"),
+			wantErr: `chart url in $ref="chart:" must contain a path`,
+		},
+		{
+			name:    "file not found",
+			url:     mustParseURL("chart:does-not-exist.tgz"),
+			wantErr: `open does-not-exist.tgz: file does not exist`,
+		},
+		{
+			name:    "invalid archive",
+			url:     mustParseURL("chart:invalid.tgz"),
+			wantErr: `invalid.tgz: read chart archive: gzip: invalid header`,
+		},
+	}
+
+	fsys := fstest.MapFS{
+		"invalid.tgz": {Data: []byte("not a gzip file")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewChartLoader(NewFileLoader(fsys, ""))
+			ctx := ContextWithLogger(t.Context(), t)
+			_, err := loader.Load(ctx, tt.url)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadChartSchema_Error(t *testing.T) {
+	truncated := func() []byte {
+		var tarBuf bytes.Buffer
+		tw := tar.NewWriter(&tarBuf)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "mylib/values.schema.json", Typeflag: tar.TypeReg, Size: 100}))
+		_, err := tw.Write([]byte("{}"))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err = gz.Write(tarBuf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{
+			name:    "invalid gzip",
+			archive: []byte("not a gzip file"),
+			wantErr: "read chart archive: gzip: invalid header",
+		},
+		{
+			name: "invalid tar",
+			archive: func() []byte {
+				var buf bytes.Buffer
+				gz := gzip.NewWriter(&buf)
+				_, err := gz.Write([]byte("foo"))
+				require.NoError(t, err)
+				require.NoError(t, gz.Close())
+				return buf.Bytes()
+			}(),
+			wantErr: "read chart archive: unexpected EOF",
+		},
+		{
+			name:    "no schema",
+			archive: createChartArchive(t, "mylib/Chart.yaml", "name: mylib"),
+			wantErr: "chart archive does not contain a values.schema.json file",
+		},
+		{
+			name: "ignores subchart and root schemas",
+			archive: createChartArchive(t,
+				"values.schema.json", "{}",
+				"mylib/charts/sub/values.schema.json", "{}",
+			),
+			wantErr: "chart archive does not contain a values.schema.json file",
+		},
+		{
+			name:    "invalid JSON",
+			archive: createChartArchive(t, "./mylib/values.schema.json", "{"),
+			wantErr: "parse ./mylib/values.schema.json: unexpected end of JSON input",
+		},
+		{
+			name:    "truncated file",
+			archive: truncated(),
+			wantErr: "read mylib/values.schema.json: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadChartSchema(tt.archive)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -41,7 +42,21 @@ func NewCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return GenerateJsonSchema(cmd.Context(), config)
+			if err := validateFormat(config.Format); err != nil {
+				return err
+			}
+			err = GenerateJsonSchema(cmd.Context(), config)
+			if format := cmp.Or(config.Format, FormatText); format != FormatText {
+				// Same as lint, an empty list of diagnostics is written on success
+				var diagnostics []LintIssue
+				if err != nil {
+					diagnostics = diagnosticsFromError(err)
+				}
+				if writeErr := writeDiagnostics(cmd.OutOrStdout(), format, diagnostics); writeErr != nil {
+					return errors.Join(err, writeErr)
+				}
+			}
+			return err
 		},
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -63,6 +78,11 @@ func NewCmd() *cobra.Command {
 	cmd.AddCommand(versionCmd)
 	cmd.AddCommand(newLintCmd())
 	cmd.AddCommand(newBundleCmd())
+	cmd.AddCommand(newUnbundleCmd())
+	cmd.AddCommand(newValuesFromSchemaCmd())
+	cmd.AddCommand(newAnnotateCmd())
+	cmd.AddCommand(newMigrateHelmDocsCmd())
+	cmd.AddCommand(newCacheCmd())
 
 	cmd.PersistentFlags().String("config", ".schema.yaml", "Config file for setting defaults.")
 
@@ -71,9 +91,18 @@ func NewCmd() *cobra.Command {
 	cmd.Flags().Int("draft", DefaultConfig.Draft, "Draft version (4, 6, 7, 2019, or 2020)")
 	cmd.Flags().Bool("no-additional-properties", false, "Default additionalProperties to false for all objects in the schema, or unevaluatedProperties where properties also come from a $ref or allOf")
 	cmd.Flags().Bool("no-default-global", false, "Disable automatic injection of 'global' property when schema root does not allow it")
+	cmd.Flags().String("merge-strategy", DefaultConfig.MergeStrategy, "How to merge conflicting types from multiple values files: override (last file wins), union (e.g [\"integer\", \"null\"]), or strict (fail)")
+	cmd.Flags().String("items-mode", DefaultConfig.ItemsMode, "How to infer the schema of array items: merge (all items into one schema), tuple (prefixItems by position, requires --draft 2020), or union (anyOf the distinct item schemas)")
+	cmd.Flags().Bool("infer-examples", false, "Add the distinct values of each key from all values files as examples")
+	cmd.Flags().Bool("infer-formats", false, "Add format or pattern to string values recognized as e.g URIs, durations, or Kubernetes quantities")
+	cmd.Flags().Bool("anchor-refs", false, "Add each YAML anchor that is used by an alias to $defs, and reference it using $ref")
+	cmd.Flags().Bool("auto-k8s-refs", false, "Add $ref: $k8s/... to well-known keys such as resources, affinity and tolerations")
+	cmd.Flags().Bool("placeholders", false, "Treat empty {}, [] and null values as placeholders that also allow non-empty values")
+	cmd.Flags().Bool("validate-metaschema", false, "Fail when the generated schema does not conform to the metaschema of its JSON Schema draft")
 
 	cmd.Flags().Bool("bundle", false, "Bundle referenced ($ref) subschemas into a single file inside $defs")
 	registerSharedFlags(cmd.Flags())
+	registerFormatFlag(cmd.Flags())
 
 	cmd.Flags().Bool("use-helm-docs", false, "Read description from https://github.com/norwoodj/helm-docs comments")
 
@@ -150,15 +179,22 @@ func LoadConfig(cmd *cobra.Command) (*Config, error) {
 	}
 
 	config.SchemaRoot.RefReferrer = refReferrer
+	if len(config.RefAliases) == 0 {
+		// koanf decodes an unset map of structs as an empty map
+		config.RefAliases = nil
+	}
 
 	return &config, nil
 }
 
 var DefaultConfig = Config{
-	Values: []string{"values.yaml"},
-	Output: "values.schema.json",
-	Draft:  2020,
-	Indent: 4,
+	Values:        []string{"values.yaml"},
+	Output:        "values.schema.json",
+	Draft:         2020,
+	Indent:        4,
+	Format:        FormatText,
+	MergeStrategy: MergeStrategyOverride,
+	ItemsMode:     ItemsModeMerge,
 
 	K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
 }
@@ -171,16 +207,36 @@ type Config struct {
 	Indent                 int      `yaml:"indent" koanf:"indent"`
 	NoAdditionalProperties bool     `yaml:"noAdditionalProperties" koanf:"no-additional-properties"`
 	NoDefaultGlobal        bool     `yaml:"noDefaultGlobal" koanf:"no-default-global"`
+	ValidateMetaschema     bool     `yaml:"validateMetaschema" koanf:"validate-metaschema"`
+	Format                 string   `yaml:"format" koanf:"format"`
+	MergeStrategy          string   `yaml:"mergeStrategy" koanf:"merge-strategy"`
+	ItemsMode              string   `yaml:"itemsMode" koanf:"items-mode"`
+	InferExamples          bool     `yaml:"inferExamples" koanf:"infer-examples"`
+	InferFormats           bool     `yaml:"inferFormats" koanf:"infer-formats"`
+	AnchorRefs             bool     `yaml:"anchorRefs" koanf:"anchor-refs"`
 	Bundle                 bool     `yaml:"bundle" koanf:"bundle"`
 	BundleRoot             string   `yaml:"bundleRoot" koanf:"bundle-root"`
 	BundleWithoutID        bool     `yaml:"bundleWithoutID" koanf:"bundle-without-id"`
 	BundleCacheMin         string   `yaml:"bundleCacheMin" koanf:"bundle-cache-min"`
+	BundleCacheDir         string   `yaml:"bundleCacheDir" koanf:"bundle-cache-dir"`
+	BundleCacheMaxSize     string   `yaml:"bundleCacheMaxSize" koanf:"bundle-cache-max-size"`
+
+	RefMirrors map[string]string   `yaml:"refMirrors" koanf:"ref-mirrors"`
+	RefAliases map[string]RefAlias `yaml:"refAliases" koanf:"ref-aliases"`
 
 	K8sSchemaURL     string `yaml:"k8sSchemaURL" koanf:"k8s-schema-url"`
 	K8sSchemaVersion string `yaml:"k8sSchemaVersion" koanf:"k8s-schema-version"`
 
+	AutoK8sRefs bool              `yaml:"autoK8sRefs" koanf:"auto-k8s-refs"`
+	K8sRefs     map[string]string `yaml:"k8sRefs" koanf:"k8s-refs"`
+
+	Placeholders     bool              `yaml:"placeholders" koanf:"placeholders"`
+	PlaceholderTypes map[string]string `yaml:"placeholderTypes" koanf:"placeholder-types"`
+
 	UseHelmDocs bool `yaml:"useHelmDocs" koanf:"use-helm-docs"`
 
+	Lint LintConfig `yaml:"lint" koanf:"lint"`
+
 	SchemaRoot SchemaRoot `yaml:"schemaRoot" koanf:"schema-root"`
 }
 
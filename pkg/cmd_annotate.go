@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// newAnnotateCmd creates the "annotate" subcommand, which writes the
+// "# @schema" annotations of an existing JSON schema file into the values
+// files, so that generating a schema from them reproduces the schema.
+func newAnnotateCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "annotate [SCHEMA_FILE]",
+		Short: "Write the \"# @schema\" annotations of a JSON schema file into the values files",
+		Long: "Annotate reads an existing JSON schema file, such as one maintained by hand, and " +
+			"inserts the equivalent \"# @schema\" annotations above the matching keys in the input " +
+			"values files, so that generating a schema from the values files reproduces the schema.\n\n" +
+			"Existing comments, formatting and helm-docs comments are preserved, and annotations " +
+			"that a key already has are not added again. The schema file defaults to the output " +
+			"setting (values.schema.json), and the values files and useHelmDocs setting are read " +
+			"from the config file (.schema.yaml), where flags take precedence over the config file.",
+		Example: `  # Annotate values.yaml using values.schema.json
+  helm schema annotate
+
+  # Show the annotations that would be added, without writing them
+  helm schema annotate --dry-run
+
+  # Annotate multiple values files using another schema file
+  helm schema annotate upstream.schema.json -f values.yaml,values-prod.yaml`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := LoadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			// The "output" flag is only registered on the generate command
+			schemaFile := cmp.Or(config.Output, DefaultConfig.Output)
+			if len(args) > 0 {
+				schemaFile = args[0]
+			}
+			return AnnotateFile(cmd.Context(), cmd.OutOrStdout(), AnnotateFileOptions{
+				SchemaFile:  schemaFile,
+				ValuesFiles: config.Values,
+				UseHelmDocs: config.UseHelmDocs,
+				DryRun:      dryRun,
+			})
+		},
+	}
+
+	cmd.Flags().StringSliceP("values", "f", DefaultConfig.Values, "One or more YAML files as inputs. Use comma-separated list or supply flag multiple times")
+	cmd.Flags().Bool("use-helm-docs", false, "Read description from https://github.com/norwoodj/helm-docs comments")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the annotations to add instead of writing the values files")
+
+	return cmd
+}
+
+// AnnotateFileOptions holds the inputs for [AnnotateFile].
+type AnnotateFileOptions struct {
+	// SchemaFile is the path to the JSON schema file to read annotations from.
+	SchemaFile string
+	// ValuesFiles are the paths to the values files to annotate.
+	ValuesFiles []string
+	// UseHelmDocs leaves out the "description" annotation on keys that have
+	// a helm-docs comment, as the description is read from there instead.
+	UseHelmDocs bool
+	// DryRun writes a unified diff of each values file to the [io.Writer]
+	// passed to [AnnotateFile], instead of writing the values files.
+	DryRun bool
+}
+
+// AnnotateFile reads the JSON schema file referenced by opts.SchemaFile and
+// inserts the "# @schema" annotations of its properties above the matching
+// keys of each values file, leaving the rest of the files untouched.
+//
+// Values files that don't need any annotations are left untouched.
+func AnnotateFile(ctx context.Context, out io.Writer, opts AnnotateFileOptions) error {
+	content, err := os.ReadFile(filepath.Clean(opts.SchemaFile))
+	if err != nil {
+		return fmt.Errorf("read schema file: %w", err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return fmt.Errorf("parse schema file %q: %w", opts.SchemaFile, err)
+	}
+
+	for _, valuesFile := range opts.ValuesFiles {
+		err := updateValuesFile(ctx, out, valuesFile, opts.DryRun, func(content []byte) (valuesInsertions, error) {
+			insertions, err := annotateValues(ctx, &schema, content, opts.UseHelmDocs)
+			if err != nil {
+				return nil, fmt.Errorf("annotate %s: %w", valuesFile, err)
+			}
+			return insertions, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateValuesFile inserts the lines returned by getInsertions into the
+// values file, keeping its file permissions. When dryRun is set, a unified
+// diff is written to out instead.
+func updateValuesFile(ctx context.Context, out io.Writer, valuesFile string, dryRun bool, getInsertions func(content []byte) (valuesInsertions, error)) error {
+	logger := LoggerFromContext(ctx)
+
+	path := filepath.Clean(valuesFile)
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("read values file: %w", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read values file: %w", err)
+	}
+
+	insertions, err := getInsertions(content)
+	if err != nil {
+		return err
+	}
+	if len(insertions) == 0 {
+		logger.Log("No annotations to add to", valuesFile)
+		return nil
+	}
+
+	if dryRun {
+		if _, err := io.WriteString(out, insertions.diff(valuesFile, content)); err != nil {
+			return fmt.Errorf("write diff: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(path, insertions.apply(content), stat.Mode().Perm()); err != nil {
+		return fmt.Errorf("write values file: %w", err)
+	}
+	logger.Logf("Added annotations to %d key(s) in %s", len(insertions), valuesFile)
+	return nil
+}
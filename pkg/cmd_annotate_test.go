@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyAnnotateTestdata copies the annotate test data into a temporary
+// directory, so the values file can be written to.
+func copyAnnotateTestdata(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"values.schema.json", "values.yaml"} {
+		content, err := os.ReadFile(filepath.Join("../testdata/annotate", name))
+		require.NoError(t, err)
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0600))
+	}
+	return dir
+}
+
+func TestAnnotateCmd(t *testing.T) {
+	golden, err := os.ReadFile("../testdata/annotate/annotated.yaml")
+	require.NoError(t, err)
+	golden = bytes.ReplaceAll(golden, []byte("\r\n"), []byte("\n"))
+
+	dir := copyAnnotateTestdata(t)
+	valuesFile := filepath.Join(dir, "values.yaml")
+	schemaFile := filepath.Join(dir, "values.schema.json")
+
+	cmd := NewCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"annotate", "--use-helm-docs", "-f", valuesFile, schemaFile})
+	require.NoError(t, cmd.Execute())
+
+	content, err := os.ReadFile(valuesFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(content))
+
+	// Running it again is a no-op
+	cmd = NewCmd()
+	buf.Reset()
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"annotate", "--use-helm-docs", "-f", valuesFile, schemaFile})
+	require.NoError(t, cmd.Execute())
+
+	content, err = os.ReadFile(valuesFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(content))
+}
+
+func TestAnnotateCmd_DryRun(t *testing.T) {
+	dir := copyAnnotateTestdata(t)
+	valuesFile := filepath.Join(dir, "values.yaml")
+	original, err := os.ReadFile(valuesFile)
+	require.NoError(t, err)
+
+	cmd := NewCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"annotate", "--dry-run", "-f", valuesFile, filepath.Join(dir, "values.schema.json")})
+	require.NoError(t, cmd.Execute())
+
+	want := "--- " + valuesFile + "\n" +
+		"+++ " + valuesFile + "\n" +
+		"@@ -1,7 +1,10 @@\n" +
+		" # Number of replicas\n" +
+		"+# @schema minimum: 1; required\n" +
+		" replicaCount: 1\n" +
+		" \n" +
+		` image:
++  # @schema enum: ["IfNotPresent","Always","Never"]
+   pullPolicy: IfNotPresent
++  # @schema description: Overrides the image tag
+   # -- Image tag
+   tag: latest
+`
+	assert.Equal(t, want, buf.String())
+
+	content, err := os.ReadFile(valuesFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(original), string(content))
+}
+
+func TestAnnotateCmd_DefaultSchemaFile(t *testing.T) {
+	golden, err := os.ReadFile("../testdata/annotate/annotated.yaml")
+	require.NoError(t, err)
+	golden = bytes.ReplaceAll(golden, []byte("\r\n"), []byte("\n"))
+
+	dir := copyAnnotateTestdata(t)
+	t.Chdir(dir)
+
+	cmd := NewCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"annotate", "--use-helm-docs"})
+	require.NoError(t, cmd.Execute())
+
+	content, err := os.ReadFile(filepath.Join(dir, "values.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(content))
+}
+
+func TestAnnotateCmd_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		setup   func(t *testing.T, dir string)
+		wantErr string
+	}{
+		{
+			name:    "missing schema file",
+			args:    []string{"annotate", "does-not-exist.json"},
+			wantErr: "read schema file",
+		},
+		{
+			name: "invalid schema file",
+			args: []string{"annotate", "invalid.json"},
+			setup: func(t *testing.T, dir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0600))
+			},
+			wantErr: `parse schema file "invalid.json"`,
+		},
+		{
+			name:    "missing values file",
+			args:    []string{"annotate", "-f", "does-not-exist.yaml"},
+			wantErr: "read values file",
+		},
+		{
+			name: "invalid values file",
+			args: []string{"annotate", "-f", "invalid.yaml"},
+			setup: func(t *testing.T, dir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.yaml"), []byte("a: ["), 0600))
+			},
+			wantErr: "annotate invalid.yaml: error unmarshalling YAML",
+		},
+		{
+			name:    "missing config file",
+			args:    []string{"annotate", "--config", "does-not-exist.yaml"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "too many args",
+			args:    []string{"annotate", "a.json", "b.json"},
+			wantErr: "accepts at most 1 arg(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := copyAnnotateTestdata(t)
+			t.Chdir(dir)
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+
+			cmd := NewCmd()
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+			cmd.SetArgs(tt.args)
+			assert.ErrorContains(t, cmd.Execute(), tt.wantErr)
+		})
+	}
+}
+
+func TestAnnotateFile_WriteError(t *testing.T) {
+	dir := copyAnnotateTestdata(t)
+	err := AnnotateFile(ContextWithLogger(t.Context(), t), errWriter{}, AnnotateFileOptions{
+		SchemaFile:  filepath.Join(dir, "values.schema.json"),
+		ValuesFiles: []string{filepath.Join(dir, "values.yaml")},
+		DryRun:      true,
+	})
+	assert.ErrorContains(t, err, "write diff")
+}
@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -21,54 +22,58 @@ var (
 
 // newBundleCmd creates the "bundle" subcommand, which reads an existing JSON
 // schema file, bundles all its "$ref" subschemas into "$defs", and prints the
-// result to stdout.
+// result to stdout or writes it to a file.
 func newBundleCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "bundle SCHEMA_FILE",
 		Short: "Bundle referenced ($ref) subschemas of a JSON schema file into $defs",
 		Long: "Bundle reads an existing JSON schema file, resolves all its \"$ref\" " +
-			"subschemas, stores them inside \"$defs\", and prints the bundled schema to stdout.\n\n" +
+			"subschemas, stores them inside \"$defs\", and prints the bundled schema to stdout " +
+			"or writes it to the --output file.\n\n" +
 			"This is the same bundling that \"helm schema --bundle\" performs while generating a " +
-			"schema, but applied to an already-existing schema file.",
+			"schema, but applied to an already-existing schema file. Settings such as bundleRoot, " +
+			"indent and k8sSchemaVersion are read from the config file (.schema.yaml), " +
+			"where flags take precedence over the config file.",
 		Example: `  # Bundle a schema file and print the result to stdout
   helm schema bundle values.schema.json
 
   # Bundle local references located outside the current directory
-  helm schema bundle values.schema.json --bundle-root ..`,
+  helm schema bundle values.schema.json --bundle-root ..
+
+  # Bundle a schema file and write the result to a file
+  helm schema bundle values.schema.json --output values.bundled.schema.json`,
 		Args:          cobra.ExactArgs(1),
 		SilenceErrors: true,
 		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// bundle does not call LoadConfig, so the inherited --config flag (and
-			// .schema.yaml) has no effect here. Warn when it is set explicitly so
-			// the silently-ignored config does not surprise the user.
-			if cmd.Flags().Changed("config") {
-				_, _ = fmt.Fprintln(cmd.ErrOrStderr(),
-					"warning: --config (and .schema.yaml) is ignored by the bundle command; "+
-						"pass --bundle-root, --indent and --k8s-schema-version directly")
+			config, err := LoadConfig(cmd)
+			if err != nil {
+				return err
 			}
 
-			// All flags are registered below, so these getters cannot fail; their
-			// errors are ignored to avoid an unreachable, uncoverable error branch.
-			indent, _ := cmd.Flags().GetInt("indent")
-			bundleRoot, _ := cmd.Flags().GetString("bundle-root")
-			bundleWithoutID, _ := cmd.Flags().GetBool("bundle-without-id")
-			cacheMin, _ := cmd.Flags().GetString("bundle-cache-min")
-			k8sSchemaURL, _ := cmd.Flags().GetString("k8s-schema-url")
-			k8sSchemaVersion, _ := cmd.Flags().GetString("k8s-schema-version")
+			// The "output" config field is the output of the generate command,
+			// so only the flag is used here to not overwrite that file by accident.
+			// The flag is registered below, so this getter cannot fail.
+			output, _ := cmd.Flags().GetString("output")
 
 			return BundleFile(cmd.Context(), cmd.OutOrStdout(), BundleFileOptions{
 				InputFile:        args[0],
-				Indent:           indent,
-				BundleRoot:       bundleRoot,
-				BundleWithoutID:  bundleWithoutID,
-				CacheMin:         cacheMin,
-				K8sSchemaURL:     k8sSchemaURL,
-				K8sSchemaVersion: k8sSchemaVersion,
+				Output:           output,
+				Indent:           config.Indent,
+				BundleRoot:       config.BundleRoot,
+				BundleWithoutID:  config.BundleWithoutID,
+				CacheMin:         config.BundleCacheMin,
+				CacheMaxSize:     config.BundleCacheMaxSize,
+				RefMirrors:       config.RefMirrors,
+				RefAliases:       config.RefAliases,
+				CacheDir:         config.BundleCacheDir,
+				K8sSchemaURL:     config.K8sSchemaURL,
+				K8sSchemaVersion: config.K8sSchemaVersion,
 			})
 		},
 	}
 
+	cmd.Flags().StringP("output", "o", "-", "Output file path, or \"-\" to print to stdout")
 	registerSharedFlags(cmd.Flags())
 
 	return cmd
@@ -78,6 +83,9 @@ func newBundleCmd() *cobra.Command {
 type BundleFileOptions struct {
 	// InputFile is the path to the JSON schema file to bundle.
 	InputFile string
+	// Output is the path of the file to write the bundled schema to.
+	// An empty string or "-" writes to the [io.Writer] passed to [BundleFile].
+	Output string
 	// Indent is the number of spaces used to indent the bundled JSON output.
 	Indent int
 	// BundleRoot, BundleWithoutID, K8sSchemaURL and K8sSchemaVersion are passed
@@ -86,15 +94,26 @@ type BundleFileOptions struct {
 	BundleWithoutID  bool
 	K8sSchemaURL     string
 	K8sSchemaVersion string
+	// CacheDir is the directory of the HTTP cache. See [HTTPCacheDir].
+	CacheDir string
 	// CacheMin is the raw --bundle-cache-min value (e.g. "24h"); it is parsed by
 	// [ParseCacheMinDuration] and passed through to [Bundle] to raise the minimum
 	// cache duration for downloaded schemas. An empty string means no override.
 	CacheMin string
+	// CacheMaxSize is the raw --bundle-cache-max-size value (e.g. "100MB"); it
+	// is parsed by [ParseCacheMaxSize] and passed through to [Bundle].
+	CacheMaxSize string
+	// RefMirrors and RefAliases are passed through to [Bundle].
+	// See [BundleOptions.RefMirrors] and [BundleOptions.RefAliases].
+	RefMirrors map[string]string
+	RefAliases map[string]RefAlias
 }
 
 // BundleFile reads the JSON schema file referenced by opts.InputFile, bundles
 // its "$ref" subschemas into "$defs" using [Bundle], and writes the indented
-// result to out.
+// result to opts.Output, or to out when opts.Output is empty or "-".
+//
+// The output file is left untouched when its content is already up to date.
 func BundleFile(ctx context.Context, out io.Writer, opts BundleFileOptions) error {
 	if opts.Indent <= 0 {
 		return errors.New("indentation must be a positive number")
@@ -103,25 +122,49 @@ func BundleFile(ctx context.Context, out io.Writer, opts BundleFileOptions) erro
 		return errors.New("indentation must be an even number")
 	}
 
-	cacheMinDuration, err := ParseCacheMinDuration(opts.CacheMin)
+	schema, err := bundleSchemaFile(ctx, opts)
 	if err != nil {
 		return err
 	}
 
+	jsonBytes, err := json.MarshalIndent(schema, "", strings.Repeat(" ", opts.Indent))
+	if err != nil || failBundleFileMarshal {
+		return fmt.Errorf("encode bundled schema: %w", err)
+	}
+	jsonBytes = append(jsonBytes, '\n')
+
+	if err := writeOutputFile(out, filepath.FromSlash(cmp.Or(opts.Output, "-")), jsonBytes); err != nil {
+		return fmt.Errorf("write bundled schema: %w", err)
+	}
+	return nil
+}
+
+// bundleSchemaFile reads the JSON schema file referenced by opts.InputFile and
+// bundles its "$ref" subschemas into "$defs" using [Bundle].
+func bundleSchemaFile(ctx context.Context, opts BundleFileOptions) (*Schema, error) {
+	cacheMinDuration, err := ParseCacheMinDuration(opts.CacheMin)
+	if err != nil {
+		return nil, err
+	}
+	cacheMaxSize, err := ParseCacheMaxSize(opts.CacheMaxSize)
+	if err != nil {
+		return nil, err
+	}
+
 	content, err := os.ReadFile(filepath.Clean(opts.InputFile))
 	if err != nil {
-		return fmt.Errorf("read schema file: %w", err)
+		return nil, fmt.Errorf("read schema file: %w", err)
 	}
 
 	var schema Schema
 	if err := json.Unmarshal(content, &schema); err != nil {
-		return fmt.Errorf("parse schema file %q: %w", opts.InputFile, err)
+		return nil, fmt.Errorf("parse schema file %q: %w", opts.InputFile, err)
 	}
 
 	// Resolve "$ref" relative to the input file's directory.
 	inputAbs, err := filepath.Abs(opts.InputFile)
 	if err != nil || failBundleFileAbs {
-		return fmt.Errorf("get absolute path of %q: %w", opts.InputFile, err)
+		return nil, fmt.Errorf("get absolute path of %q: %w", opts.InputFile, err)
 	}
 	schema.SetReferrer(ReferrerDir(filepath.Dir(inputAbs)))
 
@@ -129,18 +172,18 @@ func BundleFile(ctx context.Context, out io.Writer, opts BundleFileOptions) erro
 	// the filename internally to derive the directory used as the cosmetic base
 	// for relative $ref/$id paths. Passing the input file path makes those paths
 	// relative to the schema file's own directory, matching the generate command.
-	if err := Bundle(ctx, &schema, inputAbs, opts.BundleRoot, opts.BundleWithoutID, opts.K8sSchemaURL, opts.K8sSchemaVersion, cacheMinDuration); err != nil {
-		return err
-	}
-
-	jsonBytes, err := json.MarshalIndent(&schema, "", strings.Repeat(" ", opts.Indent))
-	if err != nil || failBundleFileMarshal {
-		return fmt.Errorf("encode bundled schema: %w", err)
+	if err := Bundle(ctx, &schema, inputAbs, BundleOptions{
+		BundleRoot:       opts.BundleRoot,
+		WithoutIDs:       opts.BundleWithoutID,
+		K8sSchemaURL:     opts.K8sSchemaURL,
+		K8sSchemaVersion: opts.K8sSchemaVersion,
+		CacheMinDuration: cacheMinDuration,
+		CacheDir:         opts.CacheDir,
+		CacheMaxSize:     cacheMaxSize,
+		RefMirrors:       opts.RefMirrors,
+		RefAliases:       opts.RefAliases,
+	}); err != nil {
+		return nil, err
 	}
-	jsonBytes = append(jsonBytes, '\n')
-
-	if _, err := out.Write(jsonBytes); err != nil {
-		return fmt.Errorf("write bundled schema: %w", err)
-	}
-	return nil
+	return &schema, nil
 }
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -48,12 +50,28 @@ func TestBundleCmd(t *testing.T) {
 			},
 		},
 		{
-			name: "config flag warns it is ignored",
-			args: []string{"bundle", "--config", ".schema.yaml", "--bundle-root", "../testdata/bundle", "../testdata/bundle/cmd.schema.json"},
+			name: "config file",
+			args: []string{"bundle", "--config", "../testdata/bundle/cmd.config.yaml", "../testdata/bundle/cmd.schema.json"},
 			wantContain: []string{
-				"warning: --config (and .schema.yaml) is ignored by the bundle command",
-				`"$defs"`,
+				"\n  \"type\": \"object\",",
+				`"$ref": "#/$defs/simple-subschema.schema.json"`,
 			},
+			wantMissing: []string{`"$id"`},
+		},
+		{
+			name:    "flags override config file",
+			args:    []string{"bundle", "--config", "../testdata/bundle/cmd.config.yaml", "--indent", "4", "--bundle-without-id=false", "../testdata/bundle/cmd.schema.json"},
+			wantOut: string(golden),
+		},
+		{
+			name:    "missing config file",
+			args:    []string{"bundle", "--config", "../testdata/bundle/does-not-exist.yaml", "../testdata/bundle/cmd.schema.json"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "output to stdout",
+			args:    []string{"bundle", "--output", "-", "--bundle-root", "../testdata/bundle", "../testdata/bundle/cmd.schema.json"},
+			wantOut: string(golden),
 		},
 		{
 			name:    "odd indent",
@@ -116,6 +134,43 @@ func TestBundleCmd(t *testing.T) {
 	}
 }
 
+func TestBundleCmd_OutputFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "bundled.schema.json")
+
+	cmd := NewCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"bundle", "--output", output, "--bundle-root", "../testdata/bundle", "../testdata/bundle/cmd.schema.json"})
+	require.NoError(t, cmd.Execute())
+	assert.Empty(t, buf.String())
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"$defs"`)
+}
+
+func TestBundleFile_OutputUnchanged(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "bundled.schema.json")
+	opts := BundleFileOptions{
+		InputFile:  "../testdata/bundle/cmd.schema.json",
+		Output:     output,
+		Indent:     DefaultConfig.Indent,
+		BundleRoot: "../testdata/bundle",
+	}
+	ctx := ContextWithLogger(t.Context(), t)
+	require.NoError(t, BundleFile(ctx, errWriter{}, opts))
+
+	// Set an old modification time to detect if the file is written again
+	oldTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(output, oldTime, oldTime))
+
+	require.NoError(t, BundleFile(ctx, errWriter{}, opts))
+	stat, err := os.Stat(output)
+	require.NoError(t, err)
+	assert.Equal(t, oldTime, stat.ModTime())
+}
+
 func TestBundleFile_IndentValidation(t *testing.T) {
 	tests := []struct {
 		name    string
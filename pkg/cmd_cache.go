@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newCacheCmd creates the "cache" subcommand, which groups commands to
+// inspect, prune and prefetch the HTTP cache used when bundling schemas.
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect, prune and prefetch the cache of downloaded schemas",
+		Long: "Schemas downloaded over HTTP while bundling are cached on disk, based on the " +
+			"Cache-Control response header and the --bundle-cache-min flag.\n\n" +
+			"The cache is stored inside the user's cache directory by default, which can be changed " +
+			"using the --bundle-cache-dir flag, the bundleCacheDir config, or the $" + HTTPCacheDirEnv +
+			" environment variable. For example to persist the cache between CI/CD jobs.",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	cmd.AddCommand(newCachePathCmd())
+	cmd.AddCommand(newCacheListCmd())
+	cmd.AddCommand(newCacheClearCmd())
+	cmd.AddCommand(newCachePrefetchCmd())
+
+	return cmd
+}
+
+// loadHTTPFileCache loads the config and returns the [HTTPFileCache] it points to.
+func loadHTTPFileCache(cmd *cobra.Command) (*HTTPFileCache, error) {
+	config, err := LoadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return NewHTTPCache(config.BundleCacheDir, 0), nil
+}
+
+func newCachePathCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "path",
+		Short:         "Print the directory of the cache",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := loadHTTPFileCache(cmd)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), cache.Dir())
+			return err
+		},
+	}
+	registerCacheDirFlag(cmd.Flags())
+	return cmd
+}
+
+func newCacheListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "list",
+		Short:         "List the cached schemas",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := loadHTTPFileCache(cmd)
+			if err != nil {
+				return err
+			}
+			entries, err := cache.List()
+			if err != nil {
+				return err
+			}
+			return writeCacheList(cmd.OutOrStdout(), cache, entries)
+		},
+	}
+	registerCacheDirFlag(cmd.Flags())
+	return cmd
+}
+
+func writeCacheList(out io.Writer, cache *HTTPFileCache, entries []HTTPFileCacheEntry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "URL\tSIZE\tCACHED AT\tEXPIRES\tETAG")
+	for _, entry := range entries {
+		if entry.Err != nil {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t-\t(invalid: %s)\t-\n", entry.Path, formatSizeBytes(int(entry.Size)), entry.Err)
+			continue
+		}
+		expires := entry.Expiry().Format(time.RFC3339)
+		if cache.Expired(entry) {
+			expires += " (expired)"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			cmp.Or(entry.URL, entry.Path),
+			formatSizeBytes(int(entry.Size)),
+			entry.CachedAt.Format(time.RFC3339),
+			expires,
+			cmp.Or(entry.ETag, "-"))
+	}
+	return w.Flush()
+}
+
+func newCacheClearCmd() *cobra.Command {
+	var expiredOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove cached schemas",
+		Example: `  # Remove all cached schemas
+  helm schema cache clear
+
+  # Only remove cached schemas that have expired
+  helm schema cache clear --expired`,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := loadHTTPFileCache(cmd)
+			if err != nil {
+				return err
+			}
+			removed, err := cache.Clear(expiredOnly)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Removed %d cached file(s)\n", removed)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&expiredOnly, "expired", false, "Only remove cached schemas that have expired")
+	registerCacheDirFlag(cmd.Flags())
+	return cmd
+}
+
+func newCachePrefetchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prefetch SCHEMA_FILE...",
+		Short: "Download the schemas referenced ($ref) by JSON schema files into the cache",
+		Long: "Prefetch bundles the given JSON schema files the same way as \"helm schema bundle\", " +
+			"but discards the result. This only populates the cache, e.g in a separate CI/CD " +
+			"step that runs before the network is restricted.",
+		Example: `  # Populate the cache using the schema generated by "helm schema --bundle=false"
+  helm schema cache prefetch values.schema.json`,
+		Args:          cobra.MinimumNArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := LoadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			for _, file := range args {
+				if err := BundleFile(cmd.Context(), io.Discard, BundleFileOptions{
+					InputFile:        file,
+					Indent:           config.Indent,
+					BundleRoot:       config.BundleRoot,
+					K8sSchemaURL:     config.K8sSchemaURL,
+					K8sSchemaVersion: config.K8sSchemaVersion,
+					CacheMin:         config.BundleCacheMin,
+					CacheMaxSize:     config.BundleCacheMaxSize,
+					RefMirrors:       config.RefMirrors,
+					RefAliases:       config.RefAliases,
+					CacheDir:         config.BundleCacheDir,
+				}); err != nil {
+					return fmt.Errorf("%s: %w", file, err)
+				}
+			}
+			return nil
+		},
+	}
+	registerSharedFlags(cmd.Flags())
+	return cmd
+}
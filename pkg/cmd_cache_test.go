@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/losisin/helm-values-schema-json/v2/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCacheCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := NewCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs(append([]string{"cache"}, args...))
+	cmd.SetContext(ContextWithLogger(t.Context(), t))
+	err := cmd.Execute()
+	return buf.String(), err
+}
+
+func TestCacheCmd_Path(t *testing.T) {
+	testutil.ResetEnvAfterTest(t)
+	require.NoError(t, os.Setenv(HTTPCacheDirEnv, "/from/env"))
+
+	out, err := runCacheCmd(t, "path")
+	require.NoError(t, err)
+	assert.Equal(t, "/from/env\n", out)
+
+	out, err = runCacheCmd(t, "path", "--bundle-cache-dir", "/from/flag")
+	require.NoError(t, err)
+	assert.Equal(t, "/from/flag\n", out)
+}
+
+func TestCacheCmd_Prefetch(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("ETag", `"abc"`)
+		_, _ = w.Write([]byte(`{"type": "string"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cacheDir := testutil.CreateTempDir(t, "schema-httpcache-*")
+	schemaFile := filepath.Join(testutil.CreateTempDir(t, "schema-*"), "values.schema.json")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(`{"$ref": "`+server.URL+`/schema.json"}`), 0600))
+
+	_, err := runCacheCmd(t, "prefetch", "--bundle-cache-dir", cacheDir, schemaFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	// Second prefetch is served from the cache
+	_, err = runCacheCmd(t, "prefetch", "--bundle-cache-dir", cacheDir, schemaFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	out, err := runCacheCmd(t, "list", "--bundle-cache-dir", cacheDir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "URL")
+	assert.Contains(t, out, server.URL+"/schema.json")
+	assert.Contains(t, out, `"abc"`)
+	assert.NotContains(t, out, "(expired)")
+
+	out, err = runCacheCmd(t, "clear", "--expired", "--bundle-cache-dir", cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, "Removed 0 cached file(s)\n", out)
+
+	out, err = runCacheCmd(t, "clear", "--bundle-cache-dir", cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, "Removed 1 cached file(s)\n", out)
+
+	out, err = runCacheCmd(t, "list", "--bundle-cache-dir", cacheDir)
+	require.NoError(t, err)
+	assert.NotContains(t, out, server.URL)
+}
+
+func TestCacheCmd_PrefetchWithoutMaxAge(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"type": "string"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cacheDir := testutil.CreateTempDir(t, "schema-httpcache-*")
+	schemaFile := filepath.Join(testutil.CreateTempDir(t, "schema-*"), "values.schema.json")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(`{"$ref": "`+server.URL+`/schema.json"}`), 0600))
+
+	// Not cached without a max-age
+	_, err := runCacheCmd(t, "prefetch", "--bundle-cache-dir", cacheDir, schemaFile)
+	require.NoError(t, err)
+	out, err := runCacheCmd(t, "list", "--bundle-cache-dir", cacheDir)
+	require.NoError(t, err)
+	assert.NotContains(t, out, server.URL)
+
+	// Cached for the minimum duration
+	_, err = runCacheCmd(t, "prefetch", "--bundle-cache-dir", cacheDir, "--bundle-cache-min", "24h", schemaFile)
+	require.NoError(t, err)
+	out, err = runCacheCmd(t, "list", "--bundle-cache-dir", cacheDir)
+	require.NoError(t, err)
+	assert.Contains(t, out, server.URL+"/schema.json")
+	assert.NotContains(t, out, "(expired)")
+
+	_, err = runCacheCmd(t, "prefetch", "--bundle-cache-dir", cacheDir, "--bundle-cache-min", "24h", schemaFile)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
+
+func TestCacheCmd_List(t *testing.T) {
+	cacheDir := testutil.CreateTempDir(t, "schema-httpcache-*")
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "invalid.cbor.gz"), []byte("foo"), 0600))
+
+	out, err := runCacheCmd(t, "list", "--bundle-cache-dir", cacheDir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "invalid.cbor.gz")
+	assert.Contains(t, out, "(invalid:")
+}
+
+func TestCacheCmd_Errors(t *testing.T) {
+	file := testutil.CreateTempFile(t, "schema-httpcache-*")
+	notADir := filepath.Join(file.Name(), "sub")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "path missing config",
+			args:    []string{"path", "--config", "../testdata/does-not-exist.yaml"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "list missing config",
+			args:    []string{"list", "--config", "../testdata/does-not-exist.yaml"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "list error",
+			args:    []string{"list", "--bundle-cache-dir", notADir},
+			wantErr: "list cache dir:",
+		},
+		{
+			name:    "clear missing config",
+			args:    []string{"clear", "--config", "../testdata/does-not-exist.yaml"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "clear error",
+			args:    []string{"clear", "--bundle-cache-dir", notADir},
+			wantErr: "mkdir:",
+		},
+		{
+			name:    "prefetch no args",
+			args:    []string{"prefetch"},
+			wantErr: "requires at least 1 arg(s)",
+		},
+		{
+			name:    "prefetch missing config",
+			args:    []string{"prefetch", "--config", "../testdata/does-not-exist.yaml", "foo.json"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "prefetch missing file",
+			args:    []string{"prefetch", "../testdata/bundle/does-not-exist.schema.json"},
+			wantErr: "../testdata/bundle/does-not-exist.schema.json: read schema file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runCacheCmd(t, tt.args...)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -4,13 +4,27 @@ import "github.com/spf13/pflag"
 
 // registerSharedFlags registers the flags shared by the root (generate) command
 // and the bundle subcommand, so their names, defaults and usage strings live in
-// one place and cannot drift apart. The root command reads them back through
-// koanf; the bundle subcommand reads them directly from the flag set.
+// one place and cannot drift apart. Both commands read them back through
+// koanf in [LoadConfig], so the flags override the config file.
 func registerSharedFlags(fs *pflag.FlagSet) {
 	fs.Int("indent", DefaultConfig.Indent, "Indentation spaces (even number)")
 	fs.String("bundle-root", "", "Root directory to allow local referenced files to be loaded from (default current working directory)")
 	fs.Bool("bundle-without-id", false, "Bundle without using $id to reference bundled schemas, which improves compatibility with e.g the VS Code JSON extension")
-	fs.String("bundle-cache-min", "", "Minimum cache duration for downloaded schemas, e.g. 24h or 30m. Raises short server Cache-Control max-age values; empty follows the server")
+	registerCacheDirFlag(fs)
+	fs.String("bundle-cache-max-size", "", "Maximum total size of the cache of downloaded schemas, e.g. 100MB or 1GB. Least recently used schemas are removed when exceeded; 0 disables the limit (default 100MB)")
+	fs.String("bundle-cache-min", "", "Minimum cache duration for downloaded schemas, e.g. 24h or 30m. Raises short or missing server Cache-Control max-age values; empty follows the server")
 	fs.String("k8s-schema-url", DefaultConfig.K8sSchemaURL, "URL template used in $ref: $k8s/... alias")
 	fs.String("k8s-schema-version", "", "Version used in the --k8s-schema-url template for $ref: $k8s/... alias")
 }
+
+// registerCacheDirFlag adds the --bundle-cache-dir flag, which is also used
+// on its own by the "cache" subcommands that don't bundle anything.
+func registerCacheDirFlag(fs *pflag.FlagSet) {
+	fs.String("bundle-cache-dir", "", "Directory to cache downloaded schemas in (default $"+HTTPCacheDirEnv+", or the user cache directory)")
+}
+
+// registerFormatFlag adds the --format flag, used by both the root (generate)
+// command and the "lint" subcommand.
+func registerFormatFlag(fs *pflag.FlagSet) {
+	fs.String("format", DefaultConfig.Format, "Format of the reported diagnostics: text, json, sarif, or github. All but text are written to stdout")
+}
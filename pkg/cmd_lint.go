@@ -2,12 +2,14 @@ package pkg
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -18,19 +20,30 @@ import (
 // files using the same parsing as schema generation and reports any errors, and
 // warns about unknown fields in the config file.
 func newLintCmd() *cobra.Command {
-	var strict bool
+	var strict, metaschema bool
 
 	cmd := &cobra.Command{
 		Use:   "lint",
 		Short: "Lint the config file and its input values files",
 		Long: "Lint parses the configured input values files using the same parsing as " +
 			"schema generation and reports any errors. It also checks the config file " +
-			"(.schema.yaml) for unknown fields and logs them as warnings.",
+			"(.schema.yaml) for unknown fields, and checks the \"# @schema\" annotations " +
+			"in the input values files for mistakes, and for keys in the input values files " +
+			"that the schema rejects, and logs them as warnings.",
 		Example: `  # Lint using .schema.yaml in the current directory
   helm schema lint
 
   # Fail with a non-zero exit code when any warning is reported
-  helm schema lint --strict`,
+  helm schema lint --strict
+
+  # Also validate the generated schema against its draft's metaschema
+  helm schema lint --metaschema
+
+  # Write a SARIF log for uploading to GitHub code scanning
+  helm schema lint --format sarif > lint.sarif
+
+  # Annotate pull requests with the issues when run in GitHub Actions
+  helm schema lint --format github`,
 		Args:          cobra.NoArgs,
 		SilenceErrors: true,
 		SilenceUsage:  true,
@@ -41,60 +54,162 @@ func newLintCmd() *cobra.Command {
 			}
 			return Lint(cmd.Context(), config, LintOptions{
 				Strict:     strict,
+				Metaschema: metaschema,
 				ConfigPath: cmd.Flag("config").Value.String(),
+				Output:     cmd.OutOrStdout(),
 			})
 		},
 	}
 
 	cmd.Flags().BoolVar(&strict, "strict", false, "Fail with a non-zero exit code when any warning is reported")
+	cmd.Flags().BoolVar(&metaschema, "metaschema", false, "Validate the generated schema against the metaschema of its JSON Schema draft")
+	registerFormatFlag(cmd.Flags())
 
 	return cmd
 }
 
 // LintOptions configures [Lint].
 type LintOptions struct {
-	// Strict makes Lint return an error when at least one warning is reported.
+	// Strict makes Lint return an error when at least one issue with the
+	// "warning" severity is reported. Issues with the "error" severity always
+	// makes Lint return an error, while "info" issues never do.
 	Strict bool
+	// Metaschema makes Lint validate the generated schema against the
+	// metaschema of its draft, and report any violations as errors.
+	Metaschema bool
 	// ConfigPath is the path to the config file checked for unknown fields.
 	ConfigPath string
+	// Output is where the diagnostics are written when Config.Format is one
+	// of the structured formats. Defaults to [os.Stdout].
+	Output io.Writer
 }
 
 // Lint parses the configured input files (reusing the same parsing as schema
-// generation) and checks the config file for unknown fields, logging each one
-// as a warning. It returns an error when parsing fails, or when LintOptions.Strict
-// is set and at least one warning was reported.
+// generation), checks the config file for unknown fields, and runs the semantic
+// lint rules on the "# @schema" annotations in the input files, reporting each
+// problem with its severity from the "lint.rules" config. It returns an error
+// when parsing fails, when an issue with the "error" severity was reported, or
+// when LintOptions.Strict is set and at least one warning was reported. With LintOptions.Metaschema it
+// also validates the generated schema against its draft's metaschema.
+//
+// With the default [FormatText] the issues are logged, while any other
+// Config.Format writes them to LintOptions.Output instead, including the
+// error when parsing fails.
 func Lint(ctx context.Context, config *Config, opts LintOptions) error {
-	logger := LoggerFromContext(ctx)
-
-	// Reuse the exact same parsing and validation as schema generation.
-	if _, err := buildJSONSchema(ctx, config); err != nil {
+	format := cmp.Or(config.Format, FormatText)
+	if err := validateFormat(format); err != nil {
 		return err
 	}
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
 
-	warnings, err := lintConfigUnknownFields(opts.ConfigPath)
+	issues, err := collectLintIssues(ctx, config, opts)
 	if err != nil {
+		if format != FormatText {
+			if writeErr := writeDiagnostics(output, format, diagnosticsFromError(err)); writeErr != nil {
+				return errors.Join(err, writeErr)
+			}
+		}
 		return err
 	}
-	for _, warning := range warnings {
-		logger.Logf("warning: %s", warning)
+
+	counts := countLintIssues(issues)
+	if format == FormatText {
+		logLintIssues(LoggerFromContext(ctx), issues, counts)
+	} else if err := writeDiagnostics(output, format, issues); err != nil {
+		return err
 	}
 
-	if len(warnings) > 0 {
-		logger.Logf("Found %d warning(s)", len(warnings))
-		if opts.Strict {
-			return fmt.Errorf("found %d warning(s) in strict mode", len(warnings))
-		}
+	switch {
+	case counts[LintSeverityError] > 0:
+		return fmt.Errorf("found %d error(s)", counts[LintSeverityError])
+	case opts.Strict && counts[LintSeverityWarning] > 0:
+		return fmt.Errorf("found %d warning(s) in strict mode", counts[LintSeverityWarning])
+	default:
 		return nil
 	}
+}
+
+// collectLintIssues runs all checks of [Lint] and returns their issues, with
+// the severities from the "lint.rules" config applied.
+func collectLintIssues(ctx context.Context, config *Config, opts LintOptions) ([]LintIssue, error) {
+	severities, err := config.Lint.severities()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reuse the exact same parsing and validation as schema generation.
+	schema, err := buildJSONSchema(ctx, config)
+	if err != nil {
+		return nil, err
+	}
 
-	logger.Log("No issues found")
-	return nil
+	var issues []LintIssue
+	if opts.Metaschema {
+		err := checkMetaschema(schema, config.Draft)
+		var metaschemaErr *MetaschemaError
+		if err != nil && !errors.As(err, &metaschemaErr) {
+			return nil, err
+		}
+		if metaschemaErr != nil {
+			issues = append(issues, diagnosticsFromError(metaschemaErr)...)
+		}
+	}
+
+	configIssues, err := lintConfigUnknownFields(opts.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range configIssues {
+		issue.Severity = severities[issue.Rule]
+		if issue.Severity != LintSeverityOff {
+			issues = append(issues, issue)
+		}
+	}
+
+	var uncoveredIssues []LintIssue
+	if severities[LintRuleUncoveredKey] != LintSeverityOff {
+		uncoveredIssues, err = lintUncoveredKeys(ctx, config, schema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The uncovered keys are suppressed same as the issues of the other rules
+	valuesIssues, err := lintValuesFiles(config.Values, config.UseHelmDocs, config.Lint, uncoveredIssues)
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, valuesIssues...)
+	return issues, nil
+}
+
+// logLintIssues logs each issue with its severity, followed by a summary.
+func logLintIssues(logger Logger, issues []LintIssue, counts map[LintSeverity]int) {
+	for _, issue := range issues {
+		logger.Logf("%s: %s", issue.Severity, issue)
+	}
+
+	if len(issues) == 0 {
+		logger.Log("No issues found")
+		return
+	}
+
+	var summary []string
+	for _, severity := range []LintSeverity{LintSeverityError, LintSeverityWarning, LintSeverityInfo} {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s(s)", counts[severity], severity))
+		}
+	}
+	logger.Logf("Found %s", strings.Join(summary, ", "))
 }
 
 // lintConfigUnknownFields decodes the config file with strict field checking and
-// returns one warning per unknown field. A missing config file yields no
+// returns one issue per unknown field, without a severity. A missing config file yields no
 // warnings, matching the lenient behavior of config loading.
-func lintConfigUnknownFields(configPath string) ([]string, error) {
+func lintConfigUnknownFields(configPath string) ([]LintIssue, error) {
 	if configPath == "" {
 		return nil, nil
 	}
@@ -123,10 +238,11 @@ func lintConfigUnknownFields(configPath string) ([]string, error) {
 		// means the config is invalid, so surface it as a hard error.
 		var typeErr *yaml.TypeError
 		if errors.As(err, &typeErr) {
-			var warnings, invalid []string
+			var issues []LintIssue
+			var invalid []string
 			for _, msg := range typeErr.Errors {
-				if cleaned, ok := unknownFieldWarning(msg); ok {
-					warnings = append(warnings, cleaned)
+				if issue, ok := unknownFieldIssue(configPath, msg); ok {
+					issues = append(issues, issue)
 				} else {
 					invalid = append(invalid, msg)
 				}
@@ -134,23 +250,38 @@ func lintConfigUnknownFields(configPath string) ([]string, error) {
 			if len(invalid) > 0 {
 				return nil, fmt.Errorf("parse config file %q: %s", configPath, strings.Join(invalid, "; "))
 			}
-			return warnings, nil
+			return issues, nil
 		}
 		// Any other error means the config YAML itself is malformed.
 		return nil, fmt.Errorf("parse config file %q: %w", configPath, err)
 	}
 }
 
-// unknownFieldWarning rewrites the YAML decoder's
-// "field X not found in type pkg.Config" message into a user-facing warning.
-// The second return value is false when msg is not an unknown-field message
-// (e.g. a type mismatch), so the caller can treat it as a hard error instead.
+// unknownFieldIssue rewrites the YAML decoder's
+// "line 4: field X not found in type pkg.Config" message into a lint issue
+// located at that line. The second return value is false when msg is not an
+// unknown-field message (e.g. a type mismatch), so the caller can treat it as
+// a hard error instead.
 //
-// This is coupled to the go.yaml.in/yaml/v3 error wording; TestCleanUnknownFieldMessage
+// This is coupled to the go.yaml.in/yaml/v3 error wording; TestUnknownFieldIssue
 // guards the phrasing so a library bump surfaces as a test failure.
-func unknownFieldWarning(msg string) (string, bool) {
-	if idx := strings.Index(msg, " not found in type "); idx != -1 {
-		return msg[:idx] + " is not a known config field", true
+func unknownFieldIssue(configPath, msg string) (LintIssue, bool) {
+	idx := strings.Index(msg, " not found in type ")
+	if idx == -1 {
+		return LintIssue{}, false
+	}
+	issue := LintIssue{
+		Rule:    LintRuleUnknownConfigField,
+		File:    configPath,
+		Message: msg[:idx] + " is not a known config field",
+	}
+	if rest, ok := strings.CutPrefix(issue.Message, "line "); ok {
+		if lineStr, message, ok := strings.Cut(rest, ": "); ok {
+			if line, err := strconv.Atoi(lineStr); err == nil {
+				issue.Line = line
+				issue.Message = message
+			}
+		}
 	}
-	return msg, false
+	return issue, true
 }
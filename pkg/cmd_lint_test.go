@@ -29,8 +29,8 @@ func TestLint(t *testing.T) {
 			config: &Config{Values: validValues, Draft: 2020, Indent: 4},
 			opts:   LintOptions{ConfigPath: "../testdata/lint/unknown.yaml"},
 			wantContain: []string{
-				"warning: line 4: field fooBar is not a known config field",
-				"warning: line 6: field unknownNested is not a known config field",
+				"warning: ../testdata/lint/unknown.yaml:4: field fooBar is not a known config field (unknown-config-field)",
+				"warning: ../testdata/lint/unknown.yaml:6: field unknownNested is not a known config field (unknown-config-field)",
 				"Found 2 warning(s)",
 			},
 		},
@@ -45,6 +45,109 @@ func TestLint(t *testing.T) {
 			config:  &Config{Values: []string{"../testdata/lint/values-bad.yaml"}, Draft: 2020, Indent: 4},
 			wantErr: `invalid type "bogustype"`,
 		},
+		{
+			name:        "metaschema valid",
+			config:      &Config{Values: validValues, Draft: 2020, Indent: 4},
+			opts:        LintOptions{Metaschema: true},
+			wantContain: []string{"No issues found"},
+		},
+		{
+			name:    "metaschema violation",
+			config:  &Config{Values: []string{"../testdata/lint/values-metaschema.yaml"}, Draft: 2020, Indent: 4},
+			opts:    LintOptions{Metaschema: true},
+			wantErr: "found 1 error(s)",
+		},
+		{
+			name:        "metaschema violation ignored by default",
+			config:      &Config{Values: []string{"../testdata/lint/values-metaschema.yaml"}, Draft: 2020, Indent: 4},
+			wantContain: []string{"No issues found"},
+		},
+		{
+			name:   "semantic rules",
+			config: &Config{Values: []string{"../testdata/lint/values-rules.yaml"}, Draft: 2020, Indent: 4},
+			wantContain: []string{
+				"warning: ../testdata/lint/values-rules.yaml:1:11: /replicas: value does not match annotations: type: got string, want \"integer\" (value-mismatch)",
+				"(min-greater-than-max)",
+				"(invalid-pattern)",
+				"(required-hidden)",
+				"(skip-properties-non-object)",
+				"(default-mismatch)",
+				"(item-properties-non-array)",
+				"Found 8 warning(s)",
+			},
+		},
+		{
+			name:    "semantic rules strict",
+			config:  &Config{Values: []string{"../testdata/lint/values-rules.yaml"}, Draft: 2020, Indent: 4},
+			opts:    LintOptions{Strict: true},
+			wantErr: "found 8 warning(s) in strict mode",
+		},
+		{
+			name: "error severity",
+			config: &Config{
+				Values: []string{"../testdata/lint/values-rules.yaml"}, Draft: 2020, Indent: 4,
+				Lint: LintConfig{Rules: map[string]LintSeverity{LintRuleValueMismatch: LintSeverityError}},
+			},
+			wantErr: "found 2 error(s)",
+		},
+		{
+			name: "info severity",
+			config: &Config{
+				Values: []string{"../testdata/lint/values-rules.yaml"}, Draft: 2020, Indent: 4,
+				Lint: LintConfig{Rules: map[string]LintSeverity{
+					LintRuleDefaultMismatch:         LintSeverityInfo,
+					LintRuleValueMismatch:           LintSeverityInfo,
+					LintRuleMinGreaterThanMax:       LintSeverityInfo,
+					LintRuleInvalidPattern:          LintSeverityOff,
+					LintRuleRequiredHidden:          LintSeverityOff,
+					LintRuleSkipPropertiesNonObject: LintSeverityOff,
+					LintRuleItemPropertiesNonArray:  LintSeverityOff,
+				}},
+			},
+			opts: LintOptions{Strict: true},
+			wantContain: []string{
+				"info: ../testdata/lint/values-rules.yaml:1:11: /replicas:",
+				"Found 4 info(s)",
+			},
+		},
+		{
+			name:   "uncovered keys",
+			config: &Config{Values: []string{"../testdata/lint/uncovered/values.yaml"}, Draft: 2020, Indent: 4, BundleRoot: ".."},
+			wantContain: []string{
+				`warning: ../testdata/lint/uncovered/values.yaml:3:3: /image/tagg: key is not covered by the schema, and is rejected by "additionalProperties: false" (uncovered-key)`,
+				`warning: ../testdata/lint/uncovered/values.yaml:6:3: /service/portt: key is not covered by the schema, and is rejected by "unevaluatedProperties: false" (uncovered-key)`,
+				"Found 2 warning(s)",
+			},
+		},
+		{
+			name: "uncovered keys disabled",
+			config: &Config{
+				Values: []string{"../testdata/lint/uncovered/values.yaml"}, Draft: 2020, Indent: 4,
+				Lint: LintConfig{Rules: map[string]LintSeverity{LintRuleUncoveredKey: LintSeverityOff}},
+			},
+			wantContain: []string{"No issues found"},
+		},
+		{
+			name:        "uncovered keys refs that fail to load",
+			config:      &Config{Values: []string{"../testdata/lint/uncovered/values.yaml"}, Draft: 2020, Indent: 4},
+			wantContain: []string{"No issues found"},
+		},
+		{
+			name:   "uncovered keys suppressed",
+			config: &Config{Values: []string{"../testdata/lint/uncovered-suppressed.yaml"}, Draft: 2020, Indent: 4},
+			wantContain: []string{
+				`warning: ../testdata/lint/uncovered-suppressed.yaml:7:3: /service/portt: key is not covered by the schema, and is rejected by "additionalProperties: false" (uncovered-key)`,
+				"Found 1 warning(s)",
+			},
+		},
+		{
+			name: "invalid lint config",
+			config: &Config{
+				Values: validValues, Draft: 2020, Indent: 4,
+				Lint: LintConfig{Rules: map[string]LintSeverity{"foo": LintSeverityError}},
+			},
+			wantErr: `lint.rules: unknown rule "foo"`,
+		},
 		{
 			name:    "malformed config",
 			config:  &Config{Values: validValues, Draft: 2020, Indent: 4},
@@ -70,6 +173,104 @@ func TestLint(t *testing.T) {
 	}
 }
 
+func TestLint_Format(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *Config
+		opts       LintOptions
+		wantErr    string
+		wantOutput string
+	}{
+		{
+			name:       "json no issues",
+			config:     &Config{Values: []string{"../testdata/lint/values.yaml"}, Draft: 2020, Indent: 4, Format: FormatJSON},
+			wantOutput: "[]\n",
+		},
+		{
+			name:   "json issues",
+			config: &Config{Values: []string{"../testdata/lint/values.yaml"}, Draft: 2020, Indent: 4, Format: FormatJSON},
+			opts:   LintOptions{ConfigPath: "../testdata/lint/unknown.yaml"},
+			wantOutput: `[
+  {
+    "rule": "unknown-config-field",
+    "severity": "warning",
+    "file": "../testdata/lint/unknown.yaml",
+    "line": 4,
+    "message": "field fooBar is not a known config field"
+  },
+  {
+    "rule": "unknown-config-field",
+    "severity": "warning",
+    "file": "../testdata/lint/unknown.yaml",
+    "line": 6,
+    "message": "field unknownNested is not a known config field"
+  }
+]
+`,
+		},
+		{
+			name:    "github parse error",
+			config:  &Config{Values: []string{"../testdata/lint/values-bad-comment.yaml"}, Draft: 2020, Indent: 4, Format: FormatGitHub},
+			wantErr: "/image/tag: parse @schema comments: hidden: invalid boolean",
+			wantOutput: "::error file=../testdata/lint/values-bad-comment.yaml,line=2,col=3,title=generate-error::" +
+				"/image/tag: parse @schema comments: hidden: invalid boolean \"foo\", must be \"true\" or \"false\"\n",
+		},
+		{
+			name:    "github invalid type annotation",
+			config:  &Config{Values: []string{"../testdata/lint/values-bad.yaml"}, Draft: 2020, Indent: 4, Format: FormatGitHub},
+			wantErr: `invalid type "bogustype"`,
+			wantOutput: "::error file=../testdata/lint/values-bad.yaml,line=1,col=1,title=generate-error::" +
+				"/bad: parse @schema comments: /type: invalid type \"bogustype\", must be one of: array, boolean, integer, null, number, object, string\n",
+		},
+		{
+			name:       "github metaschema",
+			config:     &Config{Values: []string{"../testdata/lint/values-metaschema.yaml"}, Draft: 2020, Indent: 4, Format: FormatGitHub},
+			opts:       LintOptions{Metaschema: true},
+			wantErr:    "found 1 error(s)",
+			wantOutput: "::error title=metaschema::/properties/replicas/allOf/0/required: items at 0 and 1 are equal\n",
+		},
+		{
+			name:       "github metaschema unknown keyword",
+			config:     &Config{Values: []string{"../testdata/lint/values-unknown-keyword.yaml"}, Draft: 2020, Indent: 4, Format: FormatGitHub},
+			opts:       LintOptions{Metaschema: true},
+			wantErr:    "found 1 error(s)",
+			wantOutput: "::error title=metaschema::/properties/replicas/allOf/0/tpye: unknown keyword \"tpye\"\n",
+		},
+		{
+			name:    "invalid format",
+			config:  &Config{Values: []string{"../testdata/lint/values.yaml"}, Draft: 2020, Indent: 4, Format: "xml"},
+			wantErr: `invalid --format="xml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logBuf, outBuf bytes.Buffer
+			ctx := ContextWithLogger(t.Context(), NewLogger(&logBuf))
+			tt.opts.Output = &outBuf
+			err := Lint(ctx, tt.config, tt.opts)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOutput, outBuf.String())
+			assert.Empty(t, logBuf.String())
+		})
+	}
+}
+
+func TestLint_FormatWriteError(t *testing.T) {
+	config := &Config{Values: []string{"../testdata/lint/values.yaml"}, Draft: 2020, Indent: 4, Format: FormatGitHub}
+	err := Lint(t.Context(), config, LintOptions{ConfigPath: "../testdata/lint/unknown.yaml", Output: errWriter{}})
+	assert.ErrorContains(t, err, "write diagnostics: ")
+
+	config.Values = []string{"../testdata/lint/values-bad.yaml"}
+	err = Lint(t.Context(), config, LintOptions{Output: errWriter{}})
+	assert.ErrorContains(t, err, `invalid type "bogustype"`)
+	assert.ErrorContains(t, err, "write diagnostics: ")
+}
+
 func TestLintConfigUnknownFields(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -100,14 +301,26 @@ func TestLintConfigUnknownFields(t *testing.T) {
 	}
 }
 
-func TestUnknownFieldWarning(t *testing.T) {
-	got, ok := unknownFieldWarning("line 4: field fooBar not found in type pkg.Config")
+func TestUnknownFieldIssue(t *testing.T) {
+	got, ok := unknownFieldIssue("config.yaml", "line 4: field fooBar not found in type pkg.Config")
+	assert.True(t, ok)
+	assert.Equal(t, LintIssue{
+		Rule:    LintRuleUnknownConfigField,
+		File:    "config.yaml",
+		Line:    4,
+		Message: "field fooBar is not a known config field",
+	}, got)
+
+	got, ok = unknownFieldIssue("config.yaml", "field fooBar not found in type pkg.Config")
 	assert.True(t, ok)
-	assert.Equal(t, "line 4: field fooBar is not a known config field", got)
+	assert.Equal(t, LintIssue{
+		Rule:    LintRuleUnknownConfigField,
+		File:    "config.yaml",
+		Message: "field fooBar is not a known config field",
+	}, got)
 
-	got, ok = unknownFieldWarning("line 2: cannot unmarshal !!str into int")
+	_, ok = unknownFieldIssue("config.yaml", "line 2: cannot unmarshal !!str into int")
 	assert.False(t, ok)
-	assert.Equal(t, "line 2: cannot unmarshal !!str into int", got)
 }
 
 func TestLintCmd(t *testing.T) {
@@ -125,6 +338,15 @@ func TestLintCmd(t *testing.T) {
 			args:    []string{"lint", "--config", "../testdata/lint/unknown.yaml", "--strict"},
 			wantErr: "found 2 warning(s) in strict mode",
 		},
+		{
+			name: "sarif format",
+			args: []string{"lint", "--config", "../testdata/lint/valid-config.yaml", "--format", "sarif"},
+		},
+		{
+			name:    "invalid format",
+			args:    []string{"lint", "--config", "../testdata/lint/valid-config.yaml", "--format", "xml"},
+			wantErr: `invalid --format="xml"`,
+		},
 		{
 			name:    "input parse error",
 			args:    []string{"lint", "--config", "../testdata/lint/bad-config.yaml"},
@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// newMigrateHelmDocsCmd creates the "migrate-helm-docs" subcommand, which
+// writes the helm-docs type, "@default" and "@section" hints of the values
+// files as "# @schema" annotations.
+func newMigrateHelmDocsCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate-helm-docs",
+		Short: "Convert helm-docs type, @default and @section hints into \"# @schema\" annotations",
+		Long: "Migrate-helm-docs inserts \"# @schema\" annotations above the keys of the input " +
+			"values files that have a helm-docs comment, so that the helm-docs type, such as " +
+			"\"(int)\", the \"# @default --\" and the \"# @section --\" hints are used in the " +
+			"generated schema also without the useHelmDocs setting.\n\n" +
+			"The helm-docs comments themselves are kept, so helm-docs still renders them, and " +
+			"annotations that a key already has are not added again. The values files are read " +
+			"from the config file (.schema.yaml), where flags take precedence over the config file.",
+		Example: `  # Migrate values.yaml
+  helm schema migrate-helm-docs
+
+  # Show the annotations that would be added, without writing them
+  helm schema migrate-helm-docs --dry-run
+
+  # Migrate multiple values files
+  helm schema migrate-helm-docs -f values.yaml,values-prod.yaml`,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config, err := LoadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			return MigrateHelmDocsFile(cmd.Context(), cmd.OutOrStdout(), MigrateHelmDocsFileOptions{
+				ValuesFiles: config.Values,
+				DryRun:      dryRun,
+			})
+		},
+	}
+
+	cmd.Flags().StringSliceP("values", "f", DefaultConfig.Values, "One or more YAML files as inputs. Use comma-separated list or supply flag multiple times")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the annotations to add instead of writing the values files")
+
+	return cmd
+}
+
+// MigrateHelmDocsFileOptions holds the inputs for [MigrateHelmDocsFile].
+type MigrateHelmDocsFileOptions struct {
+	// ValuesFiles are the paths to the values files to migrate.
+	ValuesFiles []string
+	// DryRun writes a unified diff of each values file to the [io.Writer]
+	// passed to [MigrateHelmDocsFile], instead of writing the values files.
+	DryRun bool
+}
+
+// MigrateHelmDocsFile inserts the "# @schema" annotations for the helm-docs
+// hints of each values file, leaving the rest of the files untouched.
+//
+// Values files that don't need any annotations are left untouched.
+func MigrateHelmDocsFile(ctx context.Context, out io.Writer, opts MigrateHelmDocsFileOptions) error {
+	for _, valuesFile := range opts.ValuesFiles {
+		err := updateValuesFile(ctx, out, valuesFile, opts.DryRun, func(content []byte) (valuesInsertions, error) {
+			insertions, err := migrateHelmDocs(ctx, content)
+			if err != nil {
+				return nil, fmt.Errorf("migrate %s: %w", valuesFile, err)
+			}
+			return insertions, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyMigrateHelmDocsTestdata copies the migrate-helm-docs values file into
+// a temporary directory, so it can be written to.
+func copyMigrateHelmDocsTestdata(t *testing.T) string {
+	t.Helper()
+	content, err := os.ReadFile("../testdata/migrate-helm-docs/values.yaml")
+	require.NoError(t, err)
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesFile, content, 0600))
+	return valuesFile
+}
+
+func TestMigrateHelmDocsCmd(t *testing.T) {
+	golden, err := os.ReadFile("../testdata/migrate-helm-docs/migrated.yaml")
+	require.NoError(t, err)
+	golden = bytes.ReplaceAll(golden, []byte("\r\n"), []byte("\n"))
+
+	valuesFile := copyMigrateHelmDocsTestdata(t)
+
+	for range 2 {
+		// Running it again is a no-op
+		cmd := NewCmd()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetErr(&buf)
+		cmd.SetArgs([]string{"migrate-helm-docs", "-f", valuesFile})
+		require.NoError(t, cmd.Execute())
+
+		content, err := os.ReadFile(valuesFile)
+		require.NoError(t, err)
+		assert.Equal(t, string(golden), string(content))
+	}
+}
+
+func TestMigrateHelmDocsCmd_DryRun(t *testing.T) {
+	valuesFile := copyMigrateHelmDocsTestdata(t)
+	original, err := os.ReadFile(valuesFile)
+	require.NoError(t, err)
+
+	cmd := NewCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"migrate-helm-docs", "--dry-run", "-f", valuesFile})
+	require.NoError(t, cmd.Execute())
+
+	want := "--- " + valuesFile + "\n" +
+		"+++ " + valuesFile + "\n" +
+		"@@ -1,14 +1,18 @@\n" +
+		"+# @schema type: [integer, null]\n" +
+		" # -- (int) Number of replicas\n" +
+		" replicaCount: null\n" +
+		" \n" +
+		` image:
++  # @schema x-section: Image
+   # -- Image repository
+   # @section -- Image
+   repository: nginx
++  # @schema x-section: Image
+   # -- Image tag
+   # @default -- the chart appVersion
+   # @section -- Image
+   tag: ""
++  # @schema type: [string, null]; default: "IfNotPresent"
+   # -- (string) Image pull policy, defaults to IfNotPresent when null
+   # @default -- ` + "`IfNotPresent`" + `
+   pullPolicy: null
+`
+	assert.Equal(t, want, buf.String())
+
+	content, err := os.ReadFile(valuesFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(original), string(content))
+}
+
+func TestMigrateHelmDocsCmd_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		setup   func(t *testing.T, dir string)
+		wantErr string
+	}{
+		{
+			name:    "missing values file",
+			args:    []string{"migrate-helm-docs", "-f", "does-not-exist.yaml"},
+			wantErr: "read values file",
+		},
+		{
+			name: "invalid values file",
+			args: []string{"migrate-helm-docs", "-f", "invalid.yaml"},
+			setup: func(t *testing.T, dir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.yaml"), []byte("a: ["), 0600))
+			},
+			wantErr: "migrate invalid.yaml: error unmarshalling YAML",
+		},
+		{
+			name:    "missing config file",
+			args:    []string{"migrate-helm-docs", "--config", "does-not-exist.yaml"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "too many args",
+			args:    []string{"migrate-helm-docs", "values.yaml"},
+			wantErr: `unknown command "values.yaml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+
+			cmd := NewCmd()
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+			cmd.SetArgs(tt.args)
+			assert.ErrorContains(t, cmd.Execute(), tt.wantErr)
+		})
+	}
+}
+
+func TestMigrateHelmDocsFile_WriteError(t *testing.T) {
+	err := MigrateHelmDocsFile(ContextWithLogger(t.Context(), t), errWriter{}, MigrateHelmDocsFileOptions{
+		ValuesFiles: []string{copyMigrateHelmDocsTestdata(t)},
+		DryRun:      true,
+	})
+	assert.ErrorContains(t, err, "write diff")
+}
@@ -34,6 +34,49 @@ func TestExecute(t *testing.T) {
 			args:    []string{"--values=/non/existing/file.yaml"},
 			wantErr: "error reading YAML file(s)",
 		},
+		{
+			name:    "invalid format",
+			args:    []string{"--values=../testdata/basic.yaml", "--output=" + os.DevNull, "--format=xml"},
+			wantErr: `invalid --format="xml", must be one of: text, json, sarif, github`,
+		},
+		{
+			name:    "fail execution as json",
+			args:    []string{"--values=../testdata/lint/values-bad-comment.yaml", "--output=" + os.DevNull, "--format=json"},
+			wantErr: "parse schema: /image/tag: parse @schema comments: hidden: invalid boolean",
+			wantOut: `[
+  {
+    "rule": "generate-error",
+    "severity": "error",
+    "file": "../testdata/lint/values-bad-comment.yaml",
+    "line": 2,
+    "column": 3,
+    "pointer": "/image/tag",
+    "message": "parse @schema comments: hidden: invalid boolean \"foo\", must be \"true\" or \"false\""
+  }
+]
+`,
+		},
+		{
+			name:    "success as json",
+			args:    []string{"--values=../testdata/basic.yaml", "--output=" + os.DevNull, "--format=json"},
+			wantOut: "[]\n",
+		},
+		{
+			name:    "metaschema violations as github",
+			args:    []string{"--values=../testdata/lint/values-metaschema.yaml", "--output=" + os.DevNull, "--validate-metaschema", "--format=github"},
+			wantErr: "schema does not conform to its metaschema: found 1 violation(s)",
+			wantOut: "::error title=metaschema::/properties/replicas/allOf/0/required: items at 0 and 1 are equal\n",
+		},
+		{
+			name: "unknown annotation keyword",
+			args: []string{"--values=../testdata/lint/values-unknown-keyword.yaml", "--output=" + os.DevNull},
+		},
+		{
+			name:    "unknown annotation keyword with metaschema as github",
+			args:    []string{"--values=../testdata/lint/values-unknown-keyword.yaml", "--output=" + os.DevNull, "--validate-metaschema", "--format=github"},
+			wantErr: "schema does not conform to its metaschema: found 1 violation(s)",
+			wantOut: "::error title=metaschema::/properties/replicas/allOf/0/tpye: unknown keyword \"tpye\"\n",
+		},
 		{
 			name:    "version flag",
 			args:    []string{"--version"},
@@ -79,65 +122,83 @@ func TestParseFlagsPass(t *testing.T) {
 		{
 			[]string{"--values", "values.yaml"},
 			Config{
-				Values:       []string{"values.yaml"},
-				Output:       "values.schema.json",
-				Draft:        2020,
-				Indent:       4,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				Values:        []string{"values.yaml"},
+				Output:        "values.schema.json",
+				Draft:         2020,
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
 			},
 		},
 		{
 			[]string{"-f", "values.yaml"},
 			Config{
-				Values:       []string{"values.yaml"},
-				Output:       "values.schema.json",
-				Draft:        2020,
-				Indent:       4,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				Values:        []string{"values.yaml"},
+				Output:        "values.schema.json",
+				Draft:         2020,
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
 			},
 		},
 
 		{
 			[]string{"--values", "values1.yaml values2.yaml", "--indent", "2"},
 			Config{
-				Values:       []string{"values1.yaml values2.yaml"},
-				Output:       "values.schema.json",
-				Draft:        2020,
-				Indent:       2,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				Values:        []string{"values1.yaml values2.yaml"},
+				Output:        "values.schema.json",
+				Draft:         2020,
+				Indent:        2,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
 			},
 		},
 
 		{
 			[]string{"--values", "values.yaml", "--output", "my.schema.json", "--draft", "2019", "--indent", "2"},
 			Config{
-				Values:       []string{"values.yaml"},
-				Output:       "my.schema.json",
-				Draft:        2019,
-				Indent:       2,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				Values:        []string{"values.yaml"},
+				Output:        "my.schema.json",
+				Draft:         2019,
+				Indent:        2,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
 			},
 		},
 
 		{
 			[]string{"--values", "values.yaml", "--output", "my.schema.json", "--draft", "2019", "--k8s-schema-url", "foobar"},
 			Config{
-				Values:       []string{"values.yaml"},
-				Output:       "my.schema.json",
-				Draft:        2019,
-				Indent:       4,
-				K8sSchemaURL: "foobar",
+				Values:        []string{"values.yaml"},
+				Output:        "my.schema.json",
+				Draft:         2019,
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "foobar",
 			},
 		},
 
 		{
 			[]string{"--values", "values.yaml", "--schema-root.id", "http://example.com/schema", "--schema-root.ref", "schema/product.json", "--schema-root.title", "MySchema", "--schema-root.description", "My schema description"},
 			Config{
-				Values:       []string{"values.yaml"},
-				Output:       "values.schema.json",
-				Draft:        2020,
-				Indent:       4,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				Values:        []string{"values.yaml"},
+				Output:        "values.schema.json",
+				Draft:         2020,
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
 				SchemaRoot: SchemaRoot{
 					ID:          "http://example.com/schema",
 					Ref:         "schema/product.json",
@@ -153,6 +214,9 @@ func TestParseFlagsPass(t *testing.T) {
 			Config{
 				Values:          []string{"values.yaml"},
 				Indent:          4,
+				Format:          FormatText,
+				MergeStrategy:   MergeStrategyOverride,
+				ItemsMode:       ItemsModeMerge,
 				Output:          "values.schema.json",
 				Draft:           2020,
 				K8sSchemaURL:    "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
@@ -166,6 +230,9 @@ func TestParseFlagsPass(t *testing.T) {
 			Config{
 				Values:          []string{"values.yaml"},
 				Indent:          4,
+				Format:          FormatText,
+				MergeStrategy:   MergeStrategyOverride,
+				ItemsMode:       ItemsModeMerge,
 				Output:          "values.schema.json",
 				Draft:           2020,
 				K8sSchemaURL:    "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
@@ -179,6 +246,9 @@ func TestParseFlagsPass(t *testing.T) {
 			Config{
 				Values:          []string{"values.yaml"},
 				Indent:          4,
+				Format:          FormatText,
+				MergeStrategy:   MergeStrategyOverride,
+				ItemsMode:       ItemsModeMerge,
 				Output:          "values.schema.json",
 				Draft:           2020,
 				K8sSchemaURL:    "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
@@ -191,23 +261,29 @@ func TestParseFlagsPass(t *testing.T) {
 		{
 			[]string{"--use-helm-docs"},
 			Config{
-				Values:       []string{"values.yaml"},
-				Indent:       4,
-				Output:       "values.schema.json",
-				Draft:        2020,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
-				UseHelmDocs:  true,
+				Values:        []string{"values.yaml"},
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				Output:        "values.schema.json",
+				Draft:         2020,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				UseHelmDocs:   true,
 			},
 		},
 		{
 			[]string{"--use-helm-docs=false"},
 			Config{
-				Values:       []string{"values.yaml"},
-				Indent:       4,
-				Output:       "values.schema.json",
-				Draft:        2020,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
-				UseHelmDocs:  false,
+				Values:        []string{"values.yaml"},
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				Output:        "values.schema.json",
+				Draft:         2020,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				UseHelmDocs:   false,
 			},
 		},
 	}
@@ -279,6 +355,9 @@ schemaRoot:
 				Output:          "values.schema.json",
 				Draft:           2020,
 				Indent:          2,
+				Format:          FormatText,
+				MergeStrategy:   MergeStrategyOverride,
+				ItemsMode:       ItemsModeMerge,
 				Bundle:          true,
 				BundleRoot:      "./",
 				BundleWithoutID: true,
@@ -294,15 +373,69 @@ schemaRoot:
 				},
 			},
 		},
+		{
+			name: "RefMirrors",
+			config: `
+refMirrors:
+  https://json.schemastore.org/: https://mirror.corp/schemastore/
+  https://raw.githubusercontent.com/yannh/kubernetes-json-schema/: https://mirror.corp/k8s/
+`,
+			want: Config{
+				Values:        []string{"values.yaml"},
+				Output:        "values.schema.json",
+				Draft:         2020,
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				RefMirrors: map[string]string{
+					"https://json.schemastore.org/":                                   "https://mirror.corp/schemastore/",
+					"https://raw.githubusercontent.com/yannh/kubernetes-json-schema/": "https://mirror.corp/k8s/",
+				},
+			},
+		},
+		{
+			name: "RefAliases",
+			config: `
+refAliases:
+  crd:
+    url: https://example.com/crds/{{ .version }}/
+    vars:
+      version: v1
+  $schemastore:
+    url: https://json.schemastore.org/
+`,
+			want: Config{
+				Values:        []string{"values.yaml"},
+				Output:        "values.schema.json",
+				Draft:         2020,
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				RefAliases: map[string]RefAlias{
+					"crd": {
+						URL:  "https://example.com/crds/{{ .version }}/",
+						Vars: map[string]string{"version": "v1"},
+					},
+					"$schemastore": {URL: "https://json.schemastore.org/"},
+				},
+			},
+		},
 		{
 			name:   "EmptyConfig",
 			config: `# just a comment`,
 			want: Config{
-				Values:       []string{"values.yaml"},
-				Output:       "values.schema.json",
-				Draft:        2020,
-				Indent:       4,
-				K8sSchemaURL: "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
+				Values:        []string{"values.yaml"},
+				Output:        "values.schema.json",
+				Draft:         2020,
+				Indent:        4,
+				Format:        FormatText,
+				MergeStrategy: MergeStrategyOverride,
+				ItemsMode:     ItemsModeMerge,
+				K8sSchemaURL:  "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .K8sSchemaVersion }}/",
 			},
 		},
 	}
@@ -465,6 +598,9 @@ schemaRoot:
 				Output:                 "flagOutput.json",
 				Draft:                  2019,
 				Indent:                 2,
+				Format:                 FormatText,
+				MergeStrategy:          MergeStrategyOverride,
+				ItemsMode:              ItemsModeMerge,
 				NoAdditionalProperties: false,
 				K8sSchemaURL:           "flagURL",
 				K8sSchemaVersion:       "flagVersion",
@@ -504,6 +640,9 @@ schemaRoot:
 				Output:                 "fileOutput.json",
 				Draft:                  2020,
 				Indent:                 4,
+				Format:                 FormatText,
+				MergeStrategy:          MergeStrategyOverride,
+				ItemsMode:              ItemsModeMerge,
 				K8sSchemaURL:           "fileURL",
 				K8sSchemaVersion:       "fileVersion",
 				NoAdditionalProperties: true,
@@ -546,6 +685,9 @@ schemaRoot:
 				Output:                 "flagOutput.json",
 				Draft:                  2020,
 				Indent:                 4,
+				Format:                 FormatText,
+				MergeStrategy:          MergeStrategyOverride,
+				ItemsMode:              ItemsModeMerge,
 				K8sSchemaURL:           "fileURL",
 				K8sSchemaVersion:       "fileVersion",
 				NoAdditionalProperties: true,
@@ -585,6 +727,9 @@ schemaRoot:
 				Output:           "flagOutput.json",
 				Draft:            2019,
 				Indent:           2,
+				Format:           FormatText,
+				MergeStrategy:    MergeStrategyOverride,
+				ItemsMode:        ItemsModeMerge,
 				K8sSchemaURL:     "flagURL",
 				K8sSchemaVersion: "flagVersion",
 				UseHelmDocs:      true,
@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags are only used in testing to achieve better test coverage
+var (
+	failUnbundleFileAbs     bool
+	failUnbundleFileClone   bool
+	failUnbundleFileMarshal bool
+)
+
+// newUnbundleCmd creates the "unbundle" subcommand, which is the inverse of
+// the "bundle" subcommand. It splits the "$defs" of a bundled JSON schema file
+// into separate files.
+func newUnbundleCmd() *cobra.Command {
+	var (
+		outDir string
+		indent int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "unbundle SCHEMA_FILE",
+		Short: "Split the bundled $defs of a JSON schema file into separate files",
+		Long: "Unbundle is the inverse of \"helm schema bundle\". It reads a bundled JSON schema " +
+			"file and writes each \"$defs\" entry that has an \"$id\" to its own file inside the " +
+			"output directory, and rewrites the \"$ref\" pointing to them into relative file paths.\n\n" +
+			"The root schema is written to the output directory using the same file name as the input " +
+			"file. After writing, the files are bundled again to verify that the result is semantically " +
+			"equal to the input schema.",
+		Example: `  # Split a bundled schema into the "schemas" directory
+  helm schema unbundle values.schema.json --out-dir schemas`,
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return UnbundleFile(cmd.Context(), cmd.OutOrStdout(), UnbundleFileOptions{
+				InputFile: args[0],
+				OutDir:    outDir,
+				Indent:    indent,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&outDir, "out-dir", "", "Directory to write the unbundled schema files to")
+	cmd.Flags().IntVar(&indent, "indent", DefaultConfig.Indent, "Indentation spaces (even number)")
+	_ = cmd.MarkFlagRequired("out-dir")
+
+	return cmd
+}
+
+// UnbundleFileOptions holds the inputs for [UnbundleFile].
+type UnbundleFileOptions struct {
+	// InputFile is the path to the bundled JSON schema file.
+	InputFile string
+	// OutDir is the directory that the root schema and each unbundled
+	// schema is written to. It is created if it does not exist.
+	OutDir string
+	// Indent is the number of spaces used to indent the JSON output.
+	Indent int
+}
+
+// UnbundleFile reads the bundled JSON schema file referenced by
+// opts.InputFile, splits it using [UnbundleSchema], writes the resulting
+// files to opts.OutDir, and verifies that bundling the written files again
+// produces a schema that is semantically equal to the input.
+func UnbundleFile(ctx context.Context, out io.Writer, opts UnbundleFileOptions) error {
+	logger := LoggerFromContext(ctx)
+
+	if opts.Indent <= 0 {
+		return errors.New("indentation must be a positive number")
+	}
+	if opts.Indent%2 != 0 {
+		return errors.New("indentation must be an even number")
+	}
+	if opts.OutDir == "" {
+		return errors.New("output directory must not be empty")
+	}
+
+	content, err := os.ReadFile(filepath.Clean(opts.InputFile))
+	if err != nil {
+		return fmt.Errorf("read schema file: %w", err)
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return fmt.Errorf("parse schema file %q: %w", opts.InputFile, err)
+	}
+	original, err := cloneSchemaJSON(&schema)
+	if err != nil || failUnbundleFileClone {
+		return fmt.Errorf("clone schema: %w", err)
+	}
+
+	files, err := UnbundleSchema(&schema)
+	if err != nil {
+		return fmt.Errorf("unbundle schemas: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no $defs with $id found in %q", opts.InputFile)
+	}
+
+	rootFile := filepath.Base(opts.InputFile)
+	for _, file := range files {
+		if file.FileName == rootFile {
+			return fmt.Errorf("%s would overwrite the root schema file %q", NewPtr("$defs", file.DefName), rootFile)
+		}
+	}
+
+	outDirAbs, err := filepath.Abs(opts.OutDir)
+	if err != nil || failUnbundleFileAbs {
+		return fmt.Errorf("get absolute path of %q: %w", opts.OutDir, err)
+	}
+	if err := os.MkdirAll(outDirAbs, 0750); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+
+	indent := strings.Repeat(" ", opts.Indent)
+	fileNames := map[string]string{} // $defs name => file name
+	for _, file := range files {
+		fileNames[file.DefName] = file.FileName
+		if err := writeUnbundledFile(out, filepath.Join(outDirAbs, file.FileName), file.Schema, indent); err != nil {
+			return err
+		}
+		logger.Log("Wrote", filepath.Join(opts.OutDir, file.FileName))
+	}
+	if err := writeUnbundledFile(out, filepath.Join(outDirAbs, rootFile), &schema, indent); err != nil {
+		return err
+	}
+	logger.Log("Wrote", filepath.Join(opts.OutDir, rootFile))
+
+	if err := verifyUnbundled(ctx, original, rootFile, outDirAbs, fileNames); err != nil {
+		return fmt.Errorf("verify unbundled schemas: %w", err)
+	}
+	logger.Log("Verified that re-bundling produces the same schema")
+	return nil
+}
+
+func writeUnbundledFile(out io.Writer, path string, schema *Schema, indent string) error {
+	jsonBytes, err := json.MarshalIndent(schema, "", indent)
+	if err != nil || failUnbundleFileMarshal {
+		return fmt.Errorf("encode %s: %w", filepath.Base(path), err)
+	}
+	jsonBytes = append(jsonBytes, '\n')
+	if err := writeOutputFile(out, path, jsonBytes); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnbundleCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantErr   string
+		wantFiles []string
+	}{
+		{
+			name:      "success",
+			args:      []string{"unbundle", "../testdata/bundle/namecollision.schema.json"},
+			wantFiles: []string{"namecollision.schema.json", "namecollision-subschema.schema.json", "namecollision-subschema.schema_2.json"},
+		},
+		{
+			name:      "custom indent",
+			args:      []string{"unbundle", "--indent", "2", "../testdata/bundle/nested.schema.json"},
+			wantFiles: []string{"nested.schema.json", "nested-subschema.schema.json", "simple-subschema.schema.json"},
+		},
+		{
+			name:    "missing out-dir",
+			args:    []string{"unbundle", "../testdata/bundle/nested.schema.json"},
+			wantErr: `required flag(s) "out-dir" not set`,
+		},
+		{
+			name:    "no args",
+			args:    []string{"unbundle"},
+			wantErr: "accepts 1 arg(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			args := tt.args
+			if tt.wantErr == "" {
+				args = append(args, "--out-dir", outDir)
+			}
+
+			cmd := NewCmd()
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+			cmd.SetArgs(args)
+			cmd.SetContext(ContextWithLogger(t.Context(), t))
+
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for _, file := range tt.wantFiles {
+				assert.FileExists(t, filepath.Join(outDir, file))
+			}
+		})
+	}
+}
+
+func TestUnbundleFile(t *testing.T) {
+	outDir := t.TempDir()
+	var buf bytes.Buffer
+	ctx := ContextWithLogger(context.Background(), NewLogger(&buf))
+	err := UnbundleFile(ctx, io.Discard, UnbundleFileOptions{
+		InputFile: "../testdata/bundle/ref-relative-to-id.schema.json",
+		OutDir:    outDir,
+		Indent:    2,
+	})
+	require.NoError(t, err)
+
+	root, err := os.ReadFile(filepath.Join(outDir, "ref-relative-to-id.schema.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(root), `"$ref": "hello.json#/items"`)
+	assert.NotContains(t, string(root), `"$defs"`)
+
+	sub, err := os.ReadFile(filepath.Join(outDir, "hello.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sub), `"$id"`)
+
+	assert.Contains(t, buf.String(), "Verified that re-bundling produces the same schema")
+	assert.NotContains(t, buf.String(), "Loading file")
+}
+
+func TestUnbundleFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    UnbundleFileOptions
+		wantErr string
+	}{
+		{
+			name:    "zero indent",
+			opts:    UnbundleFileOptions{InputFile: "../testdata/bundle/nested.schema.json", OutDir: "out", Indent: 0},
+			wantErr: "indentation must be a positive number",
+		},
+		{
+			name:    "odd indent",
+			opts:    UnbundleFileOptions{InputFile: "../testdata/bundle/nested.schema.json", OutDir: "out", Indent: 3},
+			wantErr: "indentation must be an even number",
+		},
+		{
+			name:    "empty out dir",
+			opts:    UnbundleFileOptions{InputFile: "../testdata/bundle/nested.schema.json", Indent: 4},
+			wantErr: "output directory must not be empty",
+		},
+		{
+			name:    "missing file",
+			opts:    UnbundleFileOptions{InputFile: "../testdata/bundle/does-not-exist.schema.json", OutDir: "out", Indent: 4},
+			wantErr: "read schema file",
+		},
+		{
+			name:    "invalid json",
+			opts:    UnbundleFileOptions{InputFile: "../testdata/bundle/invalid-schema.json", OutDir: "out", Indent: 4},
+			wantErr: "parse schema file",
+		},
+		{
+			name:    "nothing to unbundle",
+			opts:    UnbundleFileOptions{InputFile: "../testdata/bundle/cmd.schema.json", OutDir: "out", Indent: 4},
+			wantErr: `no $defs with $id found in "../testdata/bundle/cmd.schema.json"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ContextWithLogger(context.Background(), t)
+			err := UnbundleFile(ctx, io.Discard, tt.opts)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnbundleFile_OverwritesRoot(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "foo.json")
+	require.NoError(t, os.WriteFile(input, []byte(`{"$ref":"foo.json","$defs":{"foo.json":{"$id":"foo.json"}}}`), 0600))
+
+	ctx := ContextWithLogger(context.Background(), t)
+	err := UnbundleFile(ctx, io.Discard, UnbundleFileOptions{InputFile: input, OutDir: filepath.Join(dir, "out"), Indent: 4})
+	assert.ErrorContains(t, err, `/$defs/foo.json would overwrite the root schema file "foo.json"`)
+}
+
+func TestUnbundleFile_UnbundleError(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "foo.json")
+	require.NoError(t, os.WriteFile(input, []byte(`{"$defs":{"..":{"$id":"a/.."}}}`), 0600))
+
+	ctx := ContextWithLogger(context.Background(), t)
+	err := UnbundleFile(ctx, io.Discard, UnbundleFileOptions{InputFile: input, OutDir: filepath.Join(dir, "out"), Indent: 4})
+	assert.ErrorContains(t, err, "unbundle schemas")
+}
+
+func TestUnbundleFile_MkdirError(t *testing.T) {
+	dir := t.TempDir()
+	notADir := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0600))
+
+	ctx := ContextWithLogger(context.Background(), t)
+	err := UnbundleFile(ctx, io.Discard, UnbundleFileOptions{
+		InputFile: "../testdata/bundle/nested.schema.json",
+		OutDir:    notADir,
+		Indent:    4,
+	})
+	assert.ErrorContains(t, err, "create output directory")
+}
+
+func TestUnbundleFile_FailFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    *bool
+		wantErr string
+	}{
+		{name: "abs", flag: &failUnbundleFileAbs, wantErr: "get absolute path of"},
+		{name: "clone", flag: &failUnbundleFileClone, wantErr: "clone schema"},
+		{name: "marshal", flag: &failUnbundleFileMarshal, wantErr: "encode nested-subschema.schema.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*tt.flag = true
+			defer func() { *tt.flag = false }()
+
+			ctx := ContextWithLogger(context.Background(), t)
+			err := UnbundleFile(ctx, io.Discard, UnbundleFileOptions{
+				InputFile: "../testdata/bundle/nested.schema.json",
+				OutDir:    t.TempDir(),
+				Indent:    4,
+			})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// newValuesFromSchemaCmd creates the "values-from-schema" subcommand, which
+// is the inverse of the generate command. It writes a starter values.yaml
+// file from an existing JSON schema file.
+func newValuesFromSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values-from-schema SCHEMA_FILE",
+		Short: "Generate a starter values.yaml file from a JSON schema file",
+		Long: "Values-from-schema reads an existing JSON schema file, resolves all its \"$ref\" " +
+			"subschemas the same way as \"helm schema bundle\", and prints a starter values.yaml " +
+			"file to stdout or writes it to the --output file.\n\n" +
+			"Values are taken from \"default\", \"const\", \"enum\" or \"examples\", or else a " +
+			"placeholder for the type. Required keys are written as-is, while optional keys are " +
+			"commented out. Each key's \"description\" is written as a helm-docs \"# --\" comment, " +
+			"and other keywords as \"# @schema\" annotations, so generating a schema from the " +
+			"values file with --use-helm-docs reproduces the schema.\n\n" +
+			"Settings such as bundleRoot, indent and k8sSchemaVersion are read from the config " +
+			"file (.schema.yaml), where flags take precedence over the config file. References " +
+			"are always inlined, so bundleWithoutID has no effect.",
+		Example: `  # Print a values file for a schema to stdout
+  helm schema values-from-schema values.schema.json
+
+  # Write a values file indented with 2 spaces
+  helm schema values-from-schema values.schema.json --indent 2 --output values.yaml`,
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := LoadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			// The "output" config field is the output of the generate command,
+			// so only the flag is used here to not overwrite that file by accident.
+			// The flag is registered below, so this getter cannot fail.
+			output, _ := cmd.Flags().GetString("output")
+
+			return ValuesFromSchemaFile(cmd.Context(), cmd.OutOrStdout(), BundleFileOptions{
+				InputFile:        args[0],
+				Output:           output,
+				Indent:           config.Indent,
+				BundleRoot:       config.BundleRoot,
+				CacheMin:         config.BundleCacheMin,
+				CacheMaxSize:     config.BundleCacheMaxSize,
+				RefMirrors:       config.RefMirrors,
+				RefAliases:       config.RefAliases,
+				CacheDir:         config.BundleCacheDir,
+				K8sSchemaURL:     config.K8sSchemaURL,
+				K8sSchemaVersion: config.K8sSchemaVersion,
+			})
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "-", "Output file path, or \"-\" to print to stdout")
+	registerSharedFlags(cmd.Flags())
+
+	return cmd
+}
+
+// ValuesFromSchemaFile reads and bundles the JSON schema file referenced by
+// opts.InputFile the same way as [BundleFile], and writes the values file
+// from [ValuesFromSchema] to opts.Output, or to out when opts.Output is empty
+// or "-". The opts.Indent is used as the YAML indentation, and
+// opts.BundleWithoutID is ignored as all references are inlined.
+func ValuesFromSchemaFile(ctx context.Context, out io.Writer, opts BundleFileOptions) error {
+	if opts.Indent <= 0 {
+		return errors.New("indentation must be a positive number")
+	}
+	if opts.Indent%2 != 0 {
+		return errors.New("indentation must be an even number")
+	}
+
+	// Without "$id", all references are local JSON pointers
+	opts.BundleWithoutID = true
+	schema, err := bundleSchemaFile(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	content, err := ValuesFromSchema(ctx, schema, opts.Indent)
+	if err != nil {
+		return fmt.Errorf("generate values: %w", err)
+	}
+
+	if err := writeOutputFile(out, filepath.FromSlash(cmp.Or(opts.Output, "-")), content); err != nil {
+		return fmt.Errorf("write values: %w", err)
+	}
+	return nil
+}
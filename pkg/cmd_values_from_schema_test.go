@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuesFromSchemaCmd(t *testing.T) {
+	golden, err := os.ReadFile("../testdata/values-from-schema/values.yaml")
+	require.NoError(t, err)
+	// Normalize line endings so the byte-exact comparison holds on Windows,
+	// where the golden file is checked out with CRLF.
+	golden = bytes.ReplaceAll(golden, []byte("\r\n"), []byte("\n"))
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantErr     string
+		wantOut     string
+		wantContain []string
+	}{
+		{
+			name:    "success",
+			args:    []string{"values-from-schema", "--indent", "2", "--bundle-root", "../testdata/values-from-schema", "../testdata/values-from-schema/schema.json"},
+			wantOut: string(golden),
+		},
+		{
+			name: "default indent",
+			args: []string{"values-from-schema", "--bundle-root", "../testdata/values-from-schema", "../testdata/values-from-schema/schema.json"},
+			wantContain: []string{
+				"\nimage:\n    # # @schema enum:",
+			},
+		},
+		{
+			name:    "reference escapes bundle root",
+			args:    []string{"values-from-schema", "../testdata/values-from-schema/schema.json"},
+			wantErr: "bundle schemas",
+		},
+		{
+			name:    "missing file",
+			args:    []string{"values-from-schema", "../testdata/values-from-schema/does-not-exist.json"},
+			wantErr: "read schema file",
+		},
+		{
+			name:    "missing config file",
+			args:    []string{"values-from-schema", "--config", "../testdata/values-from-schema/does-not-exist.yaml", "../testdata/values-from-schema/schema.json"},
+			wantErr: "load config file",
+		},
+		{
+			name:    "no properties",
+			args:    []string{"values-from-schema", "--bundle-root", "../testdata/values-from-schema", "../testdata/values-from-schema/pull-policy.schema.json"},
+			wantErr: "generate values: schema has no properties to generate values from",
+		},
+		{
+			name:    "no args",
+			args:    []string{"values-from-schema"},
+			wantErr: "accepts 1 arg(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewCmd()
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+			cmd.SetArgs(tt.args)
+
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantOut != "" {
+				assert.Equal(t, tt.wantOut, buf.String())
+			}
+			for _, want := range tt.wantContain {
+				assert.Contains(t, buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestValuesFromSchemaCmd_OutputFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "values.yaml")
+
+	cmd := NewCmd()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"values-from-schema", "--indent", "2", "--output", output, "--bundle-root", "../testdata/values-from-schema", "../testdata/values-from-schema/schema.json"})
+	require.NoError(t, cmd.Execute())
+	assert.Empty(t, buf.String())
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "replicaCount: 1\n")
+}
+
+func TestValuesFromSchemaFile_IndentValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		indent  int
+		wantErr string
+	}{
+		{name: "zero", indent: 0, wantErr: "indentation must be a positive number"},
+		{name: "odd", indent: 3, wantErr: "indentation must be an even number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := ValuesFromSchemaFile(context.Background(), &buf, BundleFileOptions{
+				InputFile: "../testdata/values-from-schema/schema.json",
+				Indent:    tt.indent,
+			})
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Empty(t, buf.String())
+		})
+	}
+}
+
+func TestValuesFromSchemaFile_WriteError(t *testing.T) {
+	err := ValuesFromSchemaFile(ContextWithLogger(t.Context(), t), errWriter{}, BundleFileOptions{
+		InputFile:  "../testdata/values-from-schema/schema.json",
+		Indent:     2,
+		BundleRoot: "../testdata/values-from-schema",
+	})
+	assert.ErrorContains(t, err, "write values")
+}
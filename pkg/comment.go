@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"go.yaml.in/yaml/v3"
 )
@@ -204,7 +207,7 @@ func processComment(schema *Schema, commentLines []string) error {
 				return fmt.Errorf("minProperties: %w", err)
 			}
 		case "patternProperties":
-			if err := processObjectComment(&schema.PatternProperties, value); err != nil {
+			if err := processSchemaComment(schema, NewPtr("patternProperties"), &schema.PatternProperties, value); err != nil {
 				return fmt.Errorf("patternProperties: %w", err)
 			}
 		case "required":
@@ -217,6 +220,11 @@ func processComment(schema *Schema, commentLines []string) error {
 			if len(list) == 1 {
 				schema.Type = list[0]
 			}
+			// Checked here, and not only when the schema is complete, so the
+			// error includes the location in the values file
+			if err := validateType(NewPtr("type"), schema.Type); err != nil {
+				return err
+			}
 		case "nullable":
 			if err := processBoolComment(&nullable, value); err != nil {
 				return fmt.Errorf("nullable: %w", err)
@@ -225,6 +233,13 @@ func processComment(schema *Schema, commentLines []string) error {
 			schema.Title = value
 		case "description":
 			schema.Description = value
+			if strings.HasPrefix(value, "\"") {
+				if unquoted, err := strconv.Unquote(value); err == nil {
+					schema.Description = unquoted
+				}
+			}
+		case "x-section":
+			schema.XSection = value
 		case "examples":
 			schema.Examples = processList(value, false)
 		case "readOnly":
@@ -252,7 +267,7 @@ func processComment(schema *Schema, commentLines []string) error {
 			if schema.Items == nil {
 				schema.Items = &Schema{}
 			}
-			if err := processObjectComment(&schema.Items.Properties, value); err != nil {
+			if err := processSchemaComment(schema, NewPtr("items", "properties"), &schema.Items.Properties, value); err != nil {
 				return fmt.Errorf("itemProperties: %w", err)
 			}
 		case "itemRequired":
@@ -286,37 +301,50 @@ func processComment(schema *Schema, commentLines []string) error {
 		case "additionalProperties":
 			if strings.TrimSpace(value) == "" {
 				schema.AdditionalProperties = SchemaTrue()
-			} else if err := processObjectComment(&schema.AdditionalProperties, value); err != nil {
+			} else if err := processSchemaComment(schema, NewPtr("additionalProperties"), &schema.AdditionalProperties, value); err != nil {
 				return fmt.Errorf("additionalProperties: %w", err)
 			}
 		case "unevaluatedProperties":
 			if strings.TrimSpace(value) == "" {
 				schema.UnevaluatedProperties = SchemaTrue()
-			} else if err := processObjectComment(&schema.UnevaluatedProperties, value); err != nil {
+			} else if err := processSchemaComment(schema, NewPtr("unevaluatedProperties"), &schema.UnevaluatedProperties, value); err != nil {
 				return fmt.Errorf("unevaluatedProperties: %w", err)
 			}
 		case "$id":
 			schema.ID = value
 		case "$ref":
 			schema.Ref = value
+		case "inferEnum":
+			if err := processBoolComment(&schema.InferEnum, value); err != nil {
+				return fmt.Errorf("inferEnum: %w", err)
+			}
+		case "noInfer":
+			if err := processBoolComment(&schema.NoInfer, value); err != nil {
+				return fmt.Errorf("noInfer: %w", err)
+			}
+		case "itemsMode":
+			if !slices.Contains(ItemsModes, value) {
+				return fmt.Errorf("itemsMode: invalid value %q, must be one of: %s", value, strings.Join(ItemsModes, ", "))
+			}
+			schema.ItemsMode = value
 		case "hidden":
 			if err := processBoolComment(&schema.Hidden, value); err != nil {
 				return fmt.Errorf("hidden: %w", err)
 			}
 		case "allOf":
-			if err := processObjectComment(&schema.AllOf, value); err != nil {
+			if err := processSchemaComment(schema, NewPtr("allOf"), &schema.AllOf, value); err != nil {
 				return fmt.Errorf("allOf: %w", err)
 			}
 		case "anyOf":
-			if err := processObjectComment(&schema.AnyOf, value); err != nil {
+			if err := processSchemaComment(schema, NewPtr("anyOf"), &schema.AnyOf, value); err != nil {
 				return fmt.Errorf("anyOf: %w", err)
 			}
 		case "oneOf":
-			if err := processObjectComment(&schema.OneOf, value); err != nil {
+			if err := processSchemaComment(schema, NewPtr("oneOf"), &schema.OneOf, value); err != nil {
 				return fmt.Errorf("oneOf: %w", err)
 			}
 		case "not":
-			if err := processObjectComment(&schema.Not, value); err != nil {
+			if err := processSchemaComment(schema, NewPtr("not"), &schema.Not, value); err != nil {
 				return fmt.Errorf("not: %w", err)
 			}
 		case "const":
@@ -376,6 +404,62 @@ func processObjectComment[T any](dest *T, comment string) error {
 	return nil
 }
 
+// processSchemaComment is [processObjectComment] for annotations that hold
+// subschemas, which also adds the keywords that [Schema] doesn't know to
+// schema.UnknownKeywords, relative to ptr. These would otherwise be dropped
+// without notice, such as a misspelled "tpye" inside an "allOf" annotation.
+func processSchemaComment[T any](schema *Schema, ptr Ptr, dest *T, comment string) error {
+	if err := processObjectComment(dest, comment); err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(comment), &node); err == nil && len(node.Content) > 0 {
+		collectUnknownKeywords(ptr, node.Content[0], reflect.TypeFor[T](), &schema.UnknownKeywords)
+	}
+	return nil
+}
+
+// schemaKeywordTypes maps each keyword of [Schema] to the type of its field.
+var schemaKeywordTypes = sync.OnceValue(func() map[string]reflect.Type {
+	keywords := map[string]reflect.Type{}
+	schemaType := reflect.TypeFor[Schema]()
+	for i := range schemaType.NumField() {
+		field := schemaType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if field.IsExported() && name != "" && name != "-" {
+			keywords[name] = field.Type
+		}
+	}
+	return keywords
+})
+
+// collectUnknownKeywords adds the pointer of each keyword in the node that
+// isn't a keyword of [Schema] to unknown, where typ is the type that the node
+// is decoded into. Extension keywords starting with "x-" are allowed.
+func collectUnknownKeywords(ptr Ptr, node *yaml.Node, typ reflect.Type, unknown *[]Ptr) {
+	switch {
+	case typ == reflect.TypeFor[*Schema]() && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyword := node.Content[i].Value
+			keywordType, ok := schemaKeywordTypes()[keyword]
+			switch {
+			case ok:
+				collectUnknownKeywords(ptr.Prop(keyword), node.Content[i+1], keywordType, unknown)
+			case !strings.HasPrefix(keyword, "x-"):
+				*unknown = append(*unknown, ptr.Prop(keyword))
+			}
+		}
+	case typ.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, itemNode := range node.Content {
+			collectUnknownKeywords(ptr.Item(i), itemNode, typ.Elem(), unknown)
+		}
+	case typ.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			collectUnknownKeywords(ptr.Prop(node.Content[i].Value), node.Content[i+1], typ.Elem(), unknown)
+		}
+	}
+}
+
 func processBoolComment(dest *bool, comment string) error {
 	switch strings.TrimSpace(comment) {
 	case "true", "":
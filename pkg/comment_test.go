@@ -592,6 +592,24 @@ func TestProcessComment(t *testing.T) {
 			comment:    "# @schema title:My Title;description: some description;readOnly:false;default:\"foo\";const:\"foo\"",
 			wantSchema: &Schema{Title: "My Title", Description: "some description", ReadOnly: false, Default: "foo", Const: "foo"},
 		},
+		{
+			name:       "Set quoted description",
+			schema:     &Schema{},
+			comment:    `# @schema description: "First line.\n\"Second\" line."`,
+			wantSchema: &Schema{Description: "First line.\n\"Second\" line."},
+		},
+		{
+			name:       "Set invalid quoted description",
+			schema:     &Schema{},
+			comment:    `# @schema description: "Quoted" word`,
+			wantSchema: &Schema{Description: `"Quoted" word`},
+		},
+		{
+			name:       "Set x-section",
+			schema:     &Schema{},
+			comment:    "# @schema x-section: Image",
+			wantSchema: &Schema{XSection: "Image"},
+		},
 		{
 			name:       "Set deprecated",
 			schema:     &Schema{},
@@ -610,6 +628,24 @@ func TestProcessComment(t *testing.T) {
 			comment:    "# @schema mergeProperties:true",
 			wantSchema: &Schema{MergeProperties: true},
 		},
+		{
+			name:       "Set inferEnum",
+			schema:     &Schema{},
+			comment:    "# @schema inferEnum",
+			wantSchema: &Schema{InferEnum: true},
+		},
+		{
+			name:       "Set noInfer",
+			schema:     &Schema{},
+			comment:    "# @schema noInfer",
+			wantSchema: &Schema{NoInfer: true},
+		},
+		{
+			name:       "Set itemsMode",
+			schema:     &Schema{},
+			comment:    "# @schema itemsMode: union",
+			wantSchema: &Schema{ItemsMode: ItemsModeUnion},
+		},
 		{
 			name:       "Set hidden",
 			schema:     &Schema{},
@@ -640,6 +676,38 @@ func TestProcessComment(t *testing.T) {
 			comment:    "# @schema allOf:[{\"type\":\"string\"}]",
 			wantSchema: &Schema{AllOf: []*Schema{{Type: "string"}}},
 		},
+		{
+			name:    "Set allOf with unknown keyword",
+			schema:  &Schema{},
+			comment: `# @schema allOf: [{"tpye": "string"}]`,
+			wantSchema: &Schema{
+				AllOf:           []*Schema{{}},
+				UnknownKeywords: []Ptr{NewPtr("allOf").Item(0).Prop("tpye")},
+			},
+		},
+		{
+			name:       "Set allOf with extension keyword",
+			schema:     &Schema{},
+			comment:    `# @schema allOf: [{"x-doc": "foo", "type": "integer"}]`,
+			wantSchema: &Schema{AllOf: []*Schema{{Type: "integer"}}},
+		},
+		{
+			name:    "Set nested unknown keywords",
+			schema:  &Schema{},
+			comment: `# @schema not: {"properties": {"foo": {"minLenght": 1}}}; patternProperties: {"^a": {"tpye": "string"}}; additionalProperties: {"tpye": "string"}; itemProperties: {"a": {"tpye": "string"}}`,
+			wantSchema: &Schema{
+				Not:                  &Schema{Properties: map[string]*Schema{"foo": {}}},
+				PatternProperties:    map[string]*Schema{"^a": {}},
+				AdditionalProperties: &Schema{},
+				Items:                &Schema{Properties: map[string]*Schema{"a": {}}},
+				UnknownKeywords: []Ptr{
+					NewPtr("not", "properties", "foo", "minLenght"),
+					NewPtr("patternProperties", "^a", "tpye"),
+					NewPtr("additionalProperties", "tpye"),
+					NewPtr("items", "properties", "a", "tpye"),
+				},
+			},
+		},
 		{
 			name:       "Set anyOf",
 			schema:     &Schema{},
@@ -750,6 +818,9 @@ func TestProcessComment_Error(t *testing.T) {
 		{name: "readOnly invalid bool", comment: "# @schema readOnly: foo", wantErr: "readOnly: invalid boolean"},
 		{name: "deprecated invalid bool", comment: "# @schema deprecated: foo", wantErr: "deprecated: invalid boolean"},
 		{name: "hidden invalid bool", comment: "# @schema hidden: foo", wantErr: "hidden: invalid boolean"},
+		{name: "inferEnum invalid bool", comment: "# @schema inferEnum: foo", wantErr: "inferEnum: invalid boolean"},
+		{name: "noInfer invalid bool", comment: "# @schema noInfer: foo", wantErr: "noInfer: invalid boolean"},
+		{name: "itemsMode invalid value", comment: "# @schema itemsMode: foo", wantErr: `itemsMode: invalid value "foo", must be one of: merge, tuple, union`},
 		{name: "required invalid bool", comment: "# @schema required: foo", wantErr: "required: invalid boolean"},
 		{name: "uniqueItems invalid bool", comment: "# @schema uniqueItems: foo", wantErr: "uniqueItems: invalid boolean"},
 		{name: "skipProperties invalid bool", comment: "# @schema skipProperties: foo", wantErr: "skipProperties: invalid boolean"},
@@ -779,6 +850,9 @@ func TestProcessComment_Error(t *testing.T) {
 		{name: "oneOf invalid YAML", comment: "# @schema oneOf: {", wantErr: "oneOf: parse object \"{\": yaml"},
 		{name: "not invalid YAML", comment: "# @schema not: {", wantErr: "not: parse object \"{\": yaml"},
 		{name: "const invalid YAML", comment: "# @schema const: {", wantErr: "const: parse object \"{\": yaml"},
+
+		{name: "type invalid", comment: "# @schema type: foo", wantErr: `/type: invalid type "foo"`},
+		{name: "type list invalid", comment: "# @schema type: [string, foo]", wantErr: `/type/1: invalid type "foo"`},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,243 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// crdScheme is the URL scheme used in "$ref: crd:path/to/crd.yaml" references.
+const crdScheme = "crd"
+
+// CRDLoader loads a schema from a "$ref: crd:path/to/crd.yaml?version=v1"
+// reference, by reading the "openAPIV3Schema" of a Kubernetes
+// CustomResourceDefinition (CRD) manifest from the local file-system.
+//
+// Supported query parameters:
+//
+//   - "version" selects the CRD version. Defaults to the storage version,
+//     or the first served version.
+//   - "name" selects the CRD by its "metadata.name", which is required
+//     when the file contains multiple CRDs.
+type CRDLoader struct {
+	fileLoader FileLoader
+}
+
+// NewCRDLoader returns a new CRD loader that reads files using the given [FileLoader].
+func NewCRDLoader(fileLoader FileLoader) CRDLoader {
+	return CRDLoader{fileLoader: fileLoader}
+}
+
+var _ Loader = CRDLoader{}
+
+// Load implements [Loader].
+func (loader CRDLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
+	if ref.Scheme != crdScheme {
+		return nil, fmt.Errorf(`crd url in $ref=%q must start with "crd:"`, ref)
+	}
+	if ref.User != nil {
+		return nil, fmt.Errorf("crd url in $ref=%q: user info not supported", ref)
+	}
+	crdPath, _ := localSchemeRefPath(ref)
+	if crdPath == "" {
+		return nil, fmt.Errorf("crd url in $ref=%q must contain a path", ref)
+	}
+	query := ref.Query()
+	for key := range query {
+		if key != "version" && key != "name" {
+			return nil, fmt.Errorf("crd url in $ref=%q: unsupported query parameter %q", ref, key)
+		}
+	}
+
+	path, b, err := loader.fileLoader.readFile(ctx, filepath.FromSlash(crdPath))
+	if err != nil {
+		return nil, err
+	}
+
+	crd, err := findCRD(b, query.Get("name"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	version, err := crd.findVersion(query.Get("version"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: CRD %q: %w", path, crd.Metadata.Name, err)
+	}
+	if version.Schema.OpenAPIV3Schema == nil {
+		return nil, fmt.Errorf("%s: CRD %q: version %q has no openAPIV3Schema", path, crd.Metadata.Name, version.Name)
+	}
+
+	convertCRDSchema(version.Schema.OpenAPIV3Schema)
+	b, err = json.Marshal(version.Schema.OpenAPIV3Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: CRD %q: encode openAPIV3Schema: %w", path, crd.Metadata.Name, err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, fmt.Errorf("%s: CRD %q: parse openAPIV3Schema: %w", path, crd.Metadata.Name, err)
+	}
+	return &schema, nil
+}
+
+type crdManifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Versions []crdVersion `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+type crdVersion struct {
+	Name    string `yaml:"name"`
+	Served  bool   `yaml:"served"`
+	Storage bool   `yaml:"storage"`
+	Schema  struct {
+		OpenAPIV3Schema map[string]any `yaml:"openAPIV3Schema"`
+	} `yaml:"schema"`
+}
+
+// findCRD parses a multi-document YAML file and returns the
+// CustomResourceDefinition with the given name, or the only
+// CustomResourceDefinition in the file when the name is empty.
+func findCRD(b []byte, name string) (*crdManifest, error) {
+	var crds []*crdManifest
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var crd crdManifest
+		if err := dec.Decode(&crd); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("parse YAML file: %w", err)
+		}
+		if crd.Kind != "CustomResourceDefinition" || !strings.HasPrefix(crd.APIVersion, "apiextensions.k8s.io/") {
+			continue
+		}
+		crds = append(crds, &crd)
+	}
+
+	if len(crds) == 0 {
+		return nil, fmt.Errorf("no CustomResourceDefinition found")
+	}
+	var names []string
+	for _, crd := range crds {
+		if crd.Metadata.Name == name || (name == "" && len(crds) == 1) {
+			return crd, nil
+		}
+		names = append(names, crd.Metadata.Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf(`found multiple CustomResourceDefinitions, use "?name=" to select one of: %s`, strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("no CustomResourceDefinition named %q, found: %s", name, strings.Join(names, ", "))
+}
+
+// findVersion returns the version with the given name. When the name is empty,
+// then it returns the storage version, or the first served version.
+func (crd *crdManifest) findVersion(name string) (*crdVersion, error) {
+	if len(crd.Spec.Versions) == 0 {
+		return nil, fmt.Errorf("no versions found")
+	}
+	if name != "" {
+		var names []string
+		for i, version := range crd.Spec.Versions {
+			if version.Name == name {
+				return &crd.Spec.Versions[i], nil
+			}
+			names = append(names, version.Name)
+		}
+		return nil, fmt.Errorf("no version named %q, found: %s", name, strings.Join(names, ", "))
+	}
+	if i := slices.IndexFunc(crd.Spec.Versions, func(v crdVersion) bool { return v.Storage }); i != -1 {
+		return &crd.Spec.Versions[i], nil
+	}
+	if i := slices.IndexFunc(crd.Spec.Versions, func(v crdVersion) bool { return v.Served }); i != -1 {
+		return &crd.Spec.Versions[i], nil
+	}
+	return nil, fmt.Errorf("no served or storage version found")
+}
+
+// convertCRDSchema converts the Kubernetes OpenAPI v3 extensions
+// of a CRD schema into plain JSON Schema, in-place.
+//
+//   - "nullable: true" adds "null" to the "type"
+//   - "x-kubernetes-int-or-string: true" sets "type: [integer, string]"
+//   - "x-kubernetes-preserve-unknown-fields: true" sets "additionalProperties: true"
+//   - "x-kubernetes-list-type: set" sets "uniqueItems: true"
+//
+// All other "x-kubernetes-*" extensions are removed.
+func convertCRDSchema(schema map[string]any) {
+	if schema["x-kubernetes-int-or-string"] == true {
+		if _, ok := schema["anyOf"]; !ok {
+			if _, ok := schema["type"]; !ok {
+				schema["type"] = []any{"integer", "string"}
+			}
+		}
+	}
+	if schema["x-kubernetes-preserve-unknown-fields"] == true {
+		if _, ok := schema["additionalProperties"]; !ok {
+			schema["additionalProperties"] = true
+		}
+	}
+	if schema["x-kubernetes-list-type"] == "set" {
+		schema["uniqueItems"] = true
+	}
+	if schema["nullable"] == true {
+		switch typ := schema["type"].(type) {
+		case string:
+			schema["type"] = []any{typ, "null"}
+		case []any:
+			if !slices.Contains(typ, "null") {
+				schema["type"] = append(typ, "null")
+			}
+		}
+	}
+	delete(schema, "nullable")
+	for key := range schema {
+		if strings.HasPrefix(key, "x-kubernetes-") {
+			delete(schema, key)
+		}
+	}
+
+	for _, key := range []string{"properties", "patternProperties", "definitions", "dependencies"} {
+		if props, ok := schema[key].(map[string]any); ok {
+			for _, prop := range props {
+				if sub, ok := prop.(map[string]any); ok {
+					convertCRDSchema(sub)
+				}
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "additionalItems", "not"} {
+		switch sub := schema[key].(type) {
+		case map[string]any:
+			convertCRDSchema(sub)
+		case []any:
+			convertCRDSchemaSlice(sub)
+		}
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if subs, ok := schema[key].([]any); ok {
+			convertCRDSchemaSlice(subs)
+		}
+	}
+}
+
+func convertCRDSchemaSlice(schemas []any) {
+	for _, sub := range schemas {
+		if sub, ok := sub.(map[string]any); ok {
+			convertCRDSchema(sub)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package pkg
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRDLoader(t *testing.T) {
+	tests := []struct {
+		name string
+		url  *url.URL
+		want *Schema
+	}{
+		{
+			name: "storage version",
+			url:  mustParseURL("crd:crds.yaml"),
+			want: &Schema{Type: "object", Description: "v1"},
+		},
+		{
+			name: "explicit version",
+			url:  mustParseURL("crd:crds.yaml?version=v1alpha1"),
+			want: &Schema{Type: "object", Description: "v1alpha1"},
+		},
+		{
+			name: "with slashes",
+			url:  mustParseURL("crd://./crds.yaml?version=v1alpha1"),
+			want: &Schema{Type: "object", Description: "v1alpha1"},
+		},
+		{
+			name: "first served version",
+			url:  mustParseURL("crd:served.yaml"),
+			want: &Schema{Type: "object", Description: "v2"},
+		},
+		{
+			name: "by name",
+			url:  mustParseURL("crd:multiple.yaml?name=bars.example.com"),
+			want: &Schema{Type: "object", Description: "bar"},
+		},
+	}
+
+	fsys := fstest.MapFS{
+		"crds.yaml": {Data: []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+spec:
+  versions:
+    - name: v1alpha1
+      served: true
+      schema:
+        openAPIV3Schema: {type: object, description: v1alpha1}
+    - name: v1
+      served: true
+      storage: true
+      schema:
+        openAPIV3Schema: {type: object, description: v1}
+`)},
+		"served.yaml": {Data: []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+spec:
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema: {type: object, description: v1}
+    - name: v2
+      served: true
+      schema:
+        openAPIV3Schema: {type: object, description: v2}
+`)},
+		"multiple.yaml": {Data: []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+spec:
+  versions:
+    - name: v1
+      storage: true
+      schema:
+        openAPIV3Schema: {type: object, description: foo}
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: bars.example.com
+spec:
+  versions:
+    - name: v1
+      storage: true
+      schema:
+        openAPIV3Schema: {type: object, description: bar}
+`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewCRDLoader(NewFileLoader(fsys, ""))
+			ctx := ContextWithLogger(t.Context(), t)
+			schema, err := loader.Load(ctx, tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schema)
+		})
+	}
+}
+
+func TestCRDLoader_Testdata(t *testing.T) {
+	root, err := os.OpenRoot(filepath.FromSlash("../testdata/bundle"))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, root.Close())
+	}()
+
+	loader := NewCRDLoader(NewFileLoader((*RootFS)(root), ""))
+	ctx := ContextWithLogger(t.Context(), t)
+	schema, err := loader.Load(ctx, mustParseURL("crd:crds/certificate.yaml?name=certificates.cert-manager.io"))
+	require.NoError(t, err)
+
+	spec := schema.Properties["spec"]
+	require.NotNil(t, spec)
+	assert.Equal(t, []string{"issuerRef", "secretName"}, spec.Required)
+	assert.Equal(t, []any{"integer", "string"}, spec.Properties["revisionHistoryLimit"].Type)
+	assert.Equal(t, []any{"string", "null"}, spec.Properties["duration"].Type)
+	assert.Equal(t, SchemaTrue(), spec.Properties["keystores"].AdditionalProperties)
+	assert.True(t, spec.Properties["dnsNames"].UniqueItems)
+}
+
+func TestCRDLoader_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     *url.URL
+		wantErr string
+	}{
+		{
+			name:    "invalid scheme",
+			url:     mustParseURL("file:crds.yaml"),
+			wantErr: `crd url in $ref="file:crds.yaml" must start with "crd:"`,
+		},
+		{
+			name:    "user info",
+			url:     mustParseURL("crd://user@crds.yaml"),
+			wantErr: `crd url in $ref="crd://user@crds.yaml": user info not supported`,
+		},
+		{
+			name:    "empty path",
+			url:     mustParseURL("crd://"),
+			wantErr: `crd url in $ref="crd:" must contain a path`,
+		},
+		{
+			name:    "unsupported query",
+			url:     mustParseURL("crd:crds.yaml?foo=bar"),
+			wantErr: `crd url in $ref="crd:crds.yaml?foo=bar": unsupported query parameter "foo"`,
+		},
+		{
+			name:    "file not found",
+			url:     mustParseURL("crd:does-not-exist.yaml"),
+			wantErr: `open does-not-exist.yaml: file does not exist`,
+		},
+		{
+			name:    "invalid YAML",
+			url:     mustParseURL("crd:invalid.yaml"),
+			wantErr: `invalid.yaml: parse YAML file: yaml: line 1: did not find expected node content`,
+		},
+		{
+			name:    "no CRDs",
+			url:     mustParseURL("crd:no-crds.yaml"),
+			wantErr: `no-crds.yaml: no CustomResourceDefinition found`,
+		},
+		{
+			name:    "multiple CRDs",
+			url:     mustParseURL("crd:crds.yaml"),
+			wantErr: `crds.yaml: found multiple CustomResourceDefinitions, use "?name=" to select one of: foos.example.com, bars.example.com`,
+		},
+		{
+			name:    "CRD not found",
+			url:     mustParseURL("crd:crds.yaml?name=bazs.example.com"),
+			wantErr: `crds.yaml: no CustomResourceDefinition named "bazs.example.com", found: foos.example.com, bars.example.com`,
+		},
+		{
+			name:    "no versions",
+			url:     mustParseURL("crd:crds.yaml?name=foos.example.com"),
+			wantErr: `crds.yaml: CRD "foos.example.com": no versions found`,
+		},
+		{
+			name:    "version not found",
+			url:     mustParseURL("crd:crds.yaml?name=bars.example.com&version=v2"),
+			wantErr: `crds.yaml: CRD "bars.example.com": no version named "v2", found: v1, v1beta1`,
+		},
+		{
+			name:    "no served version",
+			url:     mustParseURL("crd:not-served.yaml"),
+			wantErr: `not-served.yaml: CRD "foos.example.com": no served or storage version found`,
+		},
+		{
+			name:    "no schema",
+			url:     mustParseURL("crd:crds.yaml?name=bars.example.com&version=v1beta1"),
+			wantErr: `crds.yaml: CRD "bars.example.com": version "v1beta1" has no openAPIV3Schema`,
+		},
+		{
+			name:    "encode schema",
+			url:     mustParseURL("crd:not-served.yaml?version=v1"),
+			wantErr: `not-served.yaml: CRD "foos.example.com": encode openAPIV3Schema: json: unsupported value: NaN`,
+		},
+		{
+			name:    "parse schema",
+			url:     mustParseURL("crd:crds.yaml?name=bars.example.com&version=v1"),
+			wantErr: `crds.yaml: CRD "bars.example.com": parse openAPIV3Schema: json: cannot unmarshal number -1 into Go struct field schema.minLength of type uint64`,
+		},
+	}
+
+	fsys := fstest.MapFS{
+		"invalid.yaml": {Data: []byte("foo: {")},
+		"no-crds.yaml": {Data: []byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: foo
+`)},
+		"crds.yaml": {Data: []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: bars.example.com
+spec:
+  versions:
+    - name: v1
+      storage: true
+      schema:
+        openAPIV3Schema: {type: string, minLength: -1}
+    - name: v1beta1
+`)},
+		"not-served.yaml": {Data: []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+spec:
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema: {default: .nan}
+`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewCRDLoader(NewFileLoader(fsys, ""))
+			ctx := ContextWithLogger(t.Context(), t)
+			_, err := loader.Load(ctx, tt.url)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestConvertCRDSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema map[string]any
+		want   map[string]any
+	}{
+		{
+			name:   "int-or-string",
+			schema: map[string]any{"x-kubernetes-int-or-string": true},
+			want:   map[string]any{"type": []any{"integer", "string"}},
+		},
+		{
+			name: "int-or-string with anyOf",
+			schema: map[string]any{
+				"x-kubernetes-int-or-string": true,
+				"anyOf":                      []any{map[string]any{"type": "integer"}, map[string]any{"type": "string"}},
+			},
+			want: map[string]any{
+				"anyOf": []any{map[string]any{"type": "integer"}, map[string]any{"type": "string"}},
+			},
+		},
+		{
+			name:   "preserve-unknown-fields",
+			schema: map[string]any{"type": "object", "x-kubernetes-preserve-unknown-fields": true},
+			want:   map[string]any{"type": "object", "additionalProperties": true},
+		},
+		{
+			name: "preserve-unknown-fields keeps additionalProperties",
+			schema: map[string]any{
+				"x-kubernetes-preserve-unknown-fields": true,
+				"additionalProperties":                 map[string]any{"type": "string"},
+			},
+			want: map[string]any{"additionalProperties": map[string]any{"type": "string"}},
+		},
+		{
+			name:   "list-type set",
+			schema: map[string]any{"type": "array", "x-kubernetes-list-type": "set"},
+			want:   map[string]any{"type": "array", "uniqueItems": true},
+		},
+		{
+			name:   "list-type map",
+			schema: map[string]any{"type": "array", "x-kubernetes-list-type": "map", "x-kubernetes-list-map-keys": []any{"name"}},
+			want:   map[string]any{"type": "array"},
+		},
+		{
+			name:   "nullable",
+			schema: map[string]any{"type": "string", "nullable": true},
+			want:   map[string]any{"type": []any{"string", "null"}},
+		},
+		{
+			name:   "nullable with type list",
+			schema: map[string]any{"type": []any{"integer", "string"}, "nullable": true},
+			want:   map[string]any{"type": []any{"integer", "string", "null"}},
+		},
+		{
+			name:   "nullable with null type",
+			schema: map[string]any{"type": []any{"string", "null"}, "nullable": true},
+			want:   map[string]any{"type": []any{"string", "null"}},
+		},
+		{
+			name:   "nullable false",
+			schema: map[string]any{"type": "string", "nullable": false},
+			want:   map[string]any{"type": "string"},
+		},
+		{
+			name: "nested",
+			schema: map[string]any{
+				"properties": map[string]any{
+					"foo": map[string]any{"nullable": true, "type": "string"},
+				},
+				"patternProperties": map[string]any{
+					"^foo": map[string]any{"x-kubernetes-int-or-string": true},
+				},
+				"items": map[string]any{
+					"x-kubernetes-embedded-resource": true,
+				},
+				"additionalProperties": map[string]any{
+					"x-kubernetes-validations": []any{},
+				},
+				"allOf": []any{
+					map[string]any{"x-kubernetes-int-or-string": true},
+				},
+				"not": []any{
+					map[string]any{"x-kubernetes-int-or-string": true},
+				},
+			},
+			want: map[string]any{
+				"properties": map[string]any{
+					"foo": map[string]any{"type": []any{"string", "null"}},
+				},
+				"patternProperties": map[string]any{
+					"^foo": map[string]any{"type": []any{"integer", "string"}},
+				},
+				"items":                map[string]any{},
+				"additionalProperties": map[string]any{},
+				"allOf": []any{
+					map[string]any{"type": []any{"integer", "string"}},
+				},
+				"not": []any{
+					map[string]any{"type": []any{"integer", "string"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			convertCRDSchema(tt.schema)
+			assert.Equal(t, tt.want, tt.schema)
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Formats of the diagnostics reported by "helm schema lint", and of the errors
+// reported by schema generation, as set by the "--format" flag.
+const (
+	// FormatText logs human-readable lines, and is the default.
+	FormatText = "text"
+	// FormatJSON writes a JSON array of diagnostics.
+	FormatJSON = "json"
+	// FormatSARIF writes a SARIF 2.1.0 log, which can be uploaded to e.g
+	// GitHub code scanning.
+	FormatSARIF = "sarif"
+	// FormatGitHub writes GitHub Actions workflow commands, such as
+	// "::error file=values.yaml,line=1::message", which show up as inline
+	// annotations on pull requests.
+	FormatGitHub = "github"
+)
+
+// Formats lists all supported diagnostics formats.
+var Formats = []string{FormatText, FormatJSON, FormatSARIF, FormatGitHub}
+
+// IDs of the diagnostics that are not semantic lint rules, and therefore
+// cannot be configured in the "lint.rules" config.
+const (
+	// LintRuleMetaschema reports a generated schema that does not conform to
+	// the metaschema of its draft.
+	LintRuleMetaschema = "metaschema"
+	// LintRuleGenerateError reports any error from parsing the input files or
+	// generating the schema.
+	LintRuleGenerateError = "generate-error"
+)
+
+// lintRuleDescriptions are the short descriptions of all diagnostics,
+// used in the rule metadata of the SARIF output.
+var lintRuleDescriptions = map[string]string{
+	LintRuleDefaultMismatch:         `"default" annotation does not match the other annotations on the same key`,
+	LintRuleValueMismatch:           "Value in the values file does not match its own annotations",
+	LintRuleMinGreaterThanMax:       `Minimum annotation is greater than its maximum, such as "minimum" and "maximum"`,
+	LintRuleInvalidPattern:          `"pattern" is not a valid ECMA-262 regular expression`,
+	LintRuleRequiredHidden:          `"required" on a key that is also "hidden"`,
+	LintRuleSkipPropertiesNonObject: `"skipProperties" on a key that is not an object`,
+	LintRuleItemPropertiesNonArray:  `"itemProperties" on a key that is not an array`,
+	LintRuleUnknownConfigField:      "Unknown field in the config file",
+	LintRuleUncoveredKey:            "Values key is not covered by the schema, and is rejected by additionalProperties or unevaluatedProperties",
+	LintRuleMetaschema:              "Generated schema does not conform to the metaschema of its draft",
+	LintRuleGenerateError:           "Error when parsing the input files or generating the schema",
+}
+
+// validateFormat returns an error if format is not one of [Formats].
+// An empty format is the same as [FormatText].
+func validateFormat(format string) error {
+	if format == "" || slices.Contains(Formats, format) {
+		return nil
+	}
+	return fmt.Errorf("invalid --format=%q, must be one of: %s", format, strings.Join(Formats, ", "))
+}
+
+// diagnosticsFromError converts an error from schema generation into
+// diagnostics, keeping the location of errors from parsing the values files
+// and splitting metaschema and merge conflict errors into one diagnostic per
+// violation or conflict.
+func diagnosticsFromError(err error) []LintIssue {
+	var metaschemaErr *MetaschemaError
+	if errors.As(err, &metaschemaErr) {
+		issues := make([]LintIssue, 0, len(metaschemaErr.Violations))
+		for _, violation := range metaschemaErr.Violations {
+			issues = append(issues, LintIssue{
+				Rule:     LintRuleMetaschema,
+				Severity: LintSeverityError,
+				Ptr:      violation.Ptr,
+				Message:  violation.Message,
+			})
+		}
+		return issues
+	}
+
+	var mergeErr *MergeConflictError
+	if errors.As(err, &mergeErr) {
+		issues := make([]LintIssue, 0, len(mergeErr.Conflicts))
+		for _, conflict := range mergeErr.Conflicts {
+			issues = append(issues, LintIssue{
+				Rule:     LintRuleGenerateError,
+				Severity: LintSeverityError,
+				File:     conflict.OtherFile,
+				Ptr:      conflict.Ptr,
+				Message: fmt.Sprintf("type %s conflicts with type %s in %s",
+					formatJSON(conflict.OtherType), formatJSON(conflict.Type), strings.Join(conflict.Files, ", ")),
+			})
+		}
+		return issues
+	}
+
+	var nodeErr *NodeError
+	if errors.As(err, &nodeErr) {
+		return []LintIssue{{
+			Rule:     LintRuleGenerateError,
+			Severity: LintSeverityError,
+			File:     nodeErr.File,
+			Line:     nodeErr.Line,
+			Column:   nodeErr.Column,
+			Ptr:      nodeErr.Ptr,
+			Message:  nodeErr.Err.Error(),
+		}}
+	}
+
+	return []LintIssue{{
+		Rule:     LintRuleGenerateError,
+		Severity: LintSeverityError,
+		Message:  err.Error(),
+	}}
+}
+
+// writeDiagnostics writes the issues to w in one of the structured formats:
+// [FormatJSON], [FormatSARIF], or [FormatGitHub].
+func writeDiagnostics(w io.Writer, format string, issues []LintIssue) error {
+	switch format {
+	case FormatJSON:
+		return writeJSONDiagnostics(w, issues)
+	case FormatSARIF:
+		return writeSARIFDiagnostics(w, issues)
+	case FormatGitHub:
+		return writeGitHubDiagnostics(w, issues)
+	default:
+		return fmt.Errorf("unsupported diagnostics format %q", format)
+	}
+}
+
+type jsonDiagnostic struct {
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	File     string       `json:"file,omitempty"`
+	Line     int          `json:"line,omitempty"`
+	Column   int          `json:"column,omitempty"`
+	Pointer  string       `json:"pointer,omitempty"`
+	Message  string       `json:"message"`
+}
+
+func writeJSONDiagnostics(w io.Writer, issues []LintIssue) error {
+	diagnostics := make([]jsonDiagnostic, 0, len(issues))
+	for _, issue := range issues {
+		diagnostic := jsonDiagnostic{
+			Rule:     issue.Rule,
+			Severity: issue.Severity,
+			File:     issue.File,
+			Line:     issue.Line,
+			Column:   issue.Column,
+			Message:  issue.Message,
+		}
+		if len(issue.Ptr) > 0 {
+			diagnostic.Pointer = issue.Ptr.String()
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return writeIndentedJSON(w, diagnostics)
+}
+
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURL = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolName  = "helm-values-schema-json"
+	sarifToolURL   = "https://github.com/losisin/helm-values-schema-json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+func writeSARIFDiagnostics(w io.Writer, issues []LintIssue) error {
+	ruleIDs := slices.Concat(LintRules, []string{LintRuleMetaschema, LintRuleGenerateError})
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for _, id := range ruleIDs {
+		rules = append(rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: lintRuleDescriptions[id]}})
+	}
+
+	results := make([]sarifResult, 0, len(issues))
+	for _, issue := range issues {
+		result := sarifResult{
+			RuleID:  issue.Rule,
+			Level:   sarifLevel(issue.Severity),
+			Message: sarifMessage{Text: issue.Message},
+		}
+		var location sarifLocation
+		if issue.File != "" {
+			location.PhysicalLocation = &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(issue.File)},
+			}
+			if issue.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: issue.Line, StartColumn: issue.Column}
+			}
+		}
+		if len(issue.Ptr) > 0 {
+			location.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: issue.Ptr.String()}}
+		}
+		if location.PhysicalLocation != nil || location.LogicalLocations != nil {
+			result.Locations = []sarifLocation{location}
+		}
+		results = append(results, result)
+	}
+
+	return writeIndentedJSON(w, sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchemaURL,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           sarifToolName,
+				InformationURI: sarifToolURL,
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	})
+}
+
+// sarifLevel maps a severity to a SARIF result level.
+func sarifLevel(severity LintSeverity) string {
+	switch severity {
+	case LintSeverityError:
+		return "error"
+	case LintSeverityInfo:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+func writeGitHubDiagnostics(w io.Writer, issues []LintIssue) error {
+	for _, issue := range issues {
+		var props []string
+		if issue.File != "" {
+			props = append(props, "file="+githubPropertyEscaper.Replace(filepath.ToSlash(issue.File)))
+			if issue.Line > 0 {
+				props = append(props, fmt.Sprintf("line=%d", issue.Line))
+				if issue.Column > 0 {
+					props = append(props, fmt.Sprintf("col=%d", issue.Column))
+				}
+			}
+		}
+		props = append(props, "title="+githubPropertyEscaper.Replace(issue.Rule))
+
+		message := issue.Message
+		if len(issue.Ptr) > 0 {
+			message = fmt.Sprintf("%s: %s", issue.Ptr, message)
+		}
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", githubCommand(issue.Severity), strings.Join(props, ","), githubMessageEscaper.Replace(message)); err != nil {
+			return fmt.Errorf("write diagnostics: %w", err)
+		}
+	}
+	return nil
+}
+
+// githubCommand maps a severity to a GitHub Actions workflow command.
+func githubCommand(severity LintSeverity) string {
+	switch severity {
+	case LintSeverityError:
+		return "error"
+	case LintSeverityInfo:
+		return "notice"
+	default:
+		return "warning"
+	}
+}
+
+// Escaping of workflow command data and properties, as done by the
+// @actions/core package.
+var (
+	githubMessageEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	)
+	githubPropertyEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	)
+)
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("write diagnostics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range append([]string{""}, Formats...) {
+		assert.NoError(t, validateFormat(format), format)
+	}
+	assert.EqualError(t, validateFormat("xml"), `invalid --format="xml", must be one of: text, json, sarif, github`)
+}
+
+func TestDiagnosticsFromError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []LintIssue
+	}{
+		{
+			name: "generic error",
+			err:  errors.New("values flag is required"),
+			want: []LintIssue{
+				{Rule: LintRuleGenerateError, Severity: LintSeverityError, Message: "values flag is required"},
+			},
+		},
+		{
+			name: "node error",
+			err: fmt.Errorf("parse schema: %w", &NodeError{
+				File: "values.yaml", Line: 2, Column: 3, Ptr: NewPtr("foo", "bar"),
+				Err: errors.New("parse @schema comments: hidden: invalid boolean"),
+			}),
+			want: []LintIssue{
+				{
+					Rule: LintRuleGenerateError, Severity: LintSeverityError,
+					File: "values.yaml", Line: 2, Column: 3, Ptr: NewPtr("foo", "bar"),
+					Message: "parse @schema comments: hidden: invalid boolean",
+				},
+			},
+		},
+		{
+			name: "merge conflict error",
+			err: fmt.Errorf("merge values: %w", &MergeConflictError{Conflicts: []MergeConflict{
+				{Ptr: NewPtr("properties", "replicas"), Type: "integer", Files: []string{"a.yaml", "b.yaml"}, OtherType: "null", OtherFile: "c.yaml"},
+			}}),
+			want: []LintIssue{
+				{
+					Rule: LintRuleGenerateError, Severity: LintSeverityError,
+					File: "c.yaml", Ptr: NewPtr("properties", "replicas"),
+					Message: `type "null" conflicts with type "integer" in a.yaml, b.yaml`,
+				},
+			},
+		},
+		{
+			name: "metaschema error",
+			err: &MetaschemaError{Violations: []MetaschemaViolation{
+				{Ptr: NewPtr("required"), Message: "items at 0 and 1 are equal"},
+				{Ptr: NewPtr("properties", "a", "minimum"), Message: "got string, want number"},
+			}},
+			want: []LintIssue{
+				{Rule: LintRuleMetaschema, Severity: LintSeverityError, Ptr: NewPtr("required"), Message: "items at 0 and 1 are equal"},
+				{Rule: LintRuleMetaschema, Severity: LintSeverityError, Ptr: NewPtr("properties", "a", "minimum"), Message: "got string, want number"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diagnosticsFromError(tt.err))
+		})
+	}
+}
+
+var testDiagnostics = []LintIssue{
+	{
+		Rule: LintRuleValueMismatch, Severity: LintSeverityWarning,
+		File: "values.yaml", Line: 1, Column: 11, Ptr: NewPtr("replicas"),
+		Message: `value does not match annotations: type: got string, want "integer"`,
+	},
+	{
+		Rule: LintRuleUnknownConfigField, Severity: LintSeverityInfo,
+		File: ".schema.yaml", Line: 4,
+		Message: "field fooBar is not a known config field",
+	},
+	{
+		Rule: LintRuleMetaschema, Severity: LintSeverityError,
+		Ptr:     NewPtr("required"),
+		Message: "items at 0 and 1 are equal",
+	},
+	{
+		Rule: LintRuleGenerateError, Severity: LintSeverityError,
+		Message: "100%\nfailed",
+	},
+}
+
+func TestWriteDiagnosticsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeDiagnostics(&buf, FormatJSON, testDiagnostics))
+	assert.JSONEq(t, `[
+		{"rule": "value-mismatch", "severity": "warning", "file": "values.yaml", "line": 1, "column": 11, "pointer": "/replicas", "message": "value does not match annotations: type: got string, want \"integer\""},
+		{"rule": "unknown-config-field", "severity": "info", "file": ".schema.yaml", "line": 4, "message": "field fooBar is not a known config field"},
+		{"rule": "metaschema", "severity": "error", "pointer": "/required", "message": "items at 0 and 1 are equal"},
+		{"rule": "generate-error", "severity": "error", "message": "100%\nfailed"}
+	]`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeDiagnostics(&buf, FormatJSON, nil))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestWriteDiagnosticsSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeDiagnostics(&buf, FormatSARIF, testDiagnostics))
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "helm-values-schema-json", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, len(LintRules)+2)
+	for _, rule := range run.Tool.Driver.Rules {
+		assert.NotEmpty(t, rule.ShortDescription.Text, rule.ID)
+	}
+
+	assert.Equal(t, []sarifResult{
+		{
+			RuleID:  "value-mismatch",
+			Level:   "warning",
+			Message: sarifMessage{Text: `value does not match annotations: type: got string, want "integer"`},
+			Locations: []sarifLocation{{
+				PhysicalLocation: &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: "values.yaml"},
+					Region:           &sarifRegion{StartLine: 1, StartColumn: 11},
+				},
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "/replicas"}},
+			}},
+		},
+		{
+			RuleID:  "unknown-config-field",
+			Level:   "note",
+			Message: sarifMessage{Text: "field fooBar is not a known config field"},
+			Locations: []sarifLocation{{
+				PhysicalLocation: &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: ".schema.yaml"},
+					Region:           &sarifRegion{StartLine: 4},
+				},
+			}},
+		},
+		{
+			RuleID:  "metaschema",
+			Level:   "error",
+			Message: sarifMessage{Text: "items at 0 and 1 are equal"},
+			Locations: []sarifLocation{{
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "/required"}},
+			}},
+		},
+		{
+			RuleID:  "generate-error",
+			Level:   "error",
+			Message: sarifMessage{Text: "100%\nfailed"},
+		},
+	}, run.Results)
+}
+
+func TestWriteDiagnosticsGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeDiagnostics(&buf, FormatGitHub, testDiagnostics))
+	assert.Equal(t, ""+
+		"::warning file=values.yaml,line=1,col=11,title=value-mismatch::/replicas: value does not match annotations: type: got string, want \"integer\"\n"+
+		"::notice file=.schema.yaml,line=4,title=unknown-config-field::field fooBar is not a known config field\n"+
+		"::error title=metaschema::/required: items at 0 and 1 are equal\n"+
+		"::error title=generate-error::100%25%0Afailed\n",
+		buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeDiagnostics(&buf, FormatGitHub, []LintIssue{
+		{Rule: "r", Severity: LintSeverityWarning, File: "dir,with:colon/values.yaml", Message: "m"},
+	}))
+	assert.Equal(t, "::warning file=dir%2Cwith%3Acolon/values.yaml,title=r::m\n", buf.String())
+}
+
+func TestWriteDiagnosticsErrors(t *testing.T) {
+	err := writeDiagnostics(&bytes.Buffer{}, FormatText, testDiagnostics)
+	assert.EqualError(t, err, `unsupported diagnostics format "text"`)
+
+	for _, format := range []string{FormatJSON, FormatSARIF, FormatGitHub} {
+		err := writeDiagnostics(errWriter{}, format, testDiagnostics)
+		assert.ErrorContains(t, err, "write diagnostics: ", format)
+	}
+}
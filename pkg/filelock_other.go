@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package pkg
+
+import "os"
+
+// lockFile is a no-op on platforms without file locking, such as WASM,
+// where the cache is not shared between processes anyway.
+func lockFile(*os.File) error {
+	return nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/losisin/helm-values-schema-json/v2/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(testutil.CreateTempDir(t, "schema-filelock-*"), "lock")
+
+	first, err := os.Create(path)
+	require.NoError(t, err)
+	defer closeIgnoreError(first)
+	second, err := os.Open(path)
+	require.NoError(t, err)
+	defer closeIgnoreError(second)
+
+	require.NoError(t, lockFile(first))
+
+	locked := make(chan error)
+	go func() { locked <- lockFile(second) }()
+
+	select {
+	case <-locked:
+		t.Fatal("second lock should block while the first lock is held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, unlockFile(first))
+
+	select {
+	case err := <-locked:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lock was not acquired after the first lock was released")
+	}
+	assert.NoError(t, unlockFile(second))
+}
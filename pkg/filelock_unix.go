@@ -0,0 +1,26 @@
+//go:build unix
+
+package pkg
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes an exclusive advisory lock on the file, blocking until it
+// is available. The lock is released by [unlockFile], or automatically by
+// the OS when the process exits.
+func lockFile(file *os.File) error {
+	for {
+		err := unix.Flock(int(file.Fd()), unix.LOCK_EX) // #nosec G115 -- file descriptors fit in an int
+		if !errors.Is(err, unix.EINTR) {
+			return err
+		}
+	}
+}
+
+func unlockFile(file *os.File) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_UN) // #nosec G115 -- file descriptors fit in an int
+}
@@ -0,0 +1,150 @@
+package pkg
+
+import (
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// stringFormat is a kind of string value recognized when using the
+// "inferFormats" config, which is emitted as either "format" or "pattern".
+type stringFormat struct {
+	Name string
+	// Format is the "format" keyword to emit, if any.
+	Format string
+	// Pattern is the "pattern" keyword to emit, if any. It is valid both as an
+	// ECMA-262 and a Go regular expression.
+	Pattern string
+	// Match reports whether the value is of this kind. It is stricter than
+	// Format and Pattern, to avoid inferring e.g a pattern for image
+	// references from any plain word.
+	Match func(s string) bool
+}
+
+var (
+	durationPattern = `^(?:0|[-+]?(?:(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:ns|us|µs|ms|s|m|h))+)$`
+	durationRegexp  = regexp.MustCompile(`^[-+]?(?:(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:ns|us|µs|ms|s|m|h))+$`)
+
+	cronField   = `(?:[0-9*?/,-]+|(?:JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC|SUN|MON|TUE|WED|THU|FRI|SAT|jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec|sun|mon|tue|wed|thu|fri|sat)(?:[-,][A-Za-z]{3})*)`
+	cronPattern = `^(?:@(?:annually|yearly|monthly|weekly|daily|midnight|hourly)|@every (?:(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:ns|us|µs|ms|s|m|h))+|` + cronField + `(?: +` + cronField + `){4})$`
+	cronRegexp  = regexp.MustCompile(cronPattern)
+
+	// quantityPattern is the same as used by Kubernetes in its OpenAPI schemas.
+	quantityPattern = `^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	// quantityRegexp only matches quantities with a unit suffix, as plain
+	// numbers such as version "1.0" are not necessarily quantities.
+	quantityRegexp = regexp.MustCompile(`^[+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:[KMGTPE]i|[numkMGTPE])$`)
+
+	// imageRefPattern is a simplified version of the image reference grammar
+	// from github.com/distribution/reference.
+	imageRefPattern = `^(?:[a-zA-Z0-9](?:[a-zA-Z0-9.-]*[a-zA-Z0-9])?(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?$`
+	imageRefRegexp  = regexp.MustCompile(imageRefPattern)
+)
+
+// stringFormats are the kinds of string values recognized by
+// [inferStringFormat].
+var stringFormats = []stringFormat{
+	{
+		Name:   "date-time",
+		Format: "date-time",
+		Match: func(s string) bool {
+			_, err := time.Parse(time.RFC3339, s)
+			return err == nil
+		},
+	},
+	{
+		Name:   "email",
+		Format: "email",
+		Match: func(s string) bool {
+			addr, err := mail.ParseAddress(s)
+			return err == nil && addr.Name == "" && addr.Address == s
+		},
+	},
+	{
+		Name:   "ipv4",
+		Format: "ipv4",
+		Match: func(s string) bool {
+			addr, err := netip.ParseAddr(s)
+			return err == nil && addr.Is4()
+		},
+	},
+	{
+		Name:   "ipv6",
+		Format: "ipv6",
+		Match: func(s string) bool {
+			addr, err := netip.ParseAddr(s)
+			return err == nil && addr.Is6() && addr.Zone() == ""
+		},
+	},
+	{
+		Name:   "uri",
+		Format: "uri",
+		Match: func(s string) bool {
+			u, err := url.Parse(s)
+			return err == nil && u.Scheme != "" && u.Host != "" && !strings.ContainsAny(s, " \t\n")
+		},
+	},
+	{
+		Name:    "duration",
+		Pattern: durationPattern,
+		Match:   durationRegexp.MatchString,
+	},
+	{
+		Name:    "cron",
+		Pattern: cronPattern,
+		Match:   cronRegexp.MatchString,
+	},
+	{
+		Name:    "quantity",
+		Pattern: quantityPattern,
+		Match:   quantityRegexp.MatchString,
+	},
+	{
+		Name:    "image",
+		Pattern: imageRefPattern,
+		Match: func(s string) bool {
+			// Requires a registry or namespace, and a tag or digest,
+			// as e.g "nginx" or "app:web" could be anything.
+			name, _, _ := strings.Cut(s, "@")
+			return imageRefRegexp.MatchString(s) && strings.Contains(name, "/") &&
+				(strings.Contains(s, "@") || strings.LastIndex(name, ":") > strings.LastIndex(name, "/"))
+		},
+	},
+}
+
+// inferStringFormat returns the kind of string that all of the string values
+// are, such as "uri" or "duration". Returns false when there are no strings,
+// any string is not recognized, or when the strings match several kinds,
+// such as "500m" which is both a Go duration and a Kubernetes quantity.
+func inferStringFormat(values []any) (stringFormat, bool) {
+	var strs []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	if len(strs) == 0 {
+		return stringFormat{}, false
+	}
+
+	var found []stringFormat
+	for _, format := range stringFormats {
+		matchesAll := true
+		for _, s := range strs {
+			if !format.Match(s) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			found = append(found, format)
+		}
+	}
+	if len(found) != 1 {
+		return stringFormat{}, false
+	}
+	return found[0], true
+}
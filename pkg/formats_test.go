@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferStringFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []any
+		want   string
+	}{
+		{name: "no values", values: nil},
+		{name: "no strings", values: []any{1, true, nil}},
+		{name: "empty string", values: []any{""}},
+		{name: "plain word", values: []any{"debug"}},
+		{name: "version", values: []any{"1.0"}},
+
+		{name: "date-time", values: []any{"2024-01-02T03:04:05Z", "2024-01-02T03:04:05.123+02:00"}, want: "date-time"},
+		{name: "date only", values: []any{"2024-01-02"}},
+
+		{name: "email", values: []any{"admin@example.com"}, want: "email"},
+		{name: "email with name", values: []any{"Admin <admin@example.com>"}},
+
+		{name: "ipv4", values: []any{"10.0.0.1", "127.0.0.1"}, want: "ipv4"},
+		{name: "ipv6", values: []any{"::1", "fe80::1"}, want: "ipv6"},
+		{name: "ipv4 and ipv6", values: []any{"10.0.0.1", "::1"}},
+
+		{name: "uri", values: []any{"https://example.com", "http://localhost:8080/path?q=1"}, want: "uri"},
+		{name: "uri without host", values: []any{"mailto:admin@example.com"}},
+		{name: "relative uri", values: []any{"/path"}},
+
+		{name: "duration", values: []any{"30s", "1h30m", "1.5s", "100ms"}, want: "duration"},
+		{name: "zero duration", values: []any{"0"}},
+
+		{name: "cron", values: []any{"*/5 * * * *", "0 0 1 JAN MON-FRI", "@daily", "@every 1h"}, want: "cron"},
+		{name: "cron wrong field count", values: []any{"* * * *"}},
+		{name: "cron words", values: []any{"foo bar baz qux quu"}},
+
+		{name: "quantity", values: []any{"512Mi", "1Gi", "100k"}, want: "quantity"},
+		{name: "quantity without suffix", values: []any{"512"}},
+		{name: "ambiguous quantity or duration", values: []any{"500m"}},
+		{name: "quantity disambiguates", values: []any{"500m", "1Gi"}, want: "quantity"},
+		{name: "duration disambiguates", values: []any{"5m", "30s"}, want: "duration"},
+
+		{name: "image", values: []any{"docker.io/library/nginx:1.25", "ghcr.io/org/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}, want: "image"},
+		{name: "image with port", values: []any{"localhost:5000/app:latest"}, want: "image"},
+		{name: "image without registry", values: []any{"nginx:1.25"}},
+		{name: "image without tag", values: []any{"bitnami/nginx"}},
+		{name: "image uppercase", values: []any{"org/App:1"}},
+
+		{name: "mixed with non-strings", values: []any{"30s", nil, 1}, want: "duration"},
+		{name: "mixed formats", values: []any{"30s", "512Mi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := inferStringFormat(tt.values)
+			if tt.want == "" {
+				assert.False(t, ok, "got %q", got.Name)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got.Name)
+		})
+	}
+}
+
+func TestStringFormatPatterns(t *testing.T) {
+	samples := map[string][]string{
+		"duration": {"0", "30s", "-1.5h", "1h30m", "10µs"},
+		"cron":     {"*/5 * * * *", "0 0 1 jan mon-fri", "@hourly", "@every 1h30m"},
+		"quantity": {"1", "512Mi", "1.5", "100m", "1e3"},
+		"image":    {"nginx", "nginx:1.25", "docker.io/library/nginx:1.25", "localhost:5000/app@sha256:0123456789abcdef0123456789abcdef"},
+	}
+
+	for _, format := range stringFormats {
+		if format.Pattern == "" {
+			assert.NotEmpty(t, format.Format, format.Name)
+			continue
+		}
+		t.Run(format.Name, func(t *testing.T) {
+			// Patterns are emitted in the schema, so they must be valid
+			// ECMA-262 regular expressions.
+			re, err := compileECMAPattern(format.Pattern)
+			require.NoError(t, err)
+			require.NotEmpty(t, samples[format.Name])
+			for _, sample := range samples[format.Name] {
+				ok, err := re.MatchString(sample)
+				require.NoError(t, err)
+				assert.True(t, ok, sample)
+			}
+			ok, err := re.MatchString("not valid!")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -21,6 +22,19 @@ func GenerateJsonSchema(ctx context.Context, config *Config) error {
 		return err
 	}
 
+	if config.ValidateMetaschema {
+		var err error
+		if cmp.Or(config.Format, FormatText) == FormatText {
+			err = validateMetaschemaAndLog(ctx, mergedSchema, config.Draft)
+		} else {
+			// Violations are reported as diagnostics by the caller instead.
+			err = checkMetaschema(mergedSchema, config.Draft)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	indentString := strings.Repeat(" ", config.Indent)
 	return WriteOutput(ctx, mergedSchema, filepath.FromSlash(config.Output), indentString)
 }
@@ -30,6 +44,12 @@ func GenerateJsonSchema(ctx context.Context, config *Config) error {
 // shared by [GenerateJsonSchema] and "helm schema lint" so both run the exact
 // same parsing and validation.
 func buildJSONSchema(ctx context.Context, config *Config) (*Schema, error) {
+	return buildJSONSchemaWithLoader(ctx, config, nil)
+}
+
+// buildJSONSchemaWithLoader is [buildJSONSchema], where wrapLoader, when not
+// nil, wraps the [Loader] used when bundling. See [BundleOptions].
+func buildJSONSchemaWithLoader(ctx context.Context, config *Config, wrapLoader func(Loader) Loader) (*Schema, error) {
 	// Check if the values flag is set
 	if len(config.Values) == 0 {
 		return nil, errors.New("values flag is required")
@@ -52,8 +72,32 @@ func buildJSONSchema(ctx context.Context, config *Config) (*Schema, error) {
 		return nil, errors.New("indentation must be an even number")
 	}
 
+	if err := validateMergeStrategy(config.MergeStrategy); err != nil {
+		return nil, err
+	}
+	if err := validateItemsMode(config.ItemsMode); err != nil {
+		return nil, err
+	}
+	if config.ItemsMode == ItemsModeTuple && config.Draft < 2020 {
+		return nil, fmt.Errorf("itemsMode %q requires draft 2020, as prefixItems is not supported by draft %d", ItemsModeTuple, config.Draft)
+	}
+
 	// Initialize a Schema to hold the merged YAML data
 	mergedSchema := &Schema{}
+	typeMerger := newTypeMerger(config.MergeStrategy)
+	valueInferrer := newValueInferrer(config)
+	refAliases := newRefAliases(config.K8sSchemaURL, config.K8sSchemaVersion, config.RefAliases)
+	var k8sRefs k8sRefTable
+	if config.AutoK8sRefs {
+		k8sRefs = newK8sRefTable(config.K8sRefs)
+	}
+	var placeholders *placeholderRules
+	if config.Placeholders {
+		placeholders, err = newPlaceholderRules(config.PlaceholderTypes)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Iterate over the input YAML files
 	for _, filePath := range config.Values {
@@ -66,69 +110,119 @@ func buildJSONSchema(ctx context.Context, config *Config) (*Schema, error) {
 		// as the YAML parser incorrectly includes them in comments otherwise
 		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
 
-		var node yaml.Node
-		if err := yaml.Unmarshal(content, &node); err != nil {
-			return nil, errors.New("error unmarshalling YAML")
+		docs, err := decodeYAMLDocuments(content)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling YAML: %w", err)
 		}
 
-		if len(node.Content) == 0 {
-			continue // Skip empty files
-		}
+		// Each document in the file is merged as if it was a separate file
+		for _, doc := range docs {
+			// Document is only set in files with multiple documents
+			document := 0
+			source := filePath
+			if multiDocument(docs) {
+				document = doc.Number
+				source = fmt.Sprintf("%s (document %d)", filePath, document)
+			}
 
-		rootNode := node.Content[0]
-		properties := make(map[string]*Schema)
-		required := []string{}
+			rootNode := doc.Content[0]
+			properties := make(map[string]*Schema)
+			required := []string{}
 
-		for i := 0; i < len(rootNode.Content); i += 2 {
-			keyNode := rootNode.Content[i]
-			valNode := rootNode.Content[i+1]
-			schema, err := parseNode(NewPtr(keyNode.Value), keyNode, valNode, config.UseHelmDocs)
+			rootPairs, err := mappingPairs(rootNode)
 			if err != nil {
-				return nil, fmt.Errorf("parse schema: %w", err)
+				return nil, fmt.Errorf("parse schema: %w", &NodeError{File: filePath, Document: document, Line: rootNode.Line, Column: rootNode.Column, Err: err})
 			}
+			parser := newNodeParser(rootNode, config.UseHelmDocs, config.AnchorRefs)
+			parser.placeholders = placeholders
+			parser.itemsMode = config.ItemsMode
+			parser.draft = config.Draft
+			parser.refAliases = refAliases
+			for _, pair := range rootPairs {
+				keyNode := pair.Key
+				schema, err := parser.parse(NewPtr(keyNode.Value), keyNode, pair.Value)
+				if err != nil {
+					var nodeErr *NodeError
+					if errors.As(err, &nodeErr) {
+						nodeErr.File = filePath
+						nodeErr.Document = document
+					}
+					return nil, fmt.Errorf("parse schema: %w", err)
+				}
 
-			// Exclude hidden nodes
-			if schema != nil && !schema.Hidden {
-				properties[keyNode.Value] = schema
-				if schema.RequiredByParent {
-					required = append(required, keyNode.Value)
+				// Exclude hidden nodes
+				if schema != nil && !schema.Hidden {
+					properties[keyNode.Value] = schema
+					if schema.RequiredByParent {
+						required = append(required, keyNode.Value)
+					}
 				}
 			}
-		}
 
-		// Create a temporary Schema to merge from the nodes
-		tempSchema := &Schema{
-			Type:        "object",
-			Properties:  properties,
-			Required:    required,
-			Title:       config.SchemaRoot.Title,
-			Description: config.SchemaRoot.Description,
-			ID:          config.SchemaRoot.ID,
-		}
+			// Create a temporary Schema to merge from the nodes
+			tempSchema := &Schema{
+				Type:        "object",
+				Properties:  properties,
+				Required:    required,
+				Title:       config.SchemaRoot.Title,
+				Description: config.SchemaRoot.Description,
+				ID:          config.SchemaRoot.ID,
+			}
+			if len(parser.defs) > 0 {
+				tempSchema.Defs = parser.defs
+			}
 
-		tempSchema.SetReferrer(fileReferrer)
-		// Set root $ref after updating the referrer on all other $refs
-		if config.SchemaRoot.Ref != "" {
-			tempSchema.Ref = config.SchemaRoot.Ref
-			tempSchema.RefReferrer = config.SchemaRoot.RefReferrer
-		}
+			// Add "$ref: $k8s/..." to well-known keys, before the aliases are
+			// expanded below, and relative to the values file same as annotations
+			k8sRefs.apply(tempSchema)
 
-		// Apply "$ref: $k8s/..." transformation
-		if err := updateRefK8sAlias(tempSchema, config.K8sSchemaURL, config.K8sSchemaVersion); err != nil {
-			return nil, fmt.Errorf("%s: %w", filePath, err)
-		}
+			tempSchema.SetReferrer(fileReferrer)
+			// Set root $ref after updating the referrer on all other $refs
+			if config.SchemaRoot.Ref != "" {
+				tempSchema.Ref = config.SchemaRoot.Ref
+				tempSchema.RefReferrer = config.SchemaRoot.RefReferrer
+			}
 
-		// Merge with existing data
-		mergedSchema = mergeSchemas(mergedSchema, tempSchema)
-		mergedSchema.Required = uniqueStringAppend(mergedSchema.Required, required)
+			// Apply "$ref: $k8s/..." and other alias transformations
+			if err := refAliases.update(tempSchema); err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+
+			valueInferrer.record(nil, tempSchema, rootNode)
+
+			// Merge with existing data
+			typeMerger.merge(nil, mergedSchema, tempSchema, source)
+			mergedSchema = mergeSchemas(mergedSchema, tempSchema)
+			mergedSchema.Required = uniqueStringAppend(mergedSchema.Required, required)
+		}
 	}
+	if err := typeMerger.err(); err != nil {
+		return nil, err
+	}
+	clearItemsModes(mergedSchema)
+	valueInferrer.apply(nil, mergedSchema, false)
 
 	if config.Bundle {
 		cacheMinDuration, err := ParseCacheMinDuration(config.BundleCacheMin)
 		if err != nil {
 			return nil, err
 		}
-		if err := Bundle(ctx, mergedSchema, config.Output, config.BundleRoot, config.BundleWithoutID, config.K8sSchemaURL, config.K8sSchemaVersion, cacheMinDuration); err != nil {
+		cacheMaxSize, err := ParseCacheMaxSize(config.BundleCacheMaxSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := Bundle(ctx, mergedSchema, config.Output, BundleOptions{
+			BundleRoot:       config.BundleRoot,
+			WithoutIDs:       config.BundleWithoutID,
+			K8sSchemaURL:     config.K8sSchemaURL,
+			K8sSchemaVersion: config.K8sSchemaVersion,
+			CacheMinDuration: cacheMinDuration,
+			CacheDir:         config.BundleCacheDir,
+			CacheMaxSize:     cacheMaxSize,
+			RefMirrors:       config.RefMirrors,
+			RefAliases:       config.RefAliases,
+			wrapLoader:       wrapLoader,
+		}); err != nil {
 			return nil, err
 		}
 	}
@@ -151,6 +245,54 @@ func buildJSONSchema(ctx context.Context, config *Config) (*Schema, error) {
 	return mergedSchema, nil
 }
 
+// yamlDocument is a document decoded by [decodeYAMLDocuments].
+type yamlDocument struct {
+	*yaml.Node
+	// Number is the 1-based position of the document in the YAML stream,
+	// which also counts the empty documents that were left out.
+	Number int
+}
+
+// decodeYAMLDocuments decodes every document in the YAML stream, where
+// documents are separated by "---". Empty documents are left out, but still
+// counted in the document numbers, so they match the document in the file.
+func decodeYAMLDocuments(content []byte) ([]yamlDocument, error) {
+	var docs []yamlDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for number := 1; ; number++ {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			if number == 1 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("document %d: %w", number, err)
+		}
+		if !isEmptyYAMLDocument(&doc) {
+			docs = append(docs, yamlDocument{Node: &doc, Number: number})
+		}
+	}
+}
+
+// multiDocument reports whether the document numbers should be shown in
+// messages, which is when there are multiple documents, or the only document
+// is not the first one in the file.
+func multiDocument(docs []yamlDocument) bool {
+	return len(docs) > 1 || (len(docs) == 1 && docs[0].Number > 1)
+}
+
+// isEmptyYAMLDocument reports whether the document has no content, such as
+// an empty file, or an empty document between two "---" separators.
+func isEmptyYAMLDocument(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 {
+		return true
+	}
+	root := doc.Content[0]
+	return root.Kind == yaml.ScalarNode && root.ShortTag() == "!!null" && root.Value == ""
+}
+
 func readInputFile(stdin io.Reader, filePath string) (Referrer, []byte, error) {
 	if filePath == "-" {
 		content, err := io.ReadAll(stdin)
@@ -71,6 +71,26 @@ func TestGenerateJsonSchema(t *testing.T) {
 			templateSchemaFile: "../testdata/k8sRef.schema.json",
 		},
 
+		{
+			name: "user-defined ref aliases",
+			config: &Config{
+				Draft:  2020,
+				Indent: 4,
+				RefAliases: map[string]RefAlias{
+					"crd": {
+						URL:  "https://raw.githubusercontent.com/datreeio/CRDs-catalog/{{ .ref }}/",
+						Vars: map[string]string{"ref": "main"},
+					},
+					"schemastore": {URL: "https://json.schemastore.org/"},
+				},
+				Values: []string{
+					"../testdata/refAliases.yaml",
+				},
+				Output: "../testdata/refAliases_output.json",
+			},
+			templateSchemaFile: "../testdata/refAliases.schema.json",
+		},
+
 		{
 			name: "ref draft 7",
 			config: &Config{
@@ -262,6 +282,34 @@ func TestGenerateJsonSchema(t *testing.T) {
 			},
 			templateSchemaFile: "../testdata/bundle/yaml.schema.json",
 		},
+		{
+			name: "bundle/crd",
+			config: &Config{
+				Draft:      2020,
+				Indent:     4,
+				Bundle:     true,
+				BundleRoot: "..",
+				Values: []string{
+					"../testdata/bundle/crd.yaml",
+				},
+				Output: "../testdata/bundle/crd_output.json",
+			},
+			templateSchemaFile: "../testdata/bundle/crd.schema.json",
+		},
+		{
+			name: "bundle/chart",
+			config: &Config{
+				Draft:      2020,
+				Indent:     4,
+				Bundle:     true,
+				BundleRoot: "..",
+				Values: []string{
+					"../testdata/bundle/chart.yaml",
+				},
+				Output: "../testdata/bundle/chart_output.json",
+			},
+			templateSchemaFile: "../testdata/bundle/chart.schema.json",
+		},
 		{
 			// https://github.com/losisin/helm-values-schema-json/issues/159
 			name: "bundle/root-ref",
@@ -623,7 +671,7 @@ func TestGenerateJsonSchema_Errors(t *testing.T) {
 				},
 				Output: "../testdata/k8sRef_output.json",
 			},
-			expectedErr: errors.New("/properties/memory: must set k8sSchemaVersion config when using \"$ref: $k8s/...\""),
+			expectedErr: errors.New("parse schema: /memory: parse @schema comments: $ref: must set k8sSchemaVersion config when using \"$ref: $k8s/...\""),
 		},
 		{
 			// When a local schema file (loaded during bundling) contains $ref: $k8s/...,
@@ -653,7 +701,7 @@ func TestGenerateJsonSchema_Errors(t *testing.T) {
 				},
 				Output: "../testdata/fail-type_output.json",
 			},
-			expectedErr: errors.New("/properties/nameOverride/type: invalid type \"foobar\", must be one of: array, boolean, integer, null, number, object, string"),
+			expectedErr: errors.New("parse schema: /nameOverride: parse @schema comments: /type: invalid type \"foobar\", must be one of: array, boolean, integer, null, number, object, string"),
 		},
 		{
 			name: "invalid helm-docs comment",
@@ -668,6 +716,19 @@ func TestGenerateJsonSchema_Errors(t *testing.T) {
 			},
 			expectedErr: errors.New("parse schema: /foo: parse helm-docs comment: '# @schema' comments are not supported in helm-docs comments."),
 		},
+		{
+			name: "metaschema violation",
+			config: &Config{
+				Draft:              2020,
+				Indent:             4,
+				ValidateMetaschema: true,
+				Values: []string{
+					"../testdata/lint/values-metaschema.yaml",
+				},
+				Output: "../testdata/lint/values-metaschema_output.json",
+			},
+			expectedErr: errors.New("schema does not conform to its metaschema: found 1 violation(s)"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -984,3 +1045,128 @@ func TestWriteOutputFile_WriteToStdoutError(t *testing.T) {
 		_ = os.Remove("-")
 	}()
 }
+
+func TestBuildJSONSchema_MultiDocument(t *testing.T) {
+	config := &Config{Values: []string{"../testdata/multidoc/values.yaml"}, Draft: 2020, Indent: 4}
+	schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), config)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Schema{Type: "object", Properties: map[string]*Schema{
+		"repository": {Type: "string"},
+		"tag":        {Type: "string"},
+		"pullPolicy": {Type: "string"},
+	}}, schema.Properties["image"])
+	assert.Equal(t, &Schema{Type: "integer", Minimum: float64Ptr(1)}, schema.Properties["replicas"])
+	assert.Equal(t, &Schema{Type: "object", Properties: map[string]*Schema{
+		"enabled": {Type: "boolean"},
+	}}, schema.Properties["ingress"])
+}
+
+func TestBuildJSONSchema_MultiDocumentErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        string
+		mergeStrategy string
+		wantErr       string
+		wantDocument  int
+	}{
+		{
+			name:    "invalid YAML in first document",
+			values:  "a: [\n---\nb: 1\n",
+			wantErr: "error unmarshalling YAML: yaml: line",
+		},
+		{
+			name:    "invalid YAML in later document",
+			values:  "a: 1\n---\nb: [\n",
+			wantErr: "error unmarshalling YAML: document 2: yaml: line",
+		},
+		{
+			name:         "invalid annotation",
+			values:       "a: 1\n---\nb: 1 # @schema minimum: foo\n",
+			wantErr:      "parse schema: document 2: /b: parse @schema comments:",
+			wantDocument: 2,
+		},
+		{
+			name:    "invalid annotation after empty documents",
+			values:  "a: 1\n---\n---\nb: 1 # @schema minimum: foo\n",
+			wantErr: "parse schema: document 3: /b: parse @schema comments:",
+			// Empty documents are counted, so the number matches the file
+			wantDocument: 3,
+		},
+		{
+			name:         "invalid annotation in single document after empty document",
+			values:       "---\n---\nb: 1 # @schema minimum: foo\n",
+			wantErr:      "parse schema: document 2: /b: parse @schema comments:",
+			wantDocument: 2,
+		},
+		{
+			name:    "invalid annotation in single document",
+			values:  "---\nb: 1 # @schema minimum: foo\n---\n",
+			wantErr: "parse schema: /b: parse @schema comments:",
+		},
+		{
+			name:          "merge conflict",
+			values:        "a: 1\n---\na: foo\n",
+			mergeStrategy: MergeStrategyStrict,
+			wantErr:       `/properties/a: type "integer" in values.yaml (document 1), but "string" in values.yaml (document 2)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			require.NoError(t, os.WriteFile("values.yaml", []byte(tt.values), 0o644))
+			_, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &Config{
+				Values:        []string{"values.yaml"},
+				Draft:         2020,
+				Indent:        4,
+				MergeStrategy: tt.mergeStrategy,
+			})
+			require.ErrorContains(t, err, tt.wantErr)
+
+			var nodeErr *NodeError
+			if tt.wantDocument > 0 {
+				require.ErrorAs(t, err, &nodeErr)
+				assert.Equal(t, tt.wantDocument, nodeErr.Document)
+				assert.Equal(t, "values.yaml", nodeErr.File)
+			}
+		})
+	}
+}
+
+func TestDecodeYAMLDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []int // line of each document
+		wantNum []int // number of each document
+		wantErr string
+	}{
+		{name: "empty", content: ""},
+		{name: "comments only", content: "# foo\n"},
+		{name: "single document", content: "a: 1\n", want: []int{1}, wantNum: []int{1}},
+		{name: "leading and trailing separators", content: "---\na: 1\n---\n", want: []int{2}, wantNum: []int{1}},
+		{name: "multiple documents", content: "a: 1\n---\n---\nb: 2\n---\nnull\n", want: []int{1, 4, 6}, wantNum: []int{1, 3, 4}},
+		{name: "invalid first document", content: "a: [\n", wantErr: "yaml: line"},
+		{name: "invalid later document", content: "a: 1\n---\n---\nb: [\n", wantErr: "document 3: yaml: line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := decodeYAMLDocuments([]byte(tt.content))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				assert.NotContains(t, err.Error(), "document 1")
+				return
+			}
+			require.NoError(t, err)
+			var lines, numbers []int
+			for _, doc := range docs {
+				lines = append(lines, doc.Content[0].Line)
+				numbers = append(numbers, doc.Number)
+			}
+			assert.Equal(t, tt.want, lines)
+			assert.Equal(t, tt.wantNum, numbers)
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"slices"
 	"strings"
 	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
 )
 
 // This became a quite long regexp, but it needs to handle the following special cases:
@@ -97,6 +99,67 @@ func ParseHelmDocsComment(helmDocsComments []string) (HelmDocsComment, error) {
 	return helmDocs, nil
 }
 
+// SchemaType returns the JSON schema type of the helm-docs type, such as
+// "integer" for "(int)", or an empty string for custom types that have no
+// JSON schema equivalent. Template types such as "(tpl/array)" are strings,
+// as the template is only rendered by the chart.
+func (c HelmDocsComment) SchemaType() string {
+	if strings.HasPrefix(c.Type, "tpl/") {
+		return "string"
+	}
+	switch c.Type {
+	case "string", "tpl":
+		return "string"
+	case "int", "integer":
+		return "integer"
+	case "float", "number":
+		return "number"
+	case "bool", "boolean":
+		return "boolean"
+	case "list", "array":
+		return "array"
+	case "object", "dict", "map":
+		return "object"
+	default:
+		return ""
+	}
+}
+
+// SchemaDefault returns the "@default" value when it is a literal value,
+// such as "# @default -- `{}`", "# @default -- 80" or "# @default -- "latest"".
+// Free-form text such as "# @default -- the chart appVersion" is only meant
+// as documentation, and returns false, as do null values.
+func (c HelmDocsComment) SchemaDefault() (any, bool) {
+	value := strings.TrimSpace(c.Default)
+	unquoted, backticks := strings.CutPrefix(value, "`")
+	if backticks {
+		unquoted, backticks = strings.CutSuffix(unquoted, "`")
+	}
+	if backticks && !strings.Contains(unquoted, "`") {
+		value = strings.TrimSpace(unquoted)
+	} else {
+		backticks = false
+	}
+	if value == "" {
+		return nil, false
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil || len(doc.Content) == 0 {
+		return nil, false
+	}
+	node := doc.Content[0]
+	if !backticks && node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && node.Style == 0 {
+		// Unquoted text
+		return nil, false
+	}
+	var parsed any
+	if err := node.Decode(&parsed); err != nil || parsed == nil {
+		return nil, false
+	}
+	return parsed, true
+}
+
 // ParseHelmDocsPath parses the path part of a helm-docs comment. This has
 // some weird parsing logic, but it's created to try replicate the logic
 // observed when running helm-docs. We can't just copy or reference their
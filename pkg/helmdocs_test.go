@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/losisin/helm-values-schema-json/v2/internal/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -228,6 +229,71 @@ func TestParseHelmDocsComment(t *testing.T) {
 	}
 }
 
+func TestHelmDocsCommentSchemaType(t *testing.T) {
+	tests := []struct {
+		helmDocsType string
+		want         string
+	}{
+		{helmDocsType: "", want: ""},
+		{helmDocsType: "string", want: "string"},
+		{helmDocsType: "int", want: "integer"},
+		{helmDocsType: "integer", want: "integer"},
+		{helmDocsType: "float", want: "number"},
+		{helmDocsType: "number", want: "number"},
+		{helmDocsType: "bool", want: "boolean"},
+		{helmDocsType: "boolean", want: "boolean"},
+		{helmDocsType: "list", want: "array"},
+		{helmDocsType: "array", want: "array"},
+		{helmDocsType: "object", want: "object"},
+		{helmDocsType: "dict", want: "object"},
+		{helmDocsType: "map", want: "object"},
+		{helmDocsType: "tpl", want: "string"},
+		{helmDocsType: "tpl/array", want: "string"},
+		{helmDocsType: "tpl/object", want: "string"},
+		{helmDocsType: "myType", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.helmDocsType, func(t *testing.T) {
+			assert.Equal(t, tt.want, HelmDocsComment{Type: tt.helmDocsType}.SchemaType())
+		})
+	}
+}
+
+func TestHelmDocsCommentSchemaDefault(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   any
+		wantOK bool
+	}{
+		{name: "empty", value: ""},
+		{name: "empty backticks", value: "``"},
+		{name: "null", value: "null"},
+		{name: "backticks null", value: "`~`"},
+		{name: "quoted string", value: `"latest"`, want: "latest", wantOK: true},
+		{name: "single quoted string", value: `'latest'`, want: "latest", wantOK: true},
+		{name: "integer", value: "3", want: 3, wantOK: true},
+		{name: "boolean", value: "true", want: true, wantOK: true},
+		{name: "flow list", value: "[a, b]", want: []any{"a", "b"}, wantOK: true},
+		{name: "backticks", value: "`{}`", want: map[string]any{}, wantOK: true},
+		{name: "backticks list", value: "` [a, b] `", want: []any{"a", "b"}, wantOK: true},
+		{name: "backticks string", value: "`IfNotPresent`", want: "IfNotPresent", wantOK: true},
+		{name: "free-form text", value: "the chart appVersion"},
+		{name: "free-form text with inline backticks", value: "Defaults to chart `appVersion`"},
+		{name: "multiple backticks", value: "`a` or `b`"},
+		{name: "invalid YAML", value: "{a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := HelmDocsComment{Default: tt.value}.SchemaDefault()
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestParseHelmDocsComment_Error(t *testing.T) {
 	tests := []struct {
 		name    string
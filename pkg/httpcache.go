@@ -4,11 +4,15 @@ import (
 	"cmp"
 	"compress/gzip"
 	"encoding/base32"
+	"errors"
 	"fmt"
+	"io/fs"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -62,13 +66,14 @@ func (h *HTTPMemoryCache) LoadCache(req *http.Request) (CachedResponse, error) {
 }
 
 func (h *HTTPMemoryCache) SaveCache(req *http.Request, resp *http.Response, body []byte) (CachedResponse, error) {
-	maxAge := getCacheControlMaxAge(resp.Header.Get("Cache-Control"))
-	if maxAge <= 0 {
+	maxAge, cacheable := getCacheControlMaxAge(resp.Header.Get("Cache-Control"))
+	maxAge = applyMinCacheDuration(maxAge, h.MinCacheDuration)
+	if !cacheable || maxAge <= 0 {
 		// Response doesn't want to be cached.
 		return CachedResponse{}, nil
 	}
-	maxAge = applyMinCacheDuration(maxAge, h.MinCacheDuration)
 	cached := CachedResponse{
+		URL:      req.URL.Redacted(),
 		Data:     body,
 		CachedAt: h.Now(),
 		MaxAge:   maxAge,
@@ -78,38 +83,124 @@ func (h *HTTPMemoryCache) SaveCache(req *http.Request, resp *http.Response, body
 	return cached, nil
 }
 
+// httpFileCacheExt is the file extension of all files stored by the [HTTPFileCache].
+const httpFileCacheExt = ".cbor.gz"
+
+// httpFileCacheLockFile is the name of the file inside the cache directory
+// that is locked while writing to, evicting from, or clearing the cache,
+// so multiple processes sharing the same cache don't race on the same files.
+const httpFileCacheLockFile = ".lock"
+
+// HTTPFileCache is a [HTTPCache] that stores each response as a gzipped CBOR
+// file inside a cache directory, which is safe to be shared by multiple
+// processes at the same time.
+//
+// Files are written to a temporary file first and then renamed into place,
+// so readers never see partially written files. The modification time of
+// each file is updated on every read, and is used to evict the least
+// recently used files when the cache grows beyond [HTTPFileCache.MaxSize].
 type HTTPFileCache struct {
 	cacheDirFunc func() string
 	now          func() time.Time
 
 	// MinCacheDuration, when greater than zero, raises a cacheable response's
-	// effective max-age to at least this value. It lets users keep downloaded
-	// schemas cached longer than the short max-age that many schema stores
-	// return. Responses the server marks as uncacheable are still not cached.
+	// effective max-age to at least this value, also when the response has no
+	// max-age. It lets users keep downloaded schemas cached longer than the
+	// short max-age that many schema stores return. Responses the server marks
+	// as uncacheable using no-cache or no-store are still not cached.
 	MinCacheDuration time.Duration
+
+	// MaxSize, when greater than zero, is the maximum total size in bytes of
+	// all files in the cache. Least recently used files are removed when
+	// saving a response makes the cache exceed this size.
+	MaxSize int64
 }
 
-func NewHTTPCache(minCacheDuration time.Duration) *HTTPFileCache {
+// HTTPCacheDirEnv is the environment variable that overrides the default
+// directory of the [HTTPFileCache], e.g to let CI/CD pipelines persist the
+// cache between jobs.
+const HTTPCacheDirEnv = "HELM_SCHEMA_CACHE_DIR"
+
+// NewHTTPCache returns a new [HTTPFileCache] that stores its files in cacheDir.
+// See [HTTPCacheDir] for how an empty cacheDir is resolved.
+func NewHTTPCache(cacheDir string, minCacheDuration time.Duration) *HTTPFileCache {
 	return &HTTPFileCache{
-		cacheDirFunc: sync.OnceValue(func() string {
-			dir, err := os.UserCacheDir()
-			if err != nil {
-				// Default to /tmp if there's no user $HOME
-				dir = os.TempDir()
-			}
-			return filepath.Join(dir, "helm-values-schema-json", "httploader")
-		}),
+		cacheDirFunc:     sync.OnceValue(func() string { return HTTPCacheDir(cacheDir) }),
 		now:              time.Now,
 		MinCacheDuration: minCacheDuration,
 	}
 }
 
+// HTTPCacheDir returns the directory used by the [HTTPFileCache].
+// It is the first non-empty value of:
+//
+//   - the dir argument, which comes from the --bundle-cache-dir flag or bundleCacheDir config
+//   - the $HELM_SCHEMA_CACHE_DIR environment variable
+//   - "helm-values-schema-json/httploader" inside [os.UserCacheDir]
+func HTTPCacheDir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+	if dir := os.Getenv(HTTPCacheDirEnv); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		// Default to /tmp if there's no user $HOME
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "helm-values-schema-json", "httploader")
+}
+
+// Dir returns the directory the cache files are stored in.
+func (h *HTTPFileCache) Dir() string {
+	return h.cacheDirFunc()
+}
+
 var _ HTTPCache = &HTTPFileCache{}
 
 func (h *HTTPFileCache) LoadCache(req *http.Request) (CachedResponse, error) {
-	path := filepath.Join(h.cacheDirFunc(), urlToCachePath(req.URL)+".cbor.gz")
+	path := filepath.Join(h.cacheDirFunc(), urlToCachePath(req.URL)+httpFileCacheExt)
+	cached, err := readCacheFile(path)
+	if err != nil {
+		return CachedResponse{}, err
+	}
+	h.touch(path)
+	return cached, nil
+}
+
+// touch marks the file as recently used, for the least recently used
+// eviction in [HTTPFileCache.evict]. Errors are ignored, as a file that was
+// just evicted or cleared by another process is simply downloaded again.
+func (h *HTTPFileCache) touch(path string) {
+	now := h.now()
+	_ = os.Chtimes(path, now, now)
+}
 
-	file, err := os.Open(path) // #nosec G304 -- path is known to be safe thanks to [urlToCachePath]
+// lock creates the cache directory and takes an exclusive lock on its lock
+// file, blocking until any other process holding the lock releases it.
+func (h *HTTPFileCache) lock() (unlock func(), err error) {
+	dir := h.cacheDirFunc()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	path := filepath.Join(dir, httpFileCacheLockFile)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600) // #nosec G304 -- path is inside the cache dir
+	if err != nil {
+		return nil, fmt.Errorf("open cache lock file: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		closeIgnoreError(file)
+		return nil, fmt.Errorf("lock cache: %w", err)
+	}
+	return func() {
+		_ = unlockFile(file)
+		closeIgnoreError(file)
+	}, nil
+}
+
+func readCacheFile(path string) (CachedResponse, error) {
+	file, err := os.Open(path) // #nosec G304 -- path is either from [urlToCachePath] or found inside the cache dir
 	if err != nil {
 		return CachedResponse{}, err
 	}
@@ -129,43 +220,241 @@ func (h *HTTPFileCache) LoadCache(req *http.Request) (CachedResponse, error) {
 }
 
 func (h *HTTPFileCache) SaveCache(req *http.Request, resp *http.Response, body []byte) (CachedResponse, error) {
-	maxAge := getCacheControlMaxAge(resp.Header.Get("Cache-Control"))
-	if maxAge <= 0 {
+	maxAge, cacheable := getCacheControlMaxAge(resp.Header.Get("Cache-Control"))
+	maxAge = applyMinCacheDuration(maxAge, h.MinCacheDuration)
+	if !cacheable || maxAge <= 0 {
 		// Response doesn't want to be cached.
 		return CachedResponse{}, nil
 	}
-
-	maxAge = applyMinCacheDuration(maxAge, h.MinCacheDuration)
 	cached := CachedResponse{
+		URL:      req.URL.Redacted(),
 		Data:     body,
 		CachedAt: h.now().UTC(),
 		MaxAge:   maxAge,
 		ETag:     resp.Header.Get("ETag"),
 	}
-	path := filepath.Join(h.cacheDirFunc(), urlToCachePath(req.URL)+".cbor.gz")
+	path := filepath.Join(h.cacheDirFunc(), urlToCachePath(req.URL)+httpFileCacheExt)
+
+	unlock, err := h.lock()
+	if err != nil {
+		return CachedResponse{}, err
+	}
+	defer unlock()
 
 	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
 		return CachedResponse{}, fmt.Errorf("mkdir: %w", err)
 	}
+	if err := writeCacheFile(path, cached); err != nil {
+		return CachedResponse{}, err
+	}
+	h.touch(path)
+	if err := h.evict(path); err != nil {
+		return CachedResponse{}, fmt.Errorf("evict cache: %w", err)
+	}
+	return cached, nil
+}
 
-	file, err := os.Create(path) // #nosec G304 -- path is known to be safe thanks to [urlToCachePath]
+// writeCacheFile writes the cached response to a temporary file next to path
+// and then renames it into place, so that concurrent readers either see the
+// old or the new file, but never a partially written one.
+func writeCacheFile(path string, cached CachedResponse) (err error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
-		return CachedResponse{}, fmt.Errorf("create cache file: %w", err)
+		return fmt.Errorf("create cache file: %w", err)
 	}
-	defer closeIgnoreError(file)
+	defer func() {
+		if err != nil {
+			closeIgnoreError(file)
+			_ = os.Remove(file.Name())
+		}
+	}()
 	gzipWriter := gzip.NewWriter(file)
-	defer closeIgnoreError(gzipWriter)
-	cborEncoder := cbor.NewEncoder(gzipWriter)
-	return cached, cborEncoder.Encode(cached)
+	if err := cbor.NewEncoder(gzipWriter).Encode(cached); err != nil {
+		return fmt.Errorf("encode cache file: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("compress cache file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close cache file: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("rename cache file: %w", err)
+	}
+	return nil
+}
+
+// evict removes the least recently used files until the total size of the
+// cache is within [HTTPFileCache.MaxSize]. The file at keep, which is the
+// file that was just saved, is never removed even if it alone exceeds the
+// limit. Must only be called while holding the lock.
+func (h *HTTPFileCache) evict(keep string) error {
+	if h.MaxSize <= 0 {
+		return nil
+	}
+	type cacheFile struct {
+		path string
+		info fs.FileInfo
+	}
+	var files []cacheFile
+	var total int64
+	err := h.walkFiles(func(path, _ string, info fs.FileInfo) error {
+		files = append(files, cacheFile{path: path, info: info})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if total <= h.MaxSize {
+		return nil
+	}
+	slices.SortStableFunc(files, func(a, b cacheFile) int {
+		return a.info.ModTime().Compare(b.info.ModTime())
+	})
+	for _, file := range files {
+		if total <= h.MaxSize {
+			break
+		}
+		if file.path == keep {
+			continue
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove cache file: %w", err)
+		}
+		total -= file.info.Size()
+	}
+	return nil
+}
+
+// HTTPFileCacheEntry is a file stored by the [HTTPFileCache],
+// as returned by [HTTPFileCache.List].
+type HTTPFileCacheEntry struct {
+	// Path is the file path, relative to [HTTPFileCache.Dir].
+	Path string
+	// Size is the size of the compressed file on disk.
+	Size int64
+	// Err is set when the file could not be decoded,
+	// in which case the [CachedResponse] is empty.
+	Err error
+
+	CachedResponse
+}
+
+// List returns all files stored in the cache, sorted by their path.
+// A cache directory that does not exist yet results in an empty list.
+func (h *HTTPFileCache) List() ([]HTTPFileCacheEntry, error) {
+	var entries []HTTPFileCacheEntry
+	err := h.walkFiles(func(path, rel string, info fs.FileInfo) error {
+		cached, err := readCacheFile(path)
+		entries = append(entries, HTTPFileCacheEntry{
+			Path:           rel,
+			Size:           info.Size(),
+			Err:            err,
+			CachedResponse: cached,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list cache dir: %w", err)
+	}
+	return entries, nil
 }
 
-func getCacheControlMaxAge(header string) time.Duration {
+// walkFiles calls fn for each cache file in the cache directory, in lexical
+// order, where rel is the path relative to the cache directory. Temporary
+// files and the lock file are skipped, and so are files that are removed by
+// another process while walking.
+func (h *HTTPFileCache) walkFiles(fn func(path, rel string, info fs.FileInfo) error) error {
+	dir := h.cacheDirFunc()
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, httpFileCacheExt) {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, rel, info)
+	})
+}
+
+// Expired returns true when the entry has expired.
+// Entries that could not be decoded are always treated as expired.
+func (h *HTTPFileCache) Expired(entry HTTPFileCacheEntry) bool {
+	return entry.Err != nil || h.now().After(entry.Expiry())
+}
+
+// Clear removes files from the cache and returns the number of removed files.
+// When expiredOnly is set, then only files where [HTTPFileCache.Expired]
+// returns true are removed. Otherwise everything inside the cache directory
+// is removed, except for the lock file.
+func (h *HTTPFileCache) Clear(expiredOnly bool) (int, error) {
+	dir := h.cacheDirFunc()
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	unlock, err := h.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, err := h.List()
+	if err != nil {
+		return 0, err
+	}
+	if !expiredOnly {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			return 0, fmt.Errorf("read cache dir: %w", err)
+		}
+		for _, dirEntry := range dirEntries {
+			if dirEntry.Name() == httpFileCacheLockFile {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(dir, dirEntry.Name())); err != nil {
+				return 0, fmt.Errorf("remove cache dir: %w", err)
+			}
+		}
+		return len(entries), nil
+	}
+	var removed int
+	for _, entry := range entries {
+		if !h.Expired(entry) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(h.cacheDirFunc(), entry.Path)); err != nil {
+			return removed, fmt.Errorf("remove cache file: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// getCacheControlMaxAge returns the max-age of the Cache-Control header, which
+// is zero when missing, and false if the response must not be cached at all
+// because of no-cache or no-store.
+func getCacheControlMaxAge(header string) (time.Duration, bool) {
 	var maxAge time.Duration
 	for directive := range strings.SplitSeq(header, ",") {
 		key, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
 		switch key {
 		case "no-cache", "no-store":
-			return 0
+			return 0, false
 		case "max-age":
 			seconds, err := strconv.Atoi(value)
 			if err != nil {
@@ -174,13 +463,12 @@ func getCacheControlMaxAge(header string) time.Duration {
 			maxAge = time.Duration(seconds) * time.Second
 		}
 	}
-	return maxAge
+	return maxAge, true
 }
 
-// applyMinCacheDuration raises maxAge to minDuration when minDuration is larger.
-// It is only meant to be called for responses that are already cacheable
-// (maxAge > 0); responses the server marked as no-store/no-cache are skipped by
-// the caller and never extended.
+// applyMinCacheDuration raises maxAge to minDuration when minDuration is larger,
+// including responses without a max-age. Responses the server marked as
+// no-store/no-cache are skipped by the caller and never extended.
 func applyMinCacheDuration(maxAge, minDuration time.Duration) time.Duration {
 	if minDuration > maxAge {
 		return minDuration
@@ -206,6 +494,42 @@ func ParseCacheMinDuration(s string) (time.Duration, error) {
 	return d, nil
 }
 
+// DefaultHTTPCacheMaxSize is the maximum size of the [HTTPFileCache] used
+// when no --bundle-cache-max-size is given.
+const DefaultHTTPCacheMaxSize int64 = 100_000_000
+
+// ParseCacheMaxSize parses a --bundle-cache-max-size value such as "100MB"
+// into a number of bytes. Supported units are B, KB, MB and GB, using powers
+// of 1000 to match how sizes are logged. An empty string returns
+// [DefaultHTTPCacheMaxSize], while "0" means no limit and returns zero.
+func ParseCacheMaxSize(s string) (int64, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	if num == "" {
+		return DefaultHTTPCacheMaxSize, nil
+	}
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"KB", 1_000}, {"MB", 1_000_000}, {"GB", 1_000_000_000}, {"B", 1}} {
+		if before, ok := strings.CutSuffix(num, u.suffix); ok {
+			num, unit = strings.TrimSpace(before), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse bundle cache max size %q: %w", s, err)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("bundle cache max size %q must not be negative", s)
+	}
+	if n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("bundle cache max size %q is too large", s)
+	}
+	return n * unit, nil
+}
+
 // urlToCachePath returns a relative path that can be used as a file path
 // when storing files in a cache, keyed by their URL.
 //
@@ -248,6 +572,9 @@ func urlToCachePath(u *url.URL) string {
 }
 
 type CachedResponse struct {
+	// URL is the (redacted) URL of the cached response, as shown by
+	// "helm schema cache list". It is empty for files cached by older versions.
+	URL      string        `cbor:"url,omitempty"`
 	CachedAt time.Time     `cbor:"cachedAt"`
 	MaxAge   time.Duration `cbor:"maxAge"`
 	ETag     string        `cbor:"etag"`
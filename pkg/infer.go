@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"go.yaml.in/yaml/v3"
+)
+
+// valueInferrer records the distinct scalar values of each key across all
+// values files. They are added as "examples" when using the "inferExamples"
+// config, or as "enum" on keys annotated with "# @schema inferEnum", so that
+// e.g values-dev.yaml and values-prod.yaml document the realistic values.
+// When using the "inferFormats" config, they are also used to infer the
+// "format" or "pattern" of string values.
+type valueInferrer struct {
+	// values holds the distinct values in the order they were found,
+	// by JSON pointer of the schema.
+	values   map[string][]any
+	examples bool
+	formats  bool
+}
+
+func newValueInferrer(config *Config) *valueInferrer {
+	return &valueInferrer{
+		values:   map[string][]any{},
+		examples: config.InferExamples,
+		formats:  config.InferFormats,
+	}
+}
+
+// record walks the values node together with the schema that was generated
+// from it, and records every scalar value. Keys left out of the schema,
+// such as hidden keys or keys inside "skipProperties", are not recorded.
+func (v *valueInferrer) record(ptr Ptr, schema *Schema, node *yaml.Node) {
+	if schema == nil || schema.Kind() != SchemaKindObject {
+		return
+	}
+	node = resolveAlias(node)
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		pairs, err := mappingPairs(node)
+		if err != nil {
+			// Already reported when parsing the values file.
+			return
+		}
+		for _, pair := range pairs {
+			key := pair.Key.Value
+			if prop, ok := schema.Properties[key]; ok {
+				v.record(ptr.Prop("properties", key), prop, pair.Value)
+			} else if schema.MergeProperties {
+				v.record(ptr.Prop("additionalProperties"), schema.AdditionalProperties, pair.Value)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			v.record(ptr.Prop("items"), schema.Items, item)
+		}
+	case yaml.ScalarNode:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			// Already reported when parsing the values file.
+			return
+		}
+		key := ptr.String()
+		if !enumContains(v.values[key], value) {
+			v.values[key] = append(v.values[key], value)
+		}
+	}
+}
+
+// apply adds the recorded values to the merged schema, as "enum" on keys
+// annotated with "inferEnum", or otherwise as "examples". Explicit "examples"
+// annotations are kept as-is, and null values are only added to "enum".
+// Values not allowed by the merged type, such as with the default "override"
+// merge strategy, are left out.
+//
+// Keys annotated with "noInfer", and everything below them, are skipped,
+// except for "inferEnum" which is set explicitly.
+func (v *valueInferrer) apply(ptr Ptr, schema *Schema, noInfer bool) {
+	if schema == nil || schema.Kind() != SchemaKindObject {
+		return
+	}
+	noInfer = noInfer || schema.NoInfer
+
+	if values := matchingValues(schema.Type, v.values[ptr.String()]); len(values) > 0 {
+		switch {
+		case schema.InferEnum:
+			schema.Enum = mergeEnum(schema.Enum, values)
+		case noInfer:
+		case v.examples && schema.Examples == nil:
+			for _, value := range values {
+				if value != nil {
+					schema.Examples = append(schema.Examples, value)
+				}
+			}
+		}
+		if v.formats && !noInfer {
+			applyStringFormat(schema, values)
+		}
+	}
+
+	// Same as "itemEnum", "inferEnum" on an array applies to its items.
+	if schema.InferEnum && schema.Items != nil && schema.Items.Kind() == SchemaKindObject {
+		schema.Items.InferEnum = true
+	}
+
+	v.apply(ptr.Prop("items"), schema.Items, noInfer)
+	v.apply(ptr.Prop("additionalProperties"), schema.AdditionalProperties, noInfer)
+	for name, prop := range schema.Properties {
+		v.apply(ptr.Prop("properties", name), prop, noInfer)
+	}
+}
+
+// matchingValues returns the values allowed by the schema type, as the type
+// from the last values file is used when merging, or the values would
+// otherwise be invalid "examples" or "enum". All values are kept when the
+// schema has no type.
+func matchingValues(schemaType any, values []any) []any {
+	if schemaType == nil {
+		return values
+	}
+	var matching []any
+	for _, value := range values {
+		if valueMatchesAnyType(schemaType, value) {
+			matching = append(matching, value)
+		}
+	}
+	return matching
+}
+
+// applyStringFormat sets "format" or "pattern" on string schemas where all
+// values are of the same kind, as found by [inferStringFormat]. Schemas that
+// already restrict the value, such as using "enum" or "pattern", are skipped.
+func applyStringFormat(schema *Schema, values []any) {
+	if !schema.IsType("string") || schema.Format != "" || schema.Pattern != "" ||
+		schema.Enum != nil || schema.Const != nil || schema.Ref != "" {
+		return
+	}
+	format, ok := inferStringFormat(values)
+	if !ok {
+		return
+	}
+	schema.Format = format.Format
+	schema.Pattern = format.Pattern
+}
@@ -0,0 +1,239 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestBuildJSONSchema_Infer(t *testing.T) {
+	config := &Config{
+		Values: []string{
+			"../testdata/infer/values.yaml",
+			"../testdata/infer/values-prod.yaml",
+			"../testdata/infer/values-staging.yaml",
+		},
+		Draft:  2020,
+		Indent: 4,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), config)
+		require.NoError(t, err)
+		assert.Equal(t, []any{"dev", "prod", "staging"}, schema.Properties["environment"].Enum)
+		assert.Nil(t, schema.Properties["replicas"].Examples)
+		assert.Equal(t, []any{"latest"}, schema.Properties["image"].Properties["tag"].Examples)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		config := *config
+		config.InferExamples = true
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		require.NoError(t, err)
+
+		environment := schema.Properties["environment"]
+		assert.Equal(t, []any{"dev", "prod", "staging"}, environment.Enum)
+		assert.Nil(t, environment.Examples)
+
+		assert.Equal(t, []any{1, 3}, schema.Properties["replicas"].Examples)
+		assert.Equal(t, []any{"debug", "info"}, schema.Properties["logLevel"].Examples)
+		assert.Equal(t, []any{"latest"}, schema.Properties["image"].Properties["tag"].Examples)
+		assert.Equal(t, []any{"Always", "IfNotPresent"}, schema.Properties["image"].Properties["pullPolicy"].Examples)
+		assert.Equal(t, []any{"dev.example.com", "example.com", "www.example.com"}, schema.Properties["ingress"].Properties["hosts"].Items.Examples)
+		assert.NotContains(t, schema.Properties, "secret")
+	})
+
+	t.Run("enabled with null last", func(t *testing.T) {
+		config := *config
+		config.Values = []string{
+			"../testdata/infer/values.yaml",
+			"../testdata/infer/values-staging.yaml",
+			"../testdata/infer/values-prod.yaml",
+		}
+		config.InferExamples = true
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		require.NoError(t, err)
+
+		assert.Equal(t, "null", schema.Properties["logLevel"].Type)
+		assert.Nil(t, schema.Properties["logLevel"].Examples)
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		config := *config
+		config.InferFormats = true
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		require.NoError(t, err)
+
+		assert.Equal(t, "uri", schema.Properties["url"].Format)
+		assert.Equal(t, cronPattern, schema.Properties["schedule"].Pattern)
+		assert.Equal(t, durationPattern, schema.Properties["timeout"].Pattern)
+		assert.Equal(t, quantityPattern, schema.Properties["memory"].Pattern)
+		assert.Empty(t, schema.Properties["cpu"].Pattern, "ambiguous")
+		assert.Empty(t, schema.Properties["healthUrl"].Format, "noInfer")
+		assert.Empty(t, schema.Properties["logLevel"].Pattern)
+		assert.Empty(t, schema.Properties["logLevel"].Format)
+		assert.Nil(t, schema.Properties["url"].Examples)
+	})
+}
+
+func TestValueInferrer(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        []string
+		inferExamples bool
+		inferFormats  bool
+		want          *Schema
+	}{
+		{
+			name:          "examples",
+			values:        []string{"foo: a", "foo: b", "foo: a"},
+			inferExamples: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "string", Examples: []any{"a", "b"}},
+			}},
+		},
+		{
+			name:   "examples are opt-in",
+			values: []string{"foo: a", "foo: b"},
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "string"},
+			}},
+		},
+		{
+			name:          "null is left out of examples",
+			values:        []string{"foo: null", "foo: 1"},
+			inferExamples: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "integer", Examples: []any{1}},
+			}},
+		},
+		{
+			name:   "inferEnum",
+			values: []string{"foo: null # @schema inferEnum", "foo: 1"},
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "integer", Enum: []any{1}, InferEnum: true},
+			}},
+		},
+		{
+			name:   "inferEnum with nullable type",
+			values: []string{"foo: null", "foo: 1 # @schema inferEnum; type: [integer, null]"},
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: []any{"integer", "null"}, Enum: []any{nil, 1}, InferEnum: true},
+			}},
+		},
+		{
+			name:          "values not matching the merged type are left out",
+			values:        []string{"foo: a", "foo: 1", "foo: b"},
+			inferExamples: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "string", Examples: []any{"a", "b"}},
+			}},
+		},
+		{
+			name:   "inferEnum merges with enum annotation",
+			values: []string{"foo: a # @schema inferEnum; enum: [a, z]", "foo: b"},
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "string", Enum: []any{"a", "z", "b"}, InferEnum: true},
+			}},
+		},
+		{
+			name:   "inferEnum on array",
+			values: []string{"foo: [a, b] # @schema inferEnum", "foo: [c]"},
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "array", InferEnum: true, Items: &Schema{Type: "string", Enum: []any{"a", "b", "c"}, InferEnum: true}},
+			}},
+		},
+		{
+			name:          "mergeProperties",
+			values:        []string{"foo: # @schema mergeProperties\n  a: x\n  b: y\n"},
+			inferExamples: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "object", MergeProperties: true, AdditionalProperties: &Schema{Type: "string", Examples: []any{"x", "y"}}},
+			}},
+		},
+		{
+			name:          "aliases",
+			values:        []string{"foo: &foo a\nbar: *foo\n"},
+			inferExamples: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "string", Examples: []any{"a"}},
+				"bar": {Type: "string", Examples: []any{"a"}},
+			}},
+		},
+		{
+			name:          "skipProperties",
+			values:        []string{"foo: # @schema skipProperties\n  a: x\n"},
+			inferExamples: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "object", SkipProperties: true},
+			}},
+		},
+		{
+			name:          "noInfer",
+			values:        []string{"foo: # @schema noInfer\n  a: x\n  b: [y] # @schema inferEnum\n", "foo:\n  a: https://example.com\n"},
+			inferExamples: true,
+			inferFormats:  true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"foo": {Type: "object", NoInfer: true, Properties: map[string]*Schema{
+					"a": {Type: "string"},
+					"b": {Type: "array", InferEnum: true, Items: &Schema{Type: "string", Enum: []any{"y"}, InferEnum: true}},
+				}},
+			}},
+		},
+		{
+			name:         "formats",
+			values:       []string{"url: https://example.com\ntimeout: 30s\nname: foo\n", "url: http://localhost:8080\ntimeout: 1m30s\n"},
+			inferFormats: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"url":     {Type: "string", Format: "uri"},
+				"timeout": {Type: "string", Pattern: durationPattern},
+				"name":    {Type: "string"},
+			}},
+		},
+		{
+			name:   "formats are opt-in",
+			values: []string{"url: https://example.com"},
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"url": {Type: "string"},
+			}},
+		},
+		{
+			name:         "formats need all values to agree",
+			values:       []string{"url: https://example.com", "url: not a url"},
+			inferFormats: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"url": {Type: "string"},
+			}},
+		},
+		{
+			name:         "formats skip annotated keys",
+			values:       []string{"a: https://example.com # @schema pattern: ^https\nb: https://example.com # @schema enum: [https://example.com]\nc: 1.2.3.4 # @schema type: [string, null]\n"},
+			inferFormats: true,
+			want: &Schema{Type: "object", Properties: map[string]*Schema{
+				"a": {Type: "string", Pattern: "^https"},
+				"b": {Type: "string", Enum: []any{"https://example.com"}},
+				"c": {Type: []any{"string", "null"}, Format: "ipv4"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inferrer := newValueInferrer(&Config{InferExamples: tt.inferExamples, InferFormats: tt.inferFormats})
+			merged := &Schema{}
+			for _, values := range tt.values {
+				var node yaml.Node
+				require.NoError(t, yaml.Unmarshal([]byte(values), &node))
+				root := node.Content[0]
+				schema, err := parseNode(nil, nil, root, false)
+				require.NoError(t, err)
+				inferrer.record(nil, schema, root)
+				merged = mergeSchemas(merged, schema)
+			}
+			inferrer.apply(nil, merged, false)
+			assert.Equal(t, tt.want, merged)
+		})
+	}
+}
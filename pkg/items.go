@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Modes for inferring the schema of array items from the items in the values
+// file, as set by the "itemsMode" config or the "itemsMode" annotation.
+const (
+	// ItemsModeMerge merges all items into a single "items" schema, and is
+	// the default.
+	ItemsModeMerge = "merge"
+	// ItemsModeTuple uses "prefixItems" with a schema for each item by
+	// position, which requires draft 2020.
+	ItemsModeTuple = "tuple"
+	// ItemsModeUnion uses "items" with an "anyOf" of the distinct item schemas.
+	ItemsModeUnion = "union"
+)
+
+// ItemsModes lists all supported items modes.
+var ItemsModes = []string{ItemsModeMerge, ItemsModeTuple, ItemsModeUnion}
+
+// validateItemsMode returns an error if mode is not one of [ItemsModes].
+// An empty mode is the same as [ItemsModeMerge].
+func validateItemsMode(mode string) error {
+	if mode == "" || slices.Contains(ItemsModes, mode) {
+		return nil
+	}
+	return fmt.Errorf("invalid itemsMode %q, must be one of: %s", mode, strings.Join(ItemsModes, ", "))
+}
+
+// applyItems sets the "items" or "prefixItems" of the array schema from the
+// schemas of its items, where hidden items are nil, using the items mode.
+func applyItems(schema *Schema, items []*Schema, mode string) {
+	switch mode {
+	case ItemsModeTuple:
+		if len(items) == 0 {
+			return
+		}
+		schema.PrefixItems = make([]*Schema, len(items))
+		for i, item := range items {
+			if item == nil {
+				// Hidden items still take up their position
+				item = SchemaTrue()
+			}
+			schema.PrefixItems[i] = item
+		}
+
+	case ItemsModeUnion:
+		var variants []*Schema
+		for _, item := range items {
+			if item != nil && !slices.ContainsFunc(variants, func(variant *Schema) bool {
+				return reflect.DeepEqual(variant, item)
+			}) {
+				variants = append(variants, item)
+			}
+		}
+		switch len(variants) {
+		case 0:
+		case 1:
+			schema.Items = variants[0]
+		default:
+			schema.Items = &Schema{AnyOf: variants}
+		}
+
+	default:
+		merged := &Schema{}
+		hasItems := false
+		for _, item := range items {
+			if item != nil {
+				merged = mergeSchemas(merged, item)
+				hasItems = true
+			}
+		}
+		if hasItems {
+			schema.Items = merged
+		}
+	}
+}
+
+// mergeUnionItems merges the "items" of two arrays using [ItemsModeUnion],
+// by combining the distinct item schemas of both.
+func mergeUnionItems(dest, src *Schema) *Schema {
+	var items []*Schema
+	for _, schema := range []*Schema{dest, src} {
+		switch {
+		case schema == nil:
+		case len(schema.AnyOf) > 0 && reflect.DeepEqual(schema, &Schema{AnyOf: schema.AnyOf}):
+			items = append(items, schema.AnyOf...)
+		default:
+			items = append(items, schema)
+		}
+	}
+	var merged Schema
+	applyItems(&merged, items, ItemsModeUnion)
+	return merged.Items
+}
+
+// mergePrefixItems merges the "prefixItems" of two arrays using
+// [ItemsModeTuple] position by position, where hidden items in one of them
+// don't replace the item of the other.
+func mergePrefixItems(dest, src []*Schema) []*Schema {
+	for i, item := range src {
+		switch {
+		case i >= len(dest):
+			dest = append(dest, item)
+		case dest[i].Kind() == SchemaKindTrue:
+			dest[i] = item
+		case item.Kind() != SchemaKindTrue:
+			dest[i] = mergeSchemas(dest[i], item)
+		}
+	}
+	return dest
+}
+
+// clearItemsModes removes the items modes that [mergeSchemas] uses to merge
+// the items of the schemas from multiple values files.
+func clearItemsModes(schema *Schema) {
+	schema.ItemsMode = ""
+	for _, subSchema := range schema.Subschemas() {
+		clearItemsModes(subSchema)
+	}
+}
@@ -0,0 +1,230 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateItemsMode(t *testing.T) {
+	for _, mode := range append([]string{""}, ItemsModes...) {
+		assert.NoError(t, validateItemsMode(mode), mode)
+	}
+	assert.EqualError(t, validateItemsMode("anyOf"), `invalid itemsMode "anyOf", must be one of: merge, tuple, union`)
+}
+
+func TestBuildJSONSchema_ItemsMode(t *testing.T) {
+	sidecar := &Schema{Type: "object", Properties: map[string]*Schema{
+		"name":  {Type: "string"},
+		"image": {Type: "string"},
+	}}
+	port := &Schema{Type: "object", Properties: map[string]*Schema{
+		"port": {Type: "integer"},
+	}}
+	command := []*Schema{{Type: "string"}, {Type: "string"}, {Type: "string"}}
+
+	tests := []struct {
+		name    string
+		mode    string
+		draft   int
+		want    map[string]*Schema
+		wantErr string
+	}{
+		{
+			name: "merge",
+			mode: ItemsModeMerge,
+			want: map[string]*Schema{
+				"args": {Type: "array", Items: &Schema{Type: "boolean"}},
+				"sidecars": {Type: "array", Items: &Schema{Type: "object", Properties: map[string]*Schema{
+					"name":  {Type: "string"},
+					"image": {Type: "string"},
+					"port":  {Type: "integer"},
+				}}},
+				"ports":   {Type: "array", Items: &Schema{Type: "integer"}},
+				"command": {Type: "array", PrefixItems: command},
+				"hosts":   {Type: "array", Items: &Schema{Type: "integer"}},
+			},
+		},
+		{
+			name: "tuple",
+			mode: ItemsModeTuple,
+			want: map[string]*Schema{
+				"args":     {Type: "array", PrefixItems: []*Schema{{Type: "string"}, {Type: "integer"}, {Type: "boolean"}}},
+				"sidecars": {Type: "array", PrefixItems: []*Schema{sidecar, sidecar, port}},
+				"ports":    {Type: "array", Items: &Schema{Type: "integer"}},
+				"command":  {Type: "array", PrefixItems: command},
+				"hosts":    {Type: "array", PrefixItems: []*Schema{{Type: "string"}, SchemaTrue(), {Type: "integer"}}},
+			},
+		},
+		{
+			name: "union",
+			mode: ItemsModeUnion,
+			want: map[string]*Schema{
+				"args":     {Type: "array", Items: &Schema{AnyOf: []*Schema{{Type: "string"}, {Type: "integer"}, {Type: "boolean"}}}},
+				"sidecars": {Type: "array", Items: &Schema{AnyOf: []*Schema{sidecar, port}}},
+				"ports":    {Type: "array", Items: &Schema{Type: "integer"}},
+				"command":  {Type: "array", PrefixItems: command},
+				"hosts":    {Type: "array", Items: &Schema{AnyOf: []*Schema{{Type: "string"}, {Type: "integer"}}}},
+			},
+		},
+		{
+			name:    "tuple requires draft 2020",
+			mode:    ItemsModeTuple,
+			draft:   2019,
+			wantErr: `itemsMode "tuple" requires draft 2020, as prefixItems is not supported by draft 2019`,
+		},
+		{
+			name:    "tuple annotation requires draft 2020",
+			mode:    ItemsModeMerge,
+			draft:   7,
+			wantErr: `parse schema: /command: itemsMode "tuple" requires draft 2020, as prefixItems is not supported by draft 7`,
+		},
+		{
+			name:    "invalid mode",
+			mode:    "anyOf",
+			wantErr: `invalid itemsMode "anyOf"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Values:    []string{"../testdata/items/values.yaml"},
+				Draft:     2020,
+				Indent:    4,
+				ItemsMode: tt.mode,
+			}
+			if tt.draft != 0 {
+				config.Draft = tt.draft
+			}
+			schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), config)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for name, want := range tt.want {
+				assert.Equal(t, want, schema.Properties[name], name)
+			}
+		})
+	}
+}
+
+func TestBuildJSONSchema_ItemsModeMultipleFiles(t *testing.T) {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{"k": {Type: "integer"}}}
+
+	tests := []struct {
+		name          string
+		mode          string
+		mergeStrategy string
+		want          *Schema
+	}{
+		{
+			name: "union",
+			mode: ItemsModeUnion,
+			want: &Schema{Type: "array", Items: &Schema{AnyOf: []*Schema{
+				{Type: "string"}, {Type: "integer"}, {Type: "boolean"}, object,
+			}}},
+		},
+		{
+			name:          "union with union merge strategy",
+			mode:          ItemsModeUnion,
+			mergeStrategy: MergeStrategyUnion,
+			want: &Schema{Type: "array", Items: &Schema{AnyOf: []*Schema{
+				{Type: "string"}, {Type: "integer"}, {Type: "boolean"}, object,
+			}}},
+		},
+		{
+			name: "tuple",
+			mode: ItemsModeTuple,
+			want: &Schema{Type: "array", PrefixItems: []*Schema{
+				{Type: "boolean"}, object, {Type: "string"},
+			}},
+		},
+		{
+			name:          "tuple with union merge strategy",
+			mode:          ItemsModeTuple,
+			mergeStrategy: MergeStrategyUnion,
+			want: &Schema{Type: "array", PrefixItems: []*Schema{
+				{Type: []any{"boolean", "string"}},
+				{Type: []any{"integer", "object"}, Properties: object.Properties},
+				{Type: "string"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			valuesFiles := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+			require.NoError(t, os.WriteFile(valuesFiles[0], []byte("args: [\"a\", 1]\n"), 0600))
+			require.NoError(t, os.WriteFile(valuesFiles[1], []byte("args: [true, {k: 1}, \"c\"]\n"), 0600))
+
+			schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &Config{
+				Values:        valuesFiles,
+				Draft:         2020,
+				Indent:        4,
+				ItemsMode:     tt.mode,
+				MergeStrategy: tt.mergeStrategy,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schema.Properties["args"])
+
+			if tt.mode == ItemsModeUnion || tt.mergeStrategy == MergeStrategyUnion {
+				compiled, err := compileValuesSchema(schema, "")
+				require.NoError(t, err)
+				assert.NoError(t, compiled.Validate(map[string]any{"args": []any{"a", 1}}))
+				assert.NoError(t, compiled.Validate(map[string]any{"args": []any{true, map[string]any{"k": 1}, "c"}}))
+			}
+		})
+	}
+}
+
+func TestApplyItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  string
+		items []*Schema
+		want  *Schema
+	}{
+		{name: "merge no items", mode: ItemsModeMerge, want: &Schema{}},
+		{name: "merge only hidden items", mode: ItemsModeMerge, items: []*Schema{nil}, want: &Schema{}},
+		{name: "empty mode merges", mode: "", items: []*Schema{{Type: "string"}, {MinLength: uint64Ptr(1)}}, want: &Schema{Items: &Schema{Type: "string", MinLength: uint64Ptr(1)}}},
+		{name: "tuple no items", mode: ItemsModeTuple, want: &Schema{}},
+		{name: "tuple", mode: ItemsModeTuple, items: []*Schema{{Type: "string"}, nil}, want: &Schema{PrefixItems: []*Schema{{Type: "string"}, SchemaTrue()}}},
+		{name: "union no items", mode: ItemsModeUnion, items: []*Schema{nil}, want: &Schema{}},
+		{name: "union single variant", mode: ItemsModeUnion, items: []*Schema{{Type: "string"}, {Type: "string"}}, want: &Schema{Items: &Schema{Type: "string"}}},
+		{name: "union deduplicates", mode: ItemsModeUnion, items: []*Schema{{Type: "string"}, {Type: "integer"}, {Type: "string"}}, want: &Schema{Items: &Schema{AnyOf: []*Schema{{Type: "string"}, {Type: "integer"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &Schema{}
+			applyItems(schema, tt.items, tt.mode)
+			assert.Equal(t, tt.want, schema)
+		})
+	}
+}
+
+func TestMergePrefixItems(t *testing.T) {
+	tests := []struct {
+		name string
+		dest []*Schema
+		src  []*Schema
+		want []*Schema
+	}{
+		{name: "empty", want: nil},
+		{name: "longer src", dest: []*Schema{{Type: "string"}}, src: []*Schema{{MinLength: uint64Ptr(1)}, {Type: "integer"}}, want: []*Schema{{Type: "string", MinLength: uint64Ptr(1)}, {Type: "integer"}}},
+		{name: "hidden in src", dest: []*Schema{{Type: "string"}}, src: []*Schema{SchemaTrue()}, want: []*Schema{{Type: "string"}}},
+		{name: "hidden in dest", dest: []*Schema{SchemaTrue()}, src: []*Schema{{Type: "string"}}, want: []*Schema{{Type: "string"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mergePrefixItems(tt.dest, tt.src))
+		})
+	}
+}
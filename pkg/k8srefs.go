@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"strings"
+)
+
+// k8sRef is a "$ref" that is added to well-known keys when using the
+// "autoK8sRefs" config.
+type k8sRef struct {
+	Ref string
+	// Type is the type the key must have in the values file for the "$ref"
+	// to be added, or empty to allow any type.
+	Type string
+}
+
+// defaultK8sRefs maps the names of keys commonly found in Helm charts to
+// their Kubernetes definitions. They use the "$k8s" alias, so the definitions
+// match the k8sSchemaVersion config.
+var defaultK8sRefs = map[string]k8sRef{
+	"affinity":           {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.Affinity", Type: "object"},
+	"env":                {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.Container/properties/env", Type: "array"},
+	"nodeSelector":       {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.PodSpec/properties/nodeSelector", Type: "object"},
+	"podSecurityContext": {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.PodSecurityContext", Type: "object"},
+	"resources":          {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.ResourceRequirements", Type: "object"},
+	"securityContext":    {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.SecurityContext", Type: "object"},
+	"tolerations":        {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.PodSpec/properties/tolerations", Type: "array"},
+	"volumeMounts":       {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.Container/properties/volumeMounts", Type: "array"},
+	"volumes":            {Ref: "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.PodSpec/properties/volumes", Type: "array"},
+}
+
+// k8sRefTable holds the "$ref" to add by key name or key path, as used by
+// the "autoK8sRefs" config.
+type k8sRefTable map[string]k8sRef
+
+// newK8sRefTable returns the [defaultK8sRefs] with the overrides from the
+// "k8sRefs" config applied, where an empty "$ref" removes a built-in entry.
+func newK8sRefTable(overrides map[string]string) k8sRefTable {
+	table := make(k8sRefTable, len(defaultK8sRefs)+len(overrides))
+	for key, ref := range defaultK8sRefs {
+		table[key] = ref
+	}
+	for key, ref := range overrides {
+		if ref == "" {
+			delete(table, key)
+			continue
+		}
+		table[key] = k8sRef{Ref: ref}
+	}
+	return table
+}
+
+// apply adds "$ref" to every property in the schema generated from a values
+// file that matches the table, either by a dot-separated key path from the
+// root, such as "controller.resources", or else by key name at any depth.
+// Keys that already have a "$ref" are left as-is.
+func (table k8sRefTable) apply(schema *Schema) {
+	if len(table) == 0 {
+		return
+	}
+	table.applyRec(schema, []string{})
+}
+
+// applyRec walks the properties of the schema. The path is nil inside array
+// items and "additionalProperties", where only key names are matched.
+func (table k8sRefTable) applyRec(schema *Schema, path []string) {
+	if schema == nil || schema.Kind() != SchemaKindObject {
+		return
+	}
+	for name, prop := range schema.Properties {
+		var propPath []string
+		if path != nil {
+			propPath = append(path[:len(path):len(path)], name)
+		}
+		ref, ok := table[strings.Join(propPath, ".")]
+		if !ok || propPath == nil {
+			ref, ok = table[name]
+		}
+		if ok && prop.Kind() == SchemaKindObject && prop.Ref == "" && (ref.Type == "" || prop.IsType(ref.Type)) {
+			prop.Ref = ref.Ref
+			// The definition describes everything below this key.
+			continue
+		}
+		table.applyRec(prop, propPath)
+	}
+	table.applyRec(schema.Items, nil)
+	table.applyRec(schema.AdditionalProperties, nil)
+}
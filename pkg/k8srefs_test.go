@@ -0,0 +1,182 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildJSONSchema_AutoK8sRefs(t *testing.T) {
+	k8sURL := "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/v1.33.1/_definitions.json"
+	config := &Config{
+		Values:           []string{"../testdata/k8srefs/values.yaml"},
+		Draft:            2020,
+		Indent:           4,
+		K8sSchemaURL:     DefaultConfig.K8sSchemaURL,
+		K8sSchemaVersion: "v1.33.1",
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), config)
+		require.NoError(t, err)
+		assert.Empty(t, schema.Properties["resources"].Ref)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		config := *config
+		config.AutoK8sRefs = true
+		config.K8sRefs = map[string]string{
+			"volumes":              "",
+			"controller.resources": "$k8s/_definitions.json#/definitions/io.k8s.api.core.v1.VolumeResourceRequirements",
+		}
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		require.NoError(t, err)
+
+		refs := map[string]string{}
+		for name, prop := range schema.Properties {
+			refs[name] = prop.Ref
+		}
+		assert.Equal(t, map[string]string{
+			"resources":          k8sURL + "#/definitions/io.k8s.api.core.v1.ResourceRequirements",
+			"nodeSelector":       k8sURL + "#/definitions/io.k8s.api.core.v1.PodSpec/properties/nodeSelector",
+			"tolerations":        k8sURL + "#/definitions/io.k8s.api.core.v1.PodSpec/properties/tolerations",
+			"affinity":           k8sURL + "#/definitions/io.k8s.api.core.v1.Affinity",
+			"podSecurityContext": k8sURL + "#/definitions/io.k8s.api.core.v1.PodSecurityContext",
+			"securityContext":    k8sURL + "#/definitions/io.k8s.api.core.v1.SecurityContext",
+			"env":                "",
+			"volumes":            "",
+			"volumeMounts":       k8sURL + "#/definitions/io.k8s.api.core.v1.Container/properties/volumeMounts",
+			"controller":         "",
+			"sidecars":           "",
+		}, refs)
+
+		controller := schema.Properties["controller"]
+		assert.Equal(t, k8sURL+"#/definitions/io.k8s.api.core.v1.VolumeResourceRequirements", controller.Properties["resources"].Ref)
+		assert.Equal(t, k8sURL+"#/definitions/io.k8s.api.core.v1.ResourceRequirements", controller.Properties["extraResources"].Ref)
+		assert.Equal(t, k8sURL+"#/definitions/io.k8s.api.core.v1.ResourceRequirements", schema.Properties["sidecars"].Items.Properties["resources"].Ref)
+	})
+
+	t.Run("requires k8sSchemaVersion", func(t *testing.T) {
+		config := *config
+		config.AutoK8sRefs = true
+		config.K8sSchemaVersion = ""
+		_, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		assert.ErrorContains(t, err, "must set k8sSchemaVersion config")
+	})
+}
+
+func TestK8sRefTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		schema    *Schema
+		want      map[string]string
+	}{
+		{
+			name: "key name at any depth",
+			schema: &Schema{Properties: map[string]*Schema{
+				"resources": {Type: "object"},
+				"app": {Type: "object", Properties: map[string]*Schema{
+					"resources": {Type: "object"},
+				}},
+			}},
+			want: map[string]string{
+				"/properties/resources":                defaultK8sRefs["resources"].Ref,
+				"/properties/app/properties/resources": defaultK8sRefs["resources"].Ref,
+			},
+		},
+		{
+			name: "type mismatch",
+			schema: &Schema{Properties: map[string]*Schema{
+				"resources":   {Type: "string"},
+				"tolerations": {Type: "object"},
+				"affinity":    {Type: []any{"object", "null"}},
+			}},
+			want: map[string]string{
+				"/properties/affinity": defaultK8sRefs["affinity"].Ref,
+			},
+		},
+		{
+			name: "keeps existing ref",
+			schema: &Schema{Properties: map[string]*Schema{
+				"resources": {Type: "object", Ref: "#/$defs/resources"},
+			}},
+			want: map[string]string{
+				"/properties/resources": "#/$defs/resources",
+			},
+		},
+		{
+			name: "does not recurse into referenced keys",
+			schema: &Schema{Properties: map[string]*Schema{
+				"securityContext": {Type: "object", Properties: map[string]*Schema{
+					"resources": {Type: "object"},
+				}},
+			}},
+			want: map[string]string{
+				"/properties/securityContext": defaultK8sRefs["securityContext"].Ref,
+			},
+		},
+		{
+			name:      "overrides",
+			overrides: map[string]string{"resources": "", "config": "config.schema.json", "app.resources": "resources.schema.json"},
+			schema: &Schema{Properties: map[string]*Schema{
+				"resources": {Type: "object"},
+				"config":    {Type: "string"},
+				"app": {Type: "object", Properties: map[string]*Schema{
+					"resources": {Type: "object"},
+				}},
+				"list": {Type: "array", Items: &Schema{Type: "object", Properties: map[string]*Schema{
+					"resources": {Type: "object"},
+				}}},
+			}},
+			want: map[string]string{
+				"/properties/config":                   "config.schema.json",
+				"/properties/app/properties/resources": "resources.schema.json",
+			},
+		},
+		{
+			name: "items and additionalProperties",
+			schema: &Schema{Properties: map[string]*Schema{
+				"list": {Type: "array", Items: &Schema{Type: "object", Properties: map[string]*Schema{
+					"env": {Type: "array"},
+				}}},
+				"map": {Type: "object", AdditionalProperties: &Schema{Type: "object", Properties: map[string]*Schema{
+					"env": {Type: "array"},
+				}}},
+				"bool": {Type: "array", Items: SchemaTrue()},
+			}},
+			want: map[string]string{
+				"/properties/list/items/properties/env":               defaultK8sRefs["env"].Ref,
+				"/properties/map/additionalProperties/properties/env": defaultK8sRefs["env"].Ref,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newK8sRefTable(tt.overrides).apply(tt.schema)
+			got := map[string]string{}
+			for ptr, sub := range tt.schema.Subschemas() {
+				collectRefs(ptr, sub, got)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func collectRefs(ptr Ptr, schema *Schema, refs map[string]string) {
+	if schema.Ref != "" {
+		refs[ptr.String()] = schema.Ref
+	}
+	for path, sub := range schema.Subschemas() {
+		collectRefs(ptr.Add(path), sub, refs)
+	}
+}
+
+func TestK8sRefTable_Empty(t *testing.T) {
+	var table k8sRefTable
+	schema := &Schema{Properties: map[string]*Schema{"resources": {Type: "object"}}}
+	table.apply(schema)
+	assert.Empty(t, schema.Properties["resources"].Ref)
+}
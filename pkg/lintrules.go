@@ -0,0 +1,620 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+	"go.yaml.in/yaml/v3"
+)
+
+// IDs of the lint rules checked on the "# @schema" annotations in the input
+// values files, on the config file, and on the values against the schema.
+const (
+	// LintRuleDefaultMismatch reports a "default" annotation that does not
+	// match the other annotations on the same key, such as "type" or "enum".
+	LintRuleDefaultMismatch = "default-mismatch"
+	// LintRuleValueMismatch reports a value in the values file that does not
+	// match its own annotations.
+	LintRuleValueMismatch = "value-mismatch"
+	// LintRuleMinGreaterThanMax reports e.g "minimum" being greater than "maximum",
+	// or "minLength" being greater than "maxLength".
+	LintRuleMinGreaterThanMax = "min-greater-than-max"
+	// LintRuleInvalidPattern reports a "pattern" that is not a valid ECMA-262
+	// regular expression, which is the regex dialect used by JSON Schema.
+	LintRuleInvalidPattern = "invalid-pattern"
+	// LintRuleRequiredHidden reports "required" on a key that is also "hidden",
+	// which makes the key required while leaving it out of the schema.
+	LintRuleRequiredHidden = "required-hidden"
+	// LintRuleSkipPropertiesNonObject reports "skipProperties" on a key that
+	// is not an object, where it has no effect.
+	LintRuleSkipPropertiesNonObject = "skip-properties-non-object"
+	// LintRuleItemPropertiesNonArray reports "itemProperties" on a key that is
+	// not an array, where it has no effect.
+	LintRuleItemPropertiesNonArray = "item-properties-non-array"
+	// LintRuleUnknownConfigField reports a field in the config file
+	// (.schema.yaml) that is not a known config field.
+	LintRuleUnknownConfigField = "unknown-config-field"
+	// LintRuleUncoveredKey reports a key in the values files that is not
+	// covered by the schema, and is rejected by "additionalProperties: false"
+	// or "unevaluatedProperties: false", such as when the schema comes from a
+	// $ref and "--no-additional-properties" is set.
+	LintRuleUncoveredKey = "uncovered-key"
+)
+
+// LintRules lists the IDs of all lint rules that can be configured in the
+// "lint.rules" config.
+var LintRules = []string{
+	LintRuleDefaultMismatch,
+	LintRuleValueMismatch,
+	LintRuleMinGreaterThanMax,
+	LintRuleInvalidPattern,
+	LintRuleRequiredHidden,
+	LintRuleSkipPropertiesNonObject,
+	LintRuleItemPropertiesNonArray,
+	LintRuleUnknownConfigField,
+	LintRuleUncoveredKey,
+}
+
+// LintSeverity is the severity of a lint rule, as configured in the
+// "lint.rules" config.
+type LintSeverity string
+
+const (
+	// LintSeverityError makes lint fail when the rule reports an issue.
+	LintSeverityError LintSeverity = "error"
+	// LintSeverityWarning makes lint fail when the rule reports an issue,
+	// but only when using "--strict".
+	LintSeverityWarning LintSeverity = "warning"
+	// LintSeverityInfo reports issues without ever making lint fail.
+	LintSeverityInfo LintSeverity = "info"
+	// LintSeverityOff disables the rule.
+	LintSeverityOff LintSeverity = "off"
+)
+
+// defaultLintSeverity is the severity of all rules not configured in the
+// "lint.rules" config.
+const defaultLintSeverity = LintSeverityWarning
+
+// LintConfig is the "lint" section of the config file.
+type LintConfig struct {
+	// Rules maps rule IDs to their severity.
+	Rules map[string]LintSeverity `yaml:"rules" koanf:"rules"`
+}
+
+// severities returns the severity of every rule, after validating the config.
+func (c LintConfig) severities() (map[string]LintSeverity, error) {
+	severities := make(map[string]LintSeverity, len(LintRules))
+	for _, rule := range LintRules {
+		severities[rule] = defaultLintSeverity
+	}
+	for rule, severity := range iterMapOrdered(c.Rules) {
+		if !slices.Contains(LintRules, rule) {
+			return nil, fmt.Errorf("lint.rules: unknown rule %q, must be one of: %s", rule, strings.Join(LintRules, ", "))
+		}
+		switch severity {
+		case LintSeverityError, LintSeverityWarning, LintSeverityInfo, LintSeverityOff:
+			severities[rule] = severity
+		default:
+			return nil, fmt.Errorf("lint.rules.%s: invalid severity %q, must be one of: error, warning, info, off", rule, severity)
+		}
+	}
+	return severities, nil
+}
+
+// LintIssue is a single problem reported by a lint rule, or a diagnostic
+// converted from a schema generation error using [diagnosticsFromError].
+type LintIssue struct {
+	Rule     string
+	Severity LintSeverity
+	// File is the path of the file with the problem, or empty when unknown.
+	File string
+	// Line and Column are 1-based, or zero when unknown.
+	Line   int
+	Column int
+	// Ptr is the JSON pointer to the problem, or empty when unknown.
+	Ptr     Ptr
+	Message string
+}
+
+// String implements [fmt.Stringer].
+//
+// Unknown parts of the location are left out, so an issue with all of them
+// set is formatted as "file:line:column: /ptr: message (rule)".
+func (issue LintIssue) String() string {
+	var sb strings.Builder
+	if issue.File != "" {
+		sb.WriteString(issue.File)
+		if issue.Line > 0 {
+			fmt.Fprintf(&sb, ":%d", issue.Line)
+			if issue.Column > 0 {
+				fmt.Fprintf(&sb, ":%d", issue.Column)
+			}
+		}
+		sb.WriteString(": ")
+	}
+	if len(issue.Ptr) > 0 {
+		fmt.Fprintf(&sb, "%s: ", issue.Ptr)
+	}
+	fmt.Fprintf(&sb, "%s (%s)", issue.Message, issue.Rule)
+	return sb.String()
+}
+
+// countLintIssues returns the number of issues for each severity.
+func countLintIssues(issues []LintIssue) map[LintSeverity]int {
+	counts := map[LintSeverity]int{}
+	for _, issue := range issues {
+		counts[issue.Severity]++
+	}
+	return counts
+}
+
+// lintValuesFiles runs the semantic lint rules on all the given values files,
+// and sets the severity of each issue using the given config. Issues from
+// disabled or suppressed rules are left out, including the otherIssues that
+// are reported on the values files by rules outside of this file, such as
+// [LintRuleUncoveredKey].
+//
+// Values read from stdin ("-") are skipped, as stdin has already been consumed
+// when generating the schema.
+func lintValuesFiles(valuesFiles []string, useHelmDocs bool, config LintConfig, otherIssues []LintIssue) ([]LintIssue, error) {
+	severities, err := config.severities()
+	if err != nil {
+		return nil, err
+	}
+	var issues []LintIssue
+	for _, filePath := range valuesFiles {
+		if filePath == "-" {
+			continue
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("read --values=%q: %w", filePath, err)
+		}
+		fileOtherIssues := slices.DeleteFunc(slices.Clone(otherIssues), func(issue LintIssue) bool {
+			return issue.File != filePath
+		})
+		fileIssues, err := lintValues(filePath, content, useHelmDocs, fileOtherIssues...)
+		if err != nil {
+			return nil, fmt.Errorf("lint --values=%q: %w", filePath, err)
+		}
+		for _, issue := range fileIssues {
+			issue.Severity = severities[issue.Rule]
+			if issue.Severity != LintSeverityOff {
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues, nil
+}
+
+// lintValues runs the semantic lint rules on a single values file. The
+// otherIssues are returned alongside them, unless they are suppressed.
+func lintValues(filePath string, content []byte, useHelmDocs bool, otherIssues ...LintIssue) ([]LintIssue, error) {
+	// Change Window's CRLF to LF line endings, same as when generating the schema
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+
+	docs, err := decodeYAMLDocuments(content)
+	if err != nil {
+		return nil, err
+	}
+
+	l := valuesLinter{file: filePath, useHelmDocs: useHelmDocs}
+	for _, doc := range docs {
+		// Comments at the top of the file, separated from the first key by an
+		// empty line, apply to the whole file.
+		l.addSuppressions(nil, strings.Split(doc.HeadComment, "\n"))
+		rootNode := doc.Content[0]
+		for i := 0; i+1 < len(rootNode.Content); i += 2 {
+			keyNode := rootNode.Content[i]
+			l.lintNode(NewPtr(keyNode.Value), keyNode, rootNode.Content[i+1], true)
+		}
+	}
+
+	return slices.DeleteFunc(append(l.issues, otherIssues...), func(issue LintIssue) bool {
+		return slices.ContainsFunc(l.suppressions, func(suppression lintSuppression) bool {
+			return suppression.suppresses(issue)
+		})
+	}), nil
+}
+
+type valuesLinter struct {
+	file         string
+	useHelmDocs  bool
+	issues       []LintIssue
+	suppressions []lintSuppression
+}
+
+// lintSuppression disables rules on a key and all of its child keys,
+// using a "# @schema-lint-disable rule-id" comment.
+type lintSuppression struct {
+	ptr Ptr
+	// rules that are disabled, where an empty slice means all rules
+	rules []string
+}
+
+func (s lintSuppression) suppresses(issue LintIssue) bool {
+	return issue.Ptr.HasPrefix(s.ptr) && (len(s.rules) == 0 || slices.Contains(s.rules, issue.Rule))
+}
+
+func (l *valuesLinter) addSuppressions(ptr Ptr, commentLines []string) {
+	for _, line := range commentLines {
+		if rules, ok := cutLintDisableComment(line); ok {
+			l.suppressions = append(l.suppressions, lintSuppression{ptr: ptr, rules: rules})
+		}
+	}
+}
+
+// cutLintDisableComment turns this:
+//
+//	"# @schema-lint-disable foo, bar"
+//
+// into this:
+//
+//	[]string{"foo", "bar"}
+func cutLintDisableComment(line string) ([]string, bool) {
+	withoutPound := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+	withoutDirective, ok := strings.CutPrefix(withoutPound, "@schema-lint-disable")
+	if !ok {
+		return nil, false
+	}
+	if withoutDirective != "" && strings.TrimLeft(withoutDirective, " \t") == withoutDirective {
+		// this checks if we had "# @schema-lint-disablefoo" instead of "# @schema-lint-disable foo"
+		return nil, false
+	}
+	return strings.FieldsFunc(withoutDirective, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}), true
+}
+
+func (l *valuesLinter) report(rule string, ptr Ptr, node *yaml.Node, format string, args ...any) {
+	l.issues = append(l.issues, LintIssue{
+		Rule:    rule,
+		File:    l.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Ptr:     ptr,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lintNode lints the node and all of its child nodes. The inSchema argument
+// is false when a parent node is excluded from the schema, such as via "hidden",
+// in which case the values are not validated.
+func (l *valuesLinter) lintNode(ptr Ptr, keyNode, valNode *yaml.Node, inSchema bool) {
+	schemaComments, _ := getComments(keyNode, valNode, false)
+	l.addSuppressions(ptr, schemaComments)
+
+	schema, annotations, ok := l.parseAnnotations(ptr, keyNode, valNode)
+
+	childInSchema := inSchema && !(ok && (schema.Hidden || schema.SkipProperties))
+	switch valNode.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(valNode.Content); i += 2 {
+			childKeyNode := valNode.Content[i]
+			l.lintNode(ptr.Prop(childKeyNode.Value), childKeyNode, valNode.Content[i+1], childInSchema)
+		}
+	case yaml.SequenceNode:
+		for i, itemNode := range valNode.Content {
+			l.lintNode(ptr.Item(i), nil, itemNode, childInSchema)
+		}
+	}
+
+	if !ok {
+		return
+	}
+
+	// Annotations are reported on the key, while the values are reported on the value
+	annotationNode := valNode
+	if keyNode != nil {
+		annotationNode = keyNode
+	}
+
+	l.lintMinMax(ptr, annotationNode, schema)
+	if schema.Items != nil {
+		l.lintMinMax(ptr, annotationNode, schema.Items)
+	}
+	validPatterns := l.lintPatterns(ptr, annotationNode, schema)
+
+	if schema.RequiredByParent && schema.Hidden {
+		l.report(LintRuleRequiredHidden, ptr, annotationNode, `"required" has no effect on a "hidden" key, as hidden keys are left out of the schema`)
+	}
+	if schema.SkipProperties && !schema.IsType("object") {
+		l.report(LintRuleSkipPropertiesNonObject, ptr, annotationNode, `"skipProperties" has no effect when type is %s`, formatLintType(schema.Type))
+	}
+	if slices.Contains(annotations, "itemProperties") && !schema.IsType("array") {
+		l.report(LintRuleItemPropertiesNonArray, ptr, annotationNode, `"itemProperties" has no effect when type is %s`, formatLintType(schema.Type))
+	}
+
+	if schema.Default != nil {
+		if problems := validateAnnotatedValue(schema, schema.Default, validPatterns); len(problems) > 0 {
+			l.report(LintRuleDefaultMismatch, ptr, annotationNode, "default does not match annotations: %s", strings.Join(problems, "; "))
+		}
+	}
+
+	if !inSchema || schema.Hidden {
+		return
+	}
+	var value any
+	if err := valNode.Decode(&value); err != nil {
+		return
+	}
+	if problems := validateAnnotatedValue(schema, value, validPatterns); len(problems) > 0 {
+		l.report(LintRuleValueMismatch, ptr, valNode, "value does not match annotations: %s", strings.Join(problems, "; "))
+	}
+	if items, ok := value.([]any); ok && schema.Items != nil && valNode.Kind == yaml.SequenceNode {
+		for i, item := range items {
+			if problems := validateAnnotatedValue(schema.Items, item, validPatterns); len(problems) > 0 {
+				l.report(LintRuleValueMismatch, ptr.Item(i), valNode.Content[i], "value does not match item annotations: %s", strings.Join(problems, "; "))
+			}
+		}
+	}
+}
+
+// parseAnnotations returns the schema from the node's "# @schema" annotations
+// and the node's inferred type, or its helm-docs type, without any child
+// nodes, as well as the names of the annotations used. Returns false if the
+// node has no annotations.
+func (l *valuesLinter) parseAnnotations(ptr Ptr, keyNode, valNode *yaml.Node) (*Schema, []string, bool) {
+	schemaComments, helmDocsComments := getComments(keyNode, valNode, l.useHelmDocs)
+	var annotations []string
+	for key := range splitCommentsByParts(schemaComments) {
+		annotations = append(annotations, key)
+	}
+	if len(annotations) == 0 {
+		return nil, nil, false
+	}
+
+	schema := &Schema{}
+	switch valNode.Kind {
+	case yaml.MappingNode:
+		schema.Type = "object"
+	case yaml.SequenceNode:
+		schema.Type = "array"
+	case yaml.ScalarNode:
+		schema.Type = getScalarType(valNode.ShortTag())
+	}
+	if l.useHelmDocs {
+		// Same as when generating, see [nodeParser.processComments]
+		helmDocs, err := ParseHelmDocsComment(helmDocsComments)
+		if err != nil {
+			// Already reported when parsing the values file
+			return nil, nil, false
+		}
+		if len(helmDocs.Path) == 0 || ptr.Equals(NewPtr(helmDocs.Path...)) {
+			schema.Type = helmDocsType(helmDocs, schema.Type)
+		}
+	}
+	if err := processComment(schema, schemaComments); err != nil {
+		// Already reported when parsing the values file
+		return nil, nil, false
+	}
+	return schema, annotations, true
+}
+
+func (l *valuesLinter) lintMinMax(ptr Ptr, node *yaml.Node, schema *Schema) {
+	if schema == nil {
+		return
+	}
+	checkFloat := func(minName string, minValue *float64, maxName string, maxValue *float64) {
+		if minValue != nil && maxValue != nil && *minValue > *maxValue {
+			l.report(LintRuleMinGreaterThanMax, ptr, node, "%s (%v) is greater than %s (%v)", minName, *minValue, maxName, *maxValue)
+		}
+	}
+	checkUint := func(minName string, minValue *uint64, maxName string, maxValue *uint64) {
+		if minValue != nil && maxValue != nil && *minValue > *maxValue {
+			l.report(LintRuleMinGreaterThanMax, ptr, node, "%s (%d) is greater than %s (%d)", minName, *minValue, maxName, *maxValue)
+		}
+	}
+	checkFloat("minimum", schema.Minimum, "maximum", schema.Maximum)
+	checkUint("minLength", schema.MinLength, "maxLength", schema.MaxLength)
+	checkUint("minItems", schema.MinItems, "maxItems", schema.MaxItems)
+	checkUint("minProperties", schema.MinProperties, "maxProperties", schema.MaxProperties)
+}
+
+// lintPatterns reports all invalid patterns, and returns the compiled regular
+// expressions of the valid ones.
+func (l *valuesLinter) lintPatterns(ptr Ptr, node *yaml.Node, schema *Schema) map[string]*regexp2.Regexp {
+	validPatterns := map[string]*regexp2.Regexp{}
+	check := func(name, pattern string) {
+		if pattern == "" {
+			return
+		}
+		re, err := compileECMAPattern(pattern)
+		if err != nil {
+			l.report(LintRuleInvalidPattern, ptr, node, "%s %q is not a valid ECMA-262 regular expression: %v", name, pattern, err)
+			return
+		}
+		validPatterns[pattern] = re
+	}
+	check("pattern", schema.Pattern)
+	if schema.Items != nil {
+		check("itemPattern", schema.Items.Pattern)
+	}
+	for pattern := range iterMapOrdered(schema.PatternProperties) {
+		check("patternProperties", pattern)
+	}
+	return validPatterns
+}
+
+func compileECMAPattern(pattern string) (*regexp2.Regexp, error) {
+	re, err := regexp2.Compile(pattern, regexp2.ECMAScript)
+	if err != nil {
+		return nil, err
+	}
+	// Guard against catastrophic backtracking, as regexp2 is not linear-time
+	re.MatchTimeout = time.Second
+	return re, nil
+}
+
+func formatLintType(t any) string {
+	switch t := t.(type) {
+	case nil:
+		return "not set"
+	case []any:
+		return fmt.Sprintf("%v", t)
+	default:
+		return fmt.Sprintf("%q", t)
+	}
+}
+
+// validateAnnotatedValue validates the value against the keywords that can be
+// set using "# @schema" annotations, without looking at any subschemas, and
+// returns a list of problems.
+//
+// Patterns are only validated when found in validPatterns.
+func validateAnnotatedValue(schema *Schema, value any, validPatterns map[string]*regexp2.Regexp) []string {
+	var problems []string
+	if schema.Type != nil && !valueMatchesAnyType(schema.Type, value) {
+		problems = append(problems, fmt.Sprintf("type: got %s, want %s", jsonTypeOf(value), formatLintType(schema.Type)))
+		// Other keywords are meaningless when the type is wrong
+		return problems
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return jsonEqual(e, value) }) {
+		problems = append(problems, fmt.Sprintf("enum: %s is not one of %s", formatJSON(value), formatJSON(schema.Enum)))
+	}
+	if schema.Const != nil && !jsonEqual(schema.Const, value) {
+		problems = append(problems, fmt.Sprintf("const: %s is not %s", formatJSON(value), formatJSON(schema.Const)))
+	}
+
+	switch value := value.(type) {
+	case string:
+		length := uint64(utf8.RuneCountInString(value))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			problems = append(problems, fmt.Sprintf("minLength: length %d is less than %d", length, *schema.MinLength))
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			problems = append(problems, fmt.Sprintf("maxLength: length %d is greater than %d", length, *schema.MaxLength))
+		}
+		if re, ok := validPatterns[schema.Pattern]; ok && schema.Pattern != "" {
+			if matched, err := re.MatchString(value); err == nil && !matched {
+				problems = append(problems, fmt.Sprintf("pattern: %q does not match %q", value, schema.Pattern))
+			}
+		}
+	case []any:
+		length := uint64(len(value))
+		if schema.MinItems != nil && length < *schema.MinItems {
+			problems = append(problems, fmt.Sprintf("minItems: got %d items, want at least %d", length, *schema.MinItems))
+		}
+		if schema.MaxItems != nil && length > *schema.MaxItems {
+			problems = append(problems, fmt.Sprintf("maxItems: got %d items, want at most %d", length, *schema.MaxItems))
+		}
+		if schema.UniqueItems {
+			for i := range value {
+				if slices.ContainsFunc(value[:i], func(e any) bool { return jsonEqual(e, value[i]) }) {
+					problems = append(problems, fmt.Sprintf("uniqueItems: item at index %d is a duplicate", i))
+					break
+				}
+			}
+		}
+	case map[string]any:
+		length := uint64(len(value))
+		if schema.MinProperties != nil && length < *schema.MinProperties {
+			problems = append(problems, fmt.Sprintf("minProperties: got %d properties, want at least %d", length, *schema.MinProperties))
+		}
+		if schema.MaxProperties != nil && length > *schema.MaxProperties {
+			problems = append(problems, fmt.Sprintf("maxProperties: got %d properties, want at most %d", length, *schema.MaxProperties))
+		}
+	default:
+		num, ok := toFloat64(value)
+		if !ok {
+			break
+		}
+		if schema.Minimum != nil && num < *schema.Minimum {
+			problems = append(problems, fmt.Sprintf("minimum: %v is less than %v", num, *schema.Minimum))
+		}
+		if schema.Maximum != nil && num > *schema.Maximum {
+			problems = append(problems, fmt.Sprintf("maximum: %v is greater than %v", num, *schema.Maximum))
+		}
+		if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+			if quotient := num / *schema.MultipleOf; quotient != math.Trunc(quotient) {
+				problems = append(problems, fmt.Sprintf("multipleOf: %v is not a multiple of %v", num, *schema.MultipleOf))
+			}
+		}
+	}
+	return problems
+}
+
+func valueMatchesAnyType(schemaType, value any) bool {
+	switch schemaType := schemaType.(type) {
+	case []any:
+		return slices.ContainsFunc(schemaType, func(t any) bool { return valueMatchesType(t, value) })
+	default:
+		return valueMatchesType(schemaType, value)
+	}
+}
+
+func valueMatchesType(schemaType, value any) bool {
+	actual := jsonTypeOf(value)
+	switch schemaType {
+	case actual:
+		return true
+	case "number":
+		return actual == "integer"
+	case "integer":
+		num, ok := toFloat64(value)
+		return ok && num == math.Trunc(num)
+	default:
+		return false
+	}
+}
+
+// jsonTypeOf returns the JSON Schema type name of a YAML-decoded value.
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+// jsonEqual compares two YAML-decoded values by their JSON encoding,
+// which makes e.g the integer 1 equal to the float 1.0.
+func jsonEqual(a, b any) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aJSON, bJSON)
+}
+
+func formatJSON(value any) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
@@ -0,0 +1,478 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      string
+		useHelmDocs bool
+		want        []LintIssue
+	}{
+		{
+			name:   "empty file",
+			values: "",
+		},
+		{
+			name: "no issues",
+			values: `
+replicas: 1 # @schema type: integer; minimum: 1; maximum: 10
+name: foo # @schema pattern: ^[a-z]+$; minLength: 1; default: bar
+tags: [a, b] # @schema item: string; itemEnum: [a, b]; uniqueItems
+env: [] # @schema itemProperties: {name: {type: string}}
+resources: {} # @schema skipProperties; minProperties: 0
+nullable: null # @schema type: string; nullable
+`,
+		},
+		{
+			name:   "multiple documents",
+			values: "replicas: 1 # @schema type: integer\n---\nreplicas: \"3\" # @schema type: integer\n",
+			want: []LintIssue{
+				{Rule: LintRuleValueMismatch, Line: 3, Column: 11, Ptr: NewPtr("replicas"), Message: `value does not match annotations: type: got string, want "integer"`},
+			},
+		},
+		{
+			name:   "value type mismatch",
+			values: `replicas: "3" # @schema type: integer`,
+			want: []LintIssue{
+				{Rule: LintRuleValueMismatch, Line: 1, Column: 11, Ptr: NewPtr("replicas"), Message: `value does not match annotations: type: got string, want "integer"`},
+			},
+		},
+		{
+			name:   "value enum mismatch",
+			values: `pullPolicy: Sometimes # @schema enum: [Always, IfNotPresent, Never]`,
+			want: []LintIssue{
+				{Rule: LintRuleValueMismatch, Line: 1, Column: 13, Ptr: NewPtr("pullPolicy"), Message: `value does not match annotations: enum: "Sometimes" is not one of ["Always","IfNotPresent","Never"]`},
+			},
+		},
+		{
+			name:   "value multiple problems",
+			values: `name: ABC # @schema pattern: ^[a-z]+$; maxLength: 2`,
+			want: []LintIssue{
+				{Rule: LintRuleValueMismatch, Line: 1, Column: 7, Ptr: NewPtr("name"), Message: `value does not match annotations: maxLength: length 3 is greater than 2; pattern: "ABC" does not match "^[a-z]+$"`},
+			},
+		},
+		{
+			name: "array item mismatch",
+			values: `
+ports: # @schema item: integer
+  - 80
+  - http
+`,
+			want: []LintIssue{
+				{Rule: LintRuleValueMismatch, Line: 4, Column: 5, Ptr: NewPtr("ports").Item(1), Message: `value does not match item annotations: type: got string, want "integer"`},
+			},
+		},
+		{
+			name:   "default type mismatch",
+			values: `tags: [] # @schema default: latest`,
+			want: []LintIssue{
+				{Rule: LintRuleDefaultMismatch, Line: 1, Column: 1, Ptr: NewPtr("tags"), Message: `default does not match annotations: type: got string, want "array"`},
+			},
+		},
+		{
+			name:   "default enum mismatch",
+			values: `pullPolicy: Always # @schema enum: [Always, Never]; default: IfNotPresent`,
+			want: []LintIssue{
+				{Rule: LintRuleDefaultMismatch, Line: 1, Column: 1, Ptr: NewPtr("pullPolicy"), Message: `default does not match annotations: enum: "IfNotPresent" is not one of ["Always","Never"]`},
+			},
+		},
+		{
+			name: "minimum greater than maximum",
+			values: `
+port: 80 # @schema minimum: 100; maximum: 10
+name: foo # @schema minLength: 5; maxLength: 1
+`,
+			want: []LintIssue{
+				{Rule: LintRuleMinGreaterThanMax, Line: 2, Column: 1, Ptr: NewPtr("port"), Message: `minimum (100) is greater than maximum (10)`},
+				{Rule: LintRuleValueMismatch, Line: 2, Column: 7, Ptr: NewPtr("port"), Message: `value does not match annotations: minimum: 80 is less than 100; maximum: 80 is greater than 10`},
+				{Rule: LintRuleMinGreaterThanMax, Line: 3, Column: 1, Ptr: NewPtr("name"), Message: `minLength (5) is greater than maxLength (1)`},
+				{Rule: LintRuleValueMismatch, Line: 3, Column: 7, Ptr: NewPtr("name"), Message: `value does not match annotations: minLength: length 3 is less than 5; maxLength: length 3 is greater than 1`},
+			},
+		},
+		{
+			name:   "invalid pattern",
+			values: `name: foo # @schema pattern: ^(foo`,
+			want: []LintIssue{
+				{Rule: LintRuleInvalidPattern, Line: 1, Column: 1, Ptr: NewPtr("name"), Message: "pattern \"^(foo\" is not a valid ECMA-262 regular expression: error parsing regexp: missing closing ) in `^(foo`"},
+			},
+		},
+		{
+			name:   "ECMA-262 lookahead is valid",
+			values: `name: foo # @schema pattern: ^(?!bar).*$`,
+		},
+		{
+			name:   "invalid item pattern",
+			values: `names: [] # @schema itemPattern: ^(foo`,
+			want: []LintIssue{
+				{Rule: LintRuleInvalidPattern, Line: 1, Column: 1, Ptr: NewPtr("names"), Message: "itemPattern \"^(foo\" is not a valid ECMA-262 regular expression: error parsing regexp: missing closing ) in `^(foo`"},
+			},
+		},
+		{
+			name: "required on hidden key",
+			values: `
+# @schema hidden; required
+secret: hunter2
+`,
+			want: []LintIssue{
+				{Rule: LintRuleRequiredHidden, Line: 3, Column: 1, Ptr: NewPtr("secret"), Message: `"required" has no effect on a "hidden" key, as hidden keys are left out of the schema`},
+			},
+		},
+		{
+			name:   "skipProperties on non-object",
+			values: `image: nginx # @schema skipProperties`,
+			want: []LintIssue{
+				{Rule: LintRuleSkipPropertiesNonObject, Line: 1, Column: 1, Ptr: NewPtr("image"), Message: `"skipProperties" has no effect when type is "string"`},
+			},
+		},
+		{
+			name:   "itemProperties on non-array",
+			values: `env: {} # @schema itemProperties: {name: {type: string}}`,
+			want: []LintIssue{
+				{Rule: LintRuleItemPropertiesNonArray, Line: 1, Column: 1, Ptr: NewPtr("env"), Message: `"itemProperties" has no effect when type is "object"`},
+			},
+		},
+		{
+			name: "skips values excluded from schema",
+			values: `
+hidden: # @schema hidden
+  replicas: foo # @schema type: integer
+skipped: # @schema skipProperties
+  replicas: foo # @schema type: integer
+`,
+		},
+		{
+			name: "nested keys",
+			values: `
+image:
+  tag: 1.0 # @schema type: string
+`,
+			want: []LintIssue{
+				{Rule: LintRuleValueMismatch, Line: 3, Column: 8, Ptr: NewPtr("image", "tag"), Message: `value does not match annotations: type: got number, want "string"`},
+			},
+		},
+		{
+			name:   "invalid annotation is ignored",
+			values: `replicas: 1 # @schema minimum: foo`,
+		},
+		{
+			name: "suppress rule on key",
+			values: `
+# @schema-lint-disable value-mismatch
+replicas: foo # @schema type: integer; default: bar
+`,
+			want: []LintIssue{
+				{Rule: LintRuleDefaultMismatch, Line: 3, Column: 1, Ptr: NewPtr("replicas"), Message: `default does not match annotations: type: got string, want "integer"`},
+			},
+		},
+		{
+			name: "suppress multiple rules on line comment",
+			values: `
+replicas: foo # @schema type: integer; default: bar
+other: foo # @schema-lint-disable value-mismatch, default-mismatch
+`,
+			want: []LintIssue{
+				{Rule: LintRuleDefaultMismatch, Line: 2, Column: 1, Ptr: NewPtr("replicas"), Message: `default does not match annotations: type: got string, want "integer"`},
+				{Rule: LintRuleValueMismatch, Line: 2, Column: 11, Ptr: NewPtr("replicas"), Message: `value does not match annotations: type: got string, want "integer"`},
+			},
+		},
+		{
+			name: "suppress all rules on child keys",
+			values: `
+# @schema-lint-disable
+image:
+  tag: 1.0 # @schema type: string
+  pullPolicy: foo # @schema enum: [Always]
+`,
+		},
+		{
+			name:        "helm-docs type",
+			values:      "# @schema default: 3\n# -- (int) replicas\nreplicas:\n",
+			useHelmDocs: true,
+		},
+		{
+			name:        "helm-docs type mismatch",
+			values:      "# @schema default: three\n# -- (int) replicas\nreplicas: 1\n",
+			useHelmDocs: true,
+			want: []LintIssue{
+				{Rule: LintRuleDefaultMismatch, Line: 3, Column: 1, Ptr: NewPtr("replicas"), Message: `default does not match annotations: type: got string, want "integer"`},
+			},
+		},
+		{
+			name:   "helm-docs type ignored without useHelmDocs",
+			values: "# @schema default: 3\n# -- (int) replicas\nreplicas:\n",
+			want: []LintIssue{
+				{Rule: LintRuleDefaultMismatch, Line: 3, Column: 1, Ptr: NewPtr("replicas"), Message: `default does not match annotations: type: got integer, want "null"`},
+			},
+		},
+		{
+			name: "suppress rule in file",
+			values: `# @schema-lint-disable value-mismatch
+
+replicas: foo # @schema type: integer
+image:
+  tag: 1.0 # @schema type: string; default: 1
+`,
+			want: []LintIssue{
+				{Rule: LintRuleDefaultMismatch, Line: 5, Column: 3, Ptr: NewPtr("image", "tag"), Message: `default does not match annotations: type: got integer, want "string"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.want {
+				tt.want[i].File = "values.yaml"
+			}
+			got, err := lintValues("values.yaml", []byte(tt.values), tt.useHelmDocs)
+			require.NoError(t, err)
+			if len(tt.want) == 0 {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLintValues_Error(t *testing.T) {
+	_, err := lintValues("values.yaml", []byte("foo: [bar"), false)
+	assert.ErrorContains(t, err, "yaml: line 1:")
+}
+
+func TestLintValuesFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "values.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("replicas: foo # @schema type: integer\n"), 0600))
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("foo: [bar"), 0600))
+
+	issues, err := lintValuesFiles([]string{"-", path}, false, LintConfig{}, nil)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, path+":1:11: /replicas: value does not match annotations: type: got string, want \"integer\" (value-mismatch)", issues[0].String())
+
+	issues, err = lintValuesFiles([]string{path}, false, LintConfig{Rules: map[string]LintSeverity{
+		LintRuleValueMismatch: LintSeverityError,
+	}}, nil)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, LintSeverityError, issues[0].Severity)
+
+	issues, err = lintValuesFiles([]string{path}, false, LintConfig{Rules: map[string]LintSeverity{
+		LintRuleValueMismatch: LintSeverityOff,
+	}}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	_, err = lintValuesFiles([]string{path}, false, LintConfig{Rules: map[string]LintSeverity{"foo": LintSeverityOff}}, nil)
+	assert.ErrorContains(t, err, `lint.rules: unknown rule "foo"`)
+
+	_, err = lintValuesFiles([]string{filepath.Join(dir, "does-not-exist.yaml")}, false, LintConfig{}, nil)
+	assert.ErrorContains(t, err, "read --values=")
+
+	_, err = lintValuesFiles([]string{invalidPath}, false, LintConfig{}, nil)
+	assert.ErrorContains(t, err, "lint --values=")
+}
+
+func TestValidateAnnotatedValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *Schema
+		value  any
+		want   []string
+	}{
+		{name: "no keywords", schema: &Schema{}, value: "foo"},
+		{name: "integer as number", schema: &Schema{Type: "number"}, value: 1},
+		{name: "integral float as integer", schema: &Schema{Type: "integer"}, value: 1.0},
+		{name: "type list", schema: &Schema{Type: []any{"string", "null"}}, value: nil},
+		{
+			name:   "type list mismatch",
+			schema: &Schema{Type: []any{"string", "null"}},
+			value:  true,
+			want:   []string{"type: got boolean, want [string null]"},
+		},
+		{
+			name:   "fraction as integer",
+			schema: &Schema{Type: "integer"},
+			value:  1.5,
+			want:   []string{`type: got number, want "integer"`},
+		},
+		{name: "enum number equality", schema: &Schema{Enum: []any{1.0}}, value: 1},
+		{
+			name:   "const",
+			schema: &Schema{Const: "foo"},
+			value:  "bar",
+			want:   []string{`const: "bar" is not "foo"`},
+		},
+		{
+			name:   "multipleOf",
+			schema: &Schema{MultipleOf: float64Ptr(2)},
+			value:  3,
+			want:   []string{"multipleOf: 3 is not a multiple of 2"},
+		},
+		{
+			name:   "array length and uniqueness",
+			schema: &Schema{MinItems: uint64Ptr(3), MaxItems: uint64Ptr(1), UniqueItems: true},
+			value:  []any{"a", "a"},
+			want: []string{
+				"minItems: got 2 items, want at least 3",
+				"maxItems: got 2 items, want at most 1",
+				"uniqueItems: item at index 1 is a duplicate",
+			},
+		},
+		{
+			name:   "object length",
+			schema: &Schema{MinProperties: uint64Ptr(2), MaxProperties: uint64Ptr(0)},
+			value:  map[string]any{"a": 1},
+			want: []string{
+				"minProperties: got 1 properties, want at least 2",
+				"maxProperties: got 1 properties, want at most 0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validateAnnotatedValue(tt.schema, tt.value, nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLintConfigSeverities(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  LintConfig
+		want    map[string]LintSeverity
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			config: LintConfig{},
+			want: map[string]LintSeverity{
+				LintRuleDefaultMismatch:         LintSeverityWarning,
+				LintRuleValueMismatch:           LintSeverityWarning,
+				LintRuleMinGreaterThanMax:       LintSeverityWarning,
+				LintRuleInvalidPattern:          LintSeverityWarning,
+				LintRuleRequiredHidden:          LintSeverityWarning,
+				LintRuleSkipPropertiesNonObject: LintSeverityWarning,
+				LintRuleItemPropertiesNonArray:  LintSeverityWarning,
+				LintRuleUnknownConfigField:      LintSeverityWarning,
+				LintRuleUncoveredKey:            LintSeverityWarning,
+			},
+		},
+		{
+			name: "override",
+			config: LintConfig{Rules: map[string]LintSeverity{
+				LintRuleDefaultMismatch: LintSeverityError,
+				LintRuleValueMismatch:   LintSeverityInfo,
+				LintRuleRequiredHidden:  LintSeverityOff,
+			}},
+			want: map[string]LintSeverity{
+				LintRuleDefaultMismatch:         LintSeverityError,
+				LintRuleValueMismatch:           LintSeverityInfo,
+				LintRuleMinGreaterThanMax:       LintSeverityWarning,
+				LintRuleInvalidPattern:          LintSeverityWarning,
+				LintRuleRequiredHidden:          LintSeverityOff,
+				LintRuleSkipPropertiesNonObject: LintSeverityWarning,
+				LintRuleItemPropertiesNonArray:  LintSeverityWarning,
+				LintRuleUnknownConfigField:      LintSeverityWarning,
+				LintRuleUncoveredKey:            LintSeverityWarning,
+			},
+		},
+		{
+			name:    "unknown rule",
+			config:  LintConfig{Rules: map[string]LintSeverity{"foo": LintSeverityError}},
+			wantErr: `lint.rules: unknown rule "foo", must be one of: default-mismatch, value-mismatch, min-greater-than-max, invalid-pattern, required-hidden, skip-properties-non-object, item-properties-non-array, unknown-config-field, uncovered-key`,
+		},
+		{
+			name:    "invalid severity",
+			config:  LintConfig{Rules: map[string]LintSeverity{LintRuleValueMismatch: "fatal"}},
+			wantErr: `lint.rules.value-mismatch: invalid severity "fatal", must be one of: error, warning, info, off`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.severities()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLintIssueString(t *testing.T) {
+	tests := []struct {
+		name  string
+		issue LintIssue
+		want  string
+	}{
+		{
+			name:  "full location",
+			issue: LintIssue{Rule: "r", File: "values.yaml", Line: 2, Column: 3, Ptr: NewPtr("foo"), Message: "msg"},
+			want:  "values.yaml:2:3: /foo: msg (r)",
+		},
+		{
+			name:  "line only",
+			issue: LintIssue{Rule: "r", File: ".schema.yaml", Line: 4, Message: "msg"},
+			want:  ".schema.yaml:4: msg (r)",
+		},
+		{
+			name:  "file only",
+			issue: LintIssue{Rule: "r", File: "values.yaml", Column: 3, Message: "msg"},
+			want:  "values.yaml: msg (r)",
+		},
+		{
+			name:  "pointer only",
+			issue: LintIssue{Rule: "r", Ptr: NewPtr("required"), Message: "msg"},
+			want:  "/required: msg (r)",
+		},
+		{
+			name:  "no location",
+			issue: LintIssue{Rule: "r", Message: "msg"},
+			want:  "msg (r)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.issue.String())
+		})
+	}
+}
+
+func TestCutLintDisableComment(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantRules []string
+		wantOK    bool
+	}{
+		{name: "no directive", line: "# foo", wantOK: false},
+		{name: "schema annotation", line: "# @schema type: string", wantOK: false},
+		{name: "all rules", line: "# @schema-lint-disable", wantRules: []string{}, wantOK: true},
+		{name: "single rule", line: "# @schema-lint-disable value-mismatch", wantRules: []string{"value-mismatch"}, wantOK: true},
+		{name: "comma separated", line: "#@schema-lint-disable a,b, c", wantRules: []string{"a", "b", "c"}, wantOK: true},
+		{name: "missing space", line: "# @schema-lint-disablefoo", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, ok := cutLintDisableComment(tt.line)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantRules, rules)
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package pkg
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"go.yaml.in/yaml/v3"
+)
+
+// lintUncoveredKeys validates the values files against the generated schema,
+// and reports every key that is rejected by "additionalProperties: false" or
+// "unevaluatedProperties: false", which would otherwise only be discovered
+// when installing the chart. The issues are returned without a severity.
+//
+// Unless config.Bundle already did so, the schemas referenced using $ref are
+// bundled first, so that they are checked too, but only from local files.
+// Schemas that would be downloaded, or that fail to load, are replaced with
+// a schema that allows any value, as the keys below them can't be checked.
+// Keys are not reported when they are only rejected inside an "anyOf" or
+// "oneOf", as any of the other subschemas may still allow them.
+//
+// Values read from stdin ("-") are skipped, as stdin has already been consumed
+// when generating the schema.
+func lintUncoveredKeys(ctx context.Context, config *Config, schema *Schema) ([]LintIssue, error) {
+	valuesFiles := slices.DeleteFunc(slices.Clone(config.Values), func(filePath string) bool {
+		return filePath == "-"
+	})
+	if len(valuesFiles) == 0 {
+		return nil, nil
+	}
+
+	if !config.Bundle {
+		bundledConfig := *config
+		bundledConfig.Bundle = true
+		bundledConfig.Values = valuesFiles
+		logger := LoggerFromContext(ctx)
+		// Bundling logs every loaded file, which is only noise in the lint output.
+		var err error
+		schema, err = buildJSONSchemaWithLoader(ContextWithLogger(ctx, NewLogger(io.Discard)), &bundledConfig, func(inner Loader) Loader {
+			return localRefLoader{inner: inner, logger: logger}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("bundle schema for the %s rule: %w", LintRuleUncoveredKey, err)
+		}
+	}
+
+	compiled, err := compileValuesSchema(schema, config.Output)
+	var schemaErr *jsonschema.SchemaValidationError
+	if errors.As(err, &schemaErr) {
+		// The schema does not conform to its metaschema, which is instead
+		// reported when using "--metaschema".
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compile schema for the %s rule: %w", LintRuleUncoveredKey, err)
+	}
+
+	var issues []LintIssue
+	for _, filePath := range valuesFiles {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("read --values=%q: %w", filePath, err)
+		}
+		fileIssues, err := uncoveredKeys(filePath, content, compiled)
+		if err != nil {
+			return nil, fmt.Errorf("lint --values=%q: %w", filePath, err)
+		}
+		issues = append(issues, fileIssues...)
+	}
+	return issues, nil
+}
+
+// localRefLoader wraps a [Loader] to only load schemas from local files when
+// bundling for [lintUncoveredKeys]. Any other $ref, and any $ref that fails
+// to load, is replaced with a schema that allows any value.
+type localRefLoader struct {
+	inner  Loader
+	logger Logger
+}
+
+// Load implements [Loader].
+func (l localRefLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
+	if !isLocalRef(ref) {
+		l.logger.Logf("Warning: %s rule: keys below $ref %q are not checked, as it is only downloaded with --bundle", LintRuleUncoveredKey, ref.Redacted())
+		return unloadedRefSchema(ref.Fragment)
+	}
+	schema, err := l.inner.Load(ctx, ref)
+	if err != nil {
+		l.logger.Logf("Warning: %s rule: keys below $ref %q are not checked: %v", LintRuleUncoveredKey, ref.Redacted(), err)
+		return unloadedRefSchema(ref.Fragment)
+	}
+	return schema, nil
+}
+
+// isLocalRef returns true if the $ref is loaded from a local file.
+func isLocalRef(ref *url.URL) bool {
+	if ref.Scheme == "" || ref.Scheme == "file" {
+		return true
+	}
+	_, ok := localSchemeRefPath(ref)
+	return ok
+}
+
+// unloadedRefSchema returns a schema that allows any value, which contains
+// the subschema or anchor of the fragment, so that the $ref still resolves.
+func unloadedRefSchema(fragment string) (*Schema, error) {
+	if isAnchorFragment(fragment) {
+		return &Schema{Anchor: fragment}, nil
+	}
+	var doc any = map[string]any{}
+	ptr := ParsePtr(fragment)
+	for i := len(ptr) - 1; i >= 0; i-- {
+		doc = map[string]any{pointerReplacerReverse.Replace(ptr[i]): doc}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal schema: %w", err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, fmt.Errorf("schema for fragment %q: %w", fragment, err)
+	}
+	return &schema, nil
+}
+
+// compileValuesSchema compiles the bundled schema for validating values,
+// using the output path as the base URI of its relative $id and $ref.
+func compileValuesSchema(schema *Schema, output string) (*jsonschema.Schema, error) {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("marshal schema: %w", err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal schema: %w", err)
+	}
+
+	outputPath, err := filepath.Abs(cmp.Or(output, DefaultConfig.Output))
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path: %w", err)
+	}
+	schemaURL := (&url.URL{Scheme: "file", Path: "/" + strings.TrimPrefix(filepath.ToSlash(outputPath), "/")}).String()
+
+	compiler := jsonschema.NewCompiler()
+	compiler.UseRegexpEngine(lenientECMARegexpEngine)
+	if err := compiler.AddResource(schemaURL, doc); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(schemaURL)
+}
+
+// uncoveredKeys validates a single values file using [lintUncoveredKeys].
+func uncoveredKeys(filePath string, content []byte, schema *jsonschema.Schema) ([]LintIssue, error) {
+	// Change Window's CRLF to LF line endings, same as when generating the schema
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+
+	docs, err := decodeYAMLDocuments(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse YAML: %w", err)
+	}
+
+	// Each document is validated as if it was a separate values file
+	var issues []LintIssue
+	for _, doc := range docs {
+		docIssues, err := uncoveredDocumentKeys(filePath, doc.Content[0], schema)
+		if err != nil {
+			if multiDocument(docs) {
+				return nil, fmt.Errorf("document %d: %w", doc.Number, err)
+			}
+			return nil, err
+		}
+		issues = append(issues, docIssues...)
+	}
+
+	slices.SortFunc(issues, func(a, b LintIssue) int {
+		return cmp.Or(
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+			cmp.Compare(a.Ptr.String(), b.Ptr.String()),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+	return slices.CompactFunc(issues, func(a, b LintIssue) bool {
+		return a.Line == b.Line && a.Ptr.Equals(b.Ptr) && a.Message == b.Message
+	}), nil
+}
+
+// uncoveredDocumentKeys validates the root node of a single YAML document
+// using [lintUncoveredKeys].
+func uncoveredDocumentKeys(filePath string, rootNode *yaml.Node, schema *jsonschema.Schema) ([]LintIssue, error) {
+	var values any
+	if err := rootNode.Decode(&values); err != nil {
+		return nil, fmt.Errorf("decode YAML: %w", err)
+	}
+	if values == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("convert values to JSON: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("convert values to JSON: %w", err)
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if err := schema.Validate(instance); !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var issues []LintIssue
+	collectUncoveredKeys(validationErr, func(ptr Ptr, keyword string) {
+		issue := LintIssue{
+			Rule:    LintRuleUncoveredKey,
+			File:    filePath,
+			Ptr:     ptr,
+			Message: fmt.Sprintf("key is not covered by the schema, and is rejected by %q", keyword+": false"),
+		}
+		if keyNode := findValuesKeyNode(rootNode, ptr); keyNode != nil {
+			issue.Line = keyNode.Line
+			issue.Column = keyNode.Column
+		}
+		issues = append(issues, issue)
+	})
+	return issues, nil
+}
+
+// collectUncoveredKeys calls yield with the pointer of every key rejected by
+// "additionalProperties: false" or "unevaluatedProperties: false".
+func collectUncoveredKeys(err *jsonschema.ValidationError, yield func(ptr Ptr, keyword string)) {
+	switch errKind := err.ErrorKind.(type) {
+	case *kind.AdditionalProperties:
+		for _, prop := range errKind.Properties {
+			yield(NewPtr(err.InstanceLocation...).Prop(prop), "additionalProperties")
+		}
+	case *kind.FalseSchema:
+		if len(err.InstanceLocation) > 0 && strings.HasSuffix(err.SchemaURL, "/unevaluatedProperties") {
+			yield(NewPtr(err.InstanceLocation...), "unevaluatedProperties")
+		}
+	case *kind.AnyOf, *kind.OneOf:
+		// Any of the other subschemas may allow the key.
+		return
+	}
+	for _, cause := range err.Causes {
+		collectUncoveredKeys(cause, yield)
+	}
+}
+
+// findValuesKeyNode returns the key node at the pointer in the values file,
+// or the item node for array items. Returns nil when not found.
+func findValuesKeyNode(node *yaml.Node, ptr Ptr) *yaml.Node {
+	var found *yaml.Node
+	for _, token := range ptr {
+		node = resolveAlias(node)
+		name := pointerReplacerReverse.Replace(token)
+		found = nil
+		switch node.Kind {
+		case yaml.MappingNode:
+			pairs, err := mappingPairs(node)
+			if err != nil {
+				return nil
+			}
+			for _, pair := range pairs {
+				if pair.Key.Value == name {
+					found = pair.Key
+					node = pair.Value
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(node.Content) {
+				found = node.Content[i]
+				node = found
+			}
+		}
+		if found == nil {
+			return nil
+		}
+	}
+	return found
+}
+
+// lenientECMARegexpEngine compiles ECMA-262 patterns when validating values.
+// Invalid patterns match everything, as they are already reported by the
+// invalid-pattern rule.
+func lenientECMARegexpEngine(pattern string) (jsonschema.Regexp, error) {
+	re, err := compileECMAPattern(pattern)
+	if err != nil {
+		return matchAllRegexp(pattern), nil
+	}
+	return ecmaRegexp{re}, nil
+}
+
+type ecmaRegexp struct {
+	re *regexp2.Regexp
+}
+
+func (r ecmaRegexp) MatchString(s string) bool {
+	// Patterns that time out are treated as matching, same as invalid patterns.
+	ok, err := r.re.MatchString(s)
+	return ok || err != nil
+}
+
+func (r ecmaRegexp) String() string {
+	return r.re.String()
+}
+
+type matchAllRegexp string
+
+func (matchAllRegexp) MatchString(string) bool {
+	return true
+}
+
+func (r matchAllRegexp) String() string {
+	return string(r)
+}
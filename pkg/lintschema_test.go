@@ -0,0 +1,305 @@
+package pkg
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestLintUncoveredKeys(t *testing.T) {
+	valuesPath := "../testdata/lint/uncovered/values.yaml"
+	wantIssues := []LintIssue{
+		{
+			Rule: LintRuleUncoveredKey, File: valuesPath, Line: 3, Column: 3, Ptr: NewPtr("image", "tagg"),
+			Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`,
+		},
+		{
+			Rule: LintRuleUncoveredKey, File: valuesPath, Line: 6, Column: 3, Ptr: NewPtr("service", "portt"),
+			Message: `key is not covered by the schema, and is rejected by "unevaluatedProperties: false"`,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		config  *Config
+		want    []LintIssue
+		wantErr string
+	}{
+		{
+			name:   "bundles when not bundled already",
+			config: &Config{Values: []string{valuesPath}, Draft: 2020, Indent: 4, BundleRoot: ".."},
+			want:   wantIssues,
+		},
+		{
+			name:   "already bundled",
+			config: &Config{Values: []string{valuesPath}, Draft: 2020, Indent: 4, BundleRoot: "..", Bundle: true},
+			want:   wantIssues,
+		},
+		{
+			name:   "skips stdin",
+			config: &Config{Values: []string{"-"}, Draft: 2020, Indent: 4},
+		},
+		{
+			name:   "refs that fail to load are not checked",
+			config: &Config{Values: []string{valuesPath}, Draft: 2020, Indent: 4},
+		},
+		{
+			name:   "remote refs are not downloaded",
+			config: &Config{Values: []string{"../testdata/lint/uncovered-remote.yaml"}, Draft: 2020, Indent: 4},
+			want: []LintIssue{
+				{
+					Rule: LintRuleUncoveredKey, File: "../testdata/lint/uncovered-remote.yaml", Line: 8, Column: 3, Ptr: NewPtr("service", "portt"),
+					Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`,
+				},
+			},
+		},
+		{
+			name:    "bundle error",
+			config:  &Config{Values: []string{valuesPath}, Draft: 2020, Indent: 4, BundleCacheMin: "foo"},
+			wantErr: "bundle schema for the uncovered-key rule: ",
+		},
+		{
+			name:   "invalid schema is skipped",
+			config: &Config{Values: []string{"../testdata/lint/values-metaschema.yaml"}, Draft: 2020, Indent: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ContextWithLogger(t.Context(), t)
+			var schema *Schema
+			if tt.config.Bundle {
+				var err error
+				schema, err = buildJSONSchema(ctx, tt.config)
+				require.NoError(t, err)
+			}
+			got, err := lintUncoveredKeys(ctx, tt.config, schema)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLintUncoveredKeys_FileErrors(t *testing.T) {
+	dir := t.TempDir()
+	schema := &Schema{Type: "object"}
+
+	_, err := lintUncoveredKeys(t.Context(), &Config{Values: []string{filepath.Join(dir, "does-not-exist.yaml")}, Bundle: true}, schema)
+	assert.ErrorContains(t, err, "read --values=")
+
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("a: [\n"), 0o644))
+	_, err = lintUncoveredKeys(t.Context(), &Config{Values: []string{invalidPath}, Bundle: true}, schema)
+	assert.ErrorContains(t, err, "lint --values=")
+
+	_, err = lintUncoveredKeys(t.Context(), &Config{Values: []string{invalidPath}, Bundle: true}, &Schema{Ref: "#/$defs/missing"})
+	assert.ErrorContains(t, err, "compile schema for the uncovered-key rule: ")
+}
+
+func TestUncoveredKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		values  string
+		want    []LintIssue
+		wantErr string
+	}{
+		{
+			name:   "empty file",
+			schema: `{"additionalProperties": false}`,
+			values: "",
+		},
+		{
+			name:   "null document",
+			schema: `{"additionalProperties": false}`,
+			values: "null",
+		},
+		{
+			name:   "all covered",
+			schema: `{"properties": {"foo": {}}, "additionalProperties": false}`,
+			values: "foo: 1",
+		},
+		{
+			name:   "other errors are ignored",
+			schema: `{"properties": {"foo": {"type": "string"}}, "required": ["bar"], "additionalProperties": false}`,
+			values: "foo: 1",
+		},
+		{
+			name:   "additional properties",
+			schema: `{"properties": {"foo": {"properties": {"bar": {}}, "additionalProperties": false}}}`,
+			values: "foo:\n  bar: 1\n  baz: 2\n  qux: 3\n",
+			want: []LintIssue{
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 3, Column: 3, Ptr: NewPtr("foo", "baz"), Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`},
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 4, Column: 3, Ptr: NewPtr("foo", "qux"), Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`},
+			},
+		},
+		{
+			name:   "unevaluated properties",
+			schema: `{"allOf": [{"properties": {"foo": {}}}], "unevaluatedProperties": false}`,
+			values: "foo: 1\nbar: 2\n",
+			want: []LintIssue{
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 2, Column: 1, Ptr: NewPtr("bar"), Message: `key is not covered by the schema, and is rejected by "unevaluatedProperties: false"`},
+			},
+		},
+		{
+			name:   "array items",
+			schema: `{"properties": {"list": {"items": {"properties": {"name": {}}, "additionalProperties": false}}}}`,
+			values: "list:\n  - name: a\n  - name: b\n    nmae: c\n",
+			want: []LintIssue{
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 4, Column: 5, Ptr: NewPtr("list").Item(1).Prop("nmae"), Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`},
+			},
+		},
+		{
+			name:   "duplicates from multiple subschemas",
+			schema: `{"allOf": [{"additionalProperties": false}, {"additionalProperties": false}]}`,
+			values: "foo: 1",
+			want: []LintIssue{
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 1, Column: 1, Ptr: NewPtr("foo"), Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`},
+			},
+		},
+		{
+			name:   "anyOf is skipped",
+			schema: `{"anyOf": [{"properties": {"foo": {}}, "additionalProperties": false}, {"properties": {"bar": {}}, "additionalProperties": false}]}`,
+			values: "foo: 1\nbar: 2\n",
+		},
+		{
+			name:   "oneOf is skipped",
+			schema: `{"oneOf": [{"properties": {"foo": {}}, "additionalProperties": false}, {"properties": {"bar": {}}, "additionalProperties": false}]}`,
+			values: "foo: 1\nbar: 2\n",
+		},
+		{
+			name:   "key with slash",
+			schema: `{"additionalProperties": false}`,
+			values: "a/b: 1",
+			want: []LintIssue{
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 1, Column: 1, Ptr: NewPtr("a/b"), Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`},
+			},
+		},
+		{
+			name:   "invalid pattern matches everything",
+			schema: `{"patternProperties": {"^(foo": {}}, "additionalProperties": false}`,
+			values: "bar: 1",
+		},
+		{
+			name:   "multiple documents",
+			schema: `{"properties": {"foo": {}}, "additionalProperties": false}`,
+			values: "foo: 1\nbar: 2\n---\n---\nfoo: 3\nbar: 4\n",
+			want: []LintIssue{
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 2, Column: 1, Ptr: NewPtr("bar"), Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`},
+				{Rule: LintRuleUncoveredKey, File: "values.yaml", Line: 6, Column: 1, Ptr: NewPtr("bar"), Message: `key is not covered by the schema, and is rejected by "additionalProperties: false"`},
+			},
+		},
+		{
+			name:    "invalid YAML",
+			schema:  `{}`,
+			values:  "a: [",
+			wantErr: "parse YAML: ",
+		},
+		{
+			name:    "invalid YAML in later document",
+			schema:  `{}`,
+			values:  "a: 1\n---\na: [",
+			wantErr: "parse YAML: document 2: ",
+		},
+		{
+			name:    "non-string keys",
+			schema:  `{}`,
+			values:  "? [a]\n: 1\n",
+			wantErr: "decode YAML: ",
+		},
+		{
+			name:    "non-string keys in later document",
+			schema:  `{}`,
+			values:  "a: 1\n---\n? [a]\n: 1\n",
+			wantErr: "document 2: decode YAML: ",
+		},
+		{
+			name:    "non-string keys after empty document",
+			schema:  `{}`,
+			values:  "a: 1\n---\n---\n? [a]\n: 1\n",
+			wantErr: "document 3: decode YAML: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema Schema
+			require.NoError(t, json.Unmarshal([]byte(tt.schema), &schema))
+			compiled, err := compileValuesSchema(&schema, "")
+			require.NoError(t, err)
+
+			got, err := uncoveredKeys("values.yaml", []byte(tt.values), compiled)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindValuesKeyNode(t *testing.T) {
+	var node yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(`
+base: &base
+  foo: 1
+alias: *base
+list:
+  - a
+  - b: 2
+`), &node))
+	root := node.Content[0]
+
+	tests := []struct {
+		name     string
+		ptr      Ptr
+		wantLine int
+		wantCol  int
+	}{
+		{name: "root key", ptr: NewPtr("base"), wantLine: 2, wantCol: 1},
+		{name: "nested key", ptr: NewPtr("base", "foo"), wantLine: 3, wantCol: 3},
+		{name: "through alias", ptr: NewPtr("alias", "foo"), wantLine: 3, wantCol: 3},
+		{name: "array item", ptr: NewPtr("list").Item(0), wantLine: 6, wantCol: 5},
+		{name: "key in array item", ptr: NewPtr("list").Item(1).Prop("b"), wantLine: 7, wantCol: 5},
+		{name: "missing key", ptr: NewPtr("missing")},
+		{name: "missing item", ptr: NewPtr("list").Item(5)},
+		{name: "not an index", ptr: NewPtr("list", "foo")},
+		{name: "scalar", ptr: NewPtr("base", "foo", "bar")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findValuesKeyNode(root, tt.ptr)
+			if tt.wantLine == 0 {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.wantLine, got.Line)
+			assert.Equal(t, tt.wantCol, got.Column)
+		})
+	}
+}
+
+func TestLenientECMARegexpEngine(t *testing.T) {
+	re, err := lenientECMARegexpEngine(`^\d+$`)
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("123"))
+	assert.False(t, re.MatchString("abc"))
+	assert.Equal(t, `^\d+$`, re.String())
+
+	re, err = lenientECMARegexpEngine(`^(foo`)
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("anything"))
+	assert.Equal(t, `^(foo`, re.String())
+}
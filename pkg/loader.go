@@ -45,10 +45,13 @@ func (r *RootFS) Open(name string) (fs.File, error) {
 	return ((*os.Root)(r)).Open(name)
 }
 
-func NewDefaultLoader(client *http.Client, bundleFS fs.FS, basePath string, cacheMinDuration time.Duration) Loader {
+func NewDefaultLoader(client *http.Client, bundleFS fs.FS, basePath string, cache HTTPCache) Loader {
 	fileLoader := NewFileLoader(bundleFS, basePath)
-	httpLoader := NewHTTPLoader(client, NewHTTPCache(cacheMinDuration))
+	httpLoader := NewHTTPLoader(client, cache)
 	return NewCacheLoader(URLSchemeLoader{
+		"crd":   NewCRDLoader(fileLoader),
+		"chart": NewChartLoader(fileLoader),
+		"oci":   NewOCILoader(client, cache, fileLoader),
 		"http":  httpLoader,
 		"https": httpLoader,
 		"file":  fileLoader, // Used for "file:///some/abs/path"
@@ -124,8 +127,6 @@ var _ Loader = FileLoader{}
 
 // Load implements [Loader].
 func (loader FileLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
-	logger := LoggerFromContext(ctx)
-
 	if ref.Scheme != "file" && ref.Scheme != "" {
 		return nil, fmt.Errorf(`file url in $ref=%q must start with "file://", "./", or "/"`, ref)
 	}
@@ -138,11 +139,37 @@ func (loader FileLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error
 	}
 	pathAbs := filepath.FromSlash(refFile.Path)
 
+	path, b, err := loader.readFile(ctx, pathAbs)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema Schema
+	switch filepath.Ext(path) {
+	case ".yml", ".yaml", ".kyml", ".kyaml":
+		if err := yaml.Unmarshal(b, &schema); err != nil {
+			return nil, fmt.Errorf("parse YAML file: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(b, &schema); err != nil {
+			return nil, fmt.Errorf("parse JSON file: %w", err)
+		}
+	}
+
+	schema.SetReferrer(ReferrerDir(filepath.Dir(pathAbs)))
+	return &schema, nil
+}
+
+// readFile reads a file from the loader's file system, and returns the
+// path that was used to open it (relative to the fsRootPath, if possible).
+func (loader FileLoader) readFile(ctx context.Context, pathAbs string) (string, []byte, error) {
+	logger := LoggerFromContext(ctx)
+
 	path := pathAbs
 	if loader.fsRootPath != "" && filepath.IsAbs(pathAbs) {
 		rel, err := filepath.Rel(loader.fsRootPath, path)
 		if err != nil {
-			return nil, fmt.Errorf("get relative path from bundle root: %w", err)
+			return "", nil, fmt.Errorf("get relative path from bundle root: %w", err)
 		}
 		path = rel
 	}
@@ -150,30 +177,51 @@ func (loader FileLoader) Load(ctx context.Context, ref *url.URL) (*Schema, error
 	logger.Log("Loading file", path)
 	f, err := loader.fs.Open(path)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	defer closeIgnoreError(f)
 	b, err := io.ReadAll(f)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
 	logger.Logf("=> got %s", formatSizeBytes(len(b)))
+	return path, b, nil
+}
 
-	var schema Schema
-	switch filepath.Ext(path) {
-	case ".yml", ".yaml", ".kyml", ".kyaml":
-		if err := yaml.Unmarshal(b, &schema); err != nil {
-			return nil, fmt.Errorf("parse YAML file: %w", err)
+// localSchemeRefPath returns the local file path of a URL using one of the
+// schemes that load local files, which can either be written as
+// "crd:path/to/file.yaml" or "crd://path/to/file.yaml".
+//
+// The "oci:" scheme only refers to a local file when it has no host,
+// as "oci://registry/repository:tag" refers to an OCI registry.
+func localSchemeRefPath(ref *url.URL) (string, bool) {
+	switch ref.Scheme {
+	case crdScheme, chartScheme:
+	case ociScheme:
+		if ref.Host != "" {
+			return "", false
 		}
 	default:
-		if err := json.Unmarshal(b, &schema); err != nil {
-			return nil, fmt.Errorf("parse JSON file: %w", err)
-		}
+		return "", false
+	}
+	if ref.Opaque != "" {
+		return ref.Opaque, true
 	}
+	return path.Join(ref.Host, ref.Path), true
+}
 
-	schema.SetReferrer(ReferrerDir(filepath.Dir(pathAbs)))
-	return &schema, nil
+// localSchemeRefWithReferrer resolves the relative file path of a URL
+// using the referrer's directory. See [localSchemeRefPath].
+func localSchemeRefWithReferrer(ref *url.URL, referrer Referrer) *url.URL {
+	refPath, ok := localSchemeRefPath(ref)
+	if !ok || referrer.url != nil || refPath == "" || path.IsAbs(refPath) || filepath.IsAbs(filepath.FromSlash(refPath)) {
+		return ref
+	}
+	joined := referrer.Join(RefFile{Path: refPath, Frag: ref.Fragment})
+	joined.Scheme = ref.Scheme
+	joined.RawQuery = ref.RawQuery
+	return joined
 }
 
 // URLSchemeLoader delegates to other [Loader] implementations
@@ -476,6 +476,7 @@ func TestHTTPLoader_Cache(t *testing.T) {
 		require.Len(t, cache.Map, 1)
 		cached := cache.Map[server.URL]
 		want := CachedResponse{
+			URL:      server.URL,
 			MaxAge:   100 * time.Second,
 			CachedAt: now,
 			Data:     []byte("{}"),
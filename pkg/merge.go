@@ -0,0 +1,226 @@
+package pkg
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Strategies for merging the types of the schemas generated from multiple
+// values files, as set by the "mergeStrategy" config.
+const (
+	// MergeStrategyOverride uses the type from the last values file, and is
+	// the default.
+	MergeStrategyOverride = "override"
+	// MergeStrategyUnion uses the union of the types from all values files,
+	// such as ["integer", "null"], where "integer" is widened to "number" when
+	// both are present.
+	MergeStrategyUnion = "union"
+	// MergeStrategyStrict fails when the values files have different types at
+	// the same location, unless one type allows all of the other, such as
+	// ["integer", "null"] and "integer", in which case the wider type is used.
+	MergeStrategyStrict = "strict"
+)
+
+// MergeStrategies lists all supported merge strategies.
+var MergeStrategies = []string{MergeStrategyOverride, MergeStrategyUnion, MergeStrategyStrict}
+
+// validateMergeStrategy returns an error if strategy is not one of
+// [MergeStrategies]. An empty strategy is the same as [MergeStrategyOverride].
+func validateMergeStrategy(strategy string) error {
+	if strategy == "" || slices.Contains(MergeStrategies, strategy) {
+		return nil
+	}
+	return fmt.Errorf("invalid mergeStrategy %q, must be one of: %s", strategy, strings.Join(MergeStrategies, ", "))
+}
+
+// MergeConflict is a location where two values files have different types,
+// as reported when using [MergeStrategyStrict].
+type MergeConflict struct {
+	Ptr Ptr
+	// Type and Files are the type from the previous values files, and the
+	// files it was found in.
+	Type  any
+	Files []string
+	// OtherType and OtherFile are the conflicting type, and the values file
+	// it was found in.
+	OtherType any
+	OtherFile string
+}
+
+// String implements [fmt.Stringer].
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s: type %s in %s, but %s in %s", c.Ptr, formatJSON(c.Type), strings.Join(c.Files, ", "), formatJSON(c.OtherType), c.OtherFile)
+}
+
+// MergeConflictError is returned when the values files have conflicting
+// types when using [MergeStrategyStrict].
+type MergeConflictError struct {
+	Conflicts []MergeConflict
+}
+
+// Error implements [error].
+func (e *MergeConflictError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "found %d conflicting type(s) between values files using mergeStrategy %q:", len(e.Conflicts), MergeStrategyStrict)
+	for _, conflict := range e.Conflicts {
+		sb.WriteString("\n  ")
+		sb.WriteString(conflict.String())
+	}
+	return sb.String()
+}
+
+// typeMerger merges the types of the schemas generated from each values file
+// using the configured strategy, before the rest of the schemas are merged
+// using [mergeSchemas], where the type of the last file otherwise wins.
+type typeMerger struct {
+	strategy string
+	// sources holds the values files that each type was found in,
+	// by JSON pointer.
+	sources   map[string][]string
+	conflicts []MergeConflict
+}
+
+func newTypeMerger(strategy string) *typeMerger {
+	return &typeMerger{
+		strategy: cmp.Or(strategy, MergeStrategyOverride),
+		sources:  map[string][]string{},
+	}
+}
+
+// merge updates the types in src, which is generated from the given values
+// file, before it is merged into dest using [mergeSchemas].
+func (m *typeMerger) merge(ptr Ptr, dest, src *Schema, file string) {
+	if src == nil || src.Kind() != SchemaKindObject {
+		return
+	}
+	if dest != nil && dest.Kind() != SchemaKindObject {
+		dest = nil
+	}
+
+	key := ptr.String()
+	srcTypes := schemaTypes(src.Type)
+	switch {
+	case len(srcTypes) == 0:
+		// Keeps the type from dest.
+	case dest == nil || len(schemaTypes(dest.Type)) == 0:
+		m.sources[key] = []string{file}
+	case slices.Equal(schemaTypes(dest.Type), srcTypes):
+		m.sources[key] = append(m.sources[key], file)
+	default:
+		switch m.strategy {
+		case MergeStrategyUnion:
+			src.Type = unionTypes(schemaTypes(dest.Type), srcTypes)
+			m.sources[key] = append(m.sources[key], file)
+		case MergeStrategyStrict:
+			switch destTypes := schemaTypes(dest.Type); {
+			case typesSubset(srcTypes, destTypes):
+				// Such as "integer" and ["integer", "null"], where the wider type is kept
+				src.Type = dest.Type
+				m.sources[key] = append(m.sources[key], file)
+			case typesSubset(destTypes, srcTypes):
+				m.sources[key] = append(m.sources[key], file)
+			default:
+				m.conflicts = append(m.conflicts, MergeConflict{
+					Ptr:       ptr,
+					Type:      dest.Type,
+					Files:     slices.Clone(m.sources[key]),
+					OtherType: src.Type,
+					OtherFile: file,
+				})
+			}
+		default:
+			m.sources[key] = []string{file}
+		}
+	}
+
+	var destItems, destAdditionalProperties *Schema
+	var destPrefixItems []*Schema
+	var destProperties, destPatternProperties map[string]*Schema
+	if dest != nil {
+		destItems = dest.Items
+		if dest.ItemsMode == ItemsModeTuple {
+			destPrefixItems = dest.PrefixItems
+		}
+		destAdditionalProperties = dest.AdditionalProperties
+		destProperties = dest.Properties
+		destPatternProperties = dest.PatternProperties
+	}
+	// The distinct item schemas are kept as-is with union items, while tuple
+	// items are merged by position
+	if src.ItemsMode != ItemsModeUnion {
+		m.merge(ptr.Prop("items"), destItems, src.Items, file)
+	}
+	if src.ItemsMode == ItemsModeTuple {
+		for i, item := range src.PrefixItems {
+			var destItem *Schema
+			if i < len(destPrefixItems) {
+				destItem = destPrefixItems[i]
+			}
+			m.merge(ptr.Prop("prefixItems").Item(i), destItem, item, file)
+		}
+	}
+	m.merge(ptr.Prop("additionalProperties"), destAdditionalProperties, src.AdditionalProperties, file)
+	for name, prop := range iterMapOrdered(src.Properties) {
+		m.merge(ptr.Prop("properties", name), destProperties[name], prop, file)
+	}
+	for pattern, prop := range iterMapOrdered(src.PatternProperties) {
+		m.merge(ptr.Prop("patternProperties", pattern), destPatternProperties[pattern], prop, file)
+	}
+}
+
+// err returns a [*MergeConflictError] when any conflicts were found.
+func (m *typeMerger) err() error {
+	if len(m.conflicts) == 0 {
+		return nil
+	}
+	return &MergeConflictError{Conflicts: m.conflicts}
+}
+
+// schemaTypes returns the sorted list of types from the "type" keyword,
+// which is either a string or a list of strings.
+func schemaTypes(schemaType any) []string {
+	var types []string
+	switch schemaType := schemaType.(type) {
+	case string:
+		types = append(types, schemaType)
+	case []any:
+		for _, t := range schemaType {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	slices.Sort(types)
+	return slices.Compact(types)
+}
+
+// typesSubset returns true if all types in a are allowed by the types in b,
+// where "integer" is also allowed by "number".
+func typesSubset(a, b []string) bool {
+	for _, t := range a {
+		if !slices.Contains(b, t) && (t != "integer" || !slices.Contains(b, "number")) {
+			return false
+		}
+	}
+	return true
+}
+
+// unionTypes returns the union of the types, as a single string when there is
+// only one type. The "integer" type is widened to "number" when both are
+// present, as "number" already allows all integers.
+func unionTypes(a, b []string) any {
+	types := slices.Compact(slices.Sorted(slices.Values(slices.Concat(a, b))))
+	if slices.Contains(types, "number") {
+		types = slices.DeleteFunc(types, func(t string) bool { return t == "integer" })
+	}
+	if len(types) == 1 {
+		return types[0]
+	}
+	list := make([]any, len(types))
+	for i, t := range types {
+		list[i] = t
+	}
+	return list
+}
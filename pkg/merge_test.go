@@ -0,0 +1,219 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMergeStrategy(t *testing.T) {
+	for _, strategy := range append([]string{""}, MergeStrategies...) {
+		assert.NoError(t, validateMergeStrategy(strategy), strategy)
+	}
+	assert.EqualError(t, validateMergeStrategy("merge"), `invalid mergeStrategy "merge", must be one of: override, union, strict`)
+}
+
+func TestBuildJSONSchema_MergeStrategy(t *testing.T) {
+	values := []string{"../testdata/merge/values.yaml", "../testdata/merge/values-autoscale.yaml"}
+
+	tests := []struct {
+		name     string
+		strategy string
+		want     map[string]any
+		wantErr  string
+	}{
+		{
+			name:     "default overrides",
+			strategy: "",
+			want: map[string]any{
+				"replicas": "null",
+				"ratio":    "number",
+				"tag":      "number",
+				"ports":    "string",
+			},
+		},
+		{
+			name:     "override",
+			strategy: MergeStrategyOverride,
+			want: map[string]any{
+				"replicas": "null",
+				"ratio":    "number",
+				"tag":      "number",
+				"ports":    "string",
+			},
+		},
+		{
+			name:     "union",
+			strategy: MergeStrategyUnion,
+			want: map[string]any{
+				"replicas": []any{"integer", "null"},
+				"ratio":    "number",
+				"tag":      []any{"number", "string"},
+				"ports":    []any{"integer", "string"},
+			},
+		},
+		{
+			name:     "strict",
+			strategy: MergeStrategyStrict,
+			wantErr: `found 3 conflicting type(s) between values files using mergeStrategy "strict":
+  /properties/image/properties/tag: type "string" in ../testdata/merge/values.yaml, but "number" in ../testdata/merge/values-autoscale.yaml
+  /properties/ports/items: type "integer" in ../testdata/merge/values.yaml, but "string" in ../testdata/merge/values-autoscale.yaml
+  /properties/replicas: type "integer" in ../testdata/merge/values.yaml, but "null" in ../testdata/merge/values-autoscale.yaml`,
+		},
+		{
+			name:     "invalid",
+			strategy: "merge",
+			wantErr:  `invalid mergeStrategy "merge"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Values: values, Draft: 2020, Indent: 4, MergeStrategy: tt.strategy}
+			schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), config)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, map[string]any{
+				"replicas": schema.Properties["replicas"].Type,
+				"ratio":    schema.Properties["ratio"].Type,
+				"tag":      schema.Properties["image"].Properties["tag"].Type,
+				"ports":    schema.Properties["ports"].Items.Type,
+			})
+		})
+	}
+}
+
+func TestTypeMerger(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      string
+		dest          *Schema
+		src           *Schema
+		wantType      any
+		wantConflicts []MergeConflict
+	}{
+		{
+			name:     "src without type keeps dest",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{Type: "integer"},
+			src:      &Schema{},
+			wantType: nil,
+		},
+		{
+			name:     "dest without type",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{},
+			src:      &Schema{Type: "integer"},
+			wantType: "integer",
+		},
+		{
+			name:     "same type list in other order",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{Type: []any{"null", "string"}},
+			src:      &Schema{Type: []any{"string", "null"}},
+			wantType: []any{"string", "null"},
+		},
+		{
+			name:     "strict src type in dest types",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{Type: []any{"integer", "null"}},
+			src:      &Schema{Type: "integer"},
+			wantType: []any{"integer", "null"},
+		},
+		{
+			name:     "strict dest type in src types",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{Type: "integer"},
+			src:      &Schema{Type: []any{"integer", "null"}},
+			wantType: []any{"integer", "null"},
+		},
+		{
+			name:     "strict integer in number",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{Type: "number"},
+			src:      &Schema{Type: "integer"},
+			wantType: "number",
+		},
+		{
+			name:     "union widens integer to number",
+			strategy: MergeStrategyUnion,
+			dest:     &Schema{Type: []any{"integer", "null"}},
+			src:      &Schema{Type: "number"},
+			wantType: []any{"null", "number"},
+		},
+		{
+			name:     "union single type",
+			strategy: MergeStrategyUnion,
+			dest:     &Schema{Type: "number"},
+			src:      &Schema{Type: "integer"},
+			wantType: "number",
+		},
+		{
+			name:     "union nested in additionalProperties and patternProperties",
+			strategy: MergeStrategyUnion,
+			dest: &Schema{
+				AdditionalProperties: &Schema{Type: "string"},
+				PatternProperties:    map[string]*Schema{"^a": {Type: "string"}},
+			},
+			src: &Schema{
+				AdditionalProperties: &Schema{Type: "boolean"},
+				PatternProperties:    map[string]*Schema{"^a": {Type: "boolean"}},
+			},
+		},
+		{
+			name:     "bool schemas are skipped",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{Items: SchemaFalse()},
+			src:      &Schema{Items: &Schema{Type: "string"}},
+		},
+		{
+			name:     "strict",
+			strategy: MergeStrategyStrict,
+			dest:     &Schema{Type: "object", Properties: map[string]*Schema{"a": {Type: "string"}}},
+			src:      &Schema{Type: "object", Properties: map[string]*Schema{"a": {Type: []any{"integer", "null"}}}},
+			wantType: "object",
+			wantConflicts: []MergeConflict{
+				{Ptr: NewPtr("properties", "a"), Type: "string", Files: []string{"a.yaml"}, OtherType: []any{"integer", "null"}, OtherFile: "b.yaml"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merger := newTypeMerger(tt.strategy)
+			merger.merge(nil, &Schema{}, tt.dest, "a.yaml")
+			merger.merge(nil, tt.dest, tt.src, "b.yaml")
+			assert.Equal(t, tt.wantType, tt.src.Type)
+			assert.Equal(t, tt.wantConflicts, merger.conflicts)
+			if tt.wantConflicts == nil {
+				assert.NoError(t, merger.err())
+			} else {
+				assert.ErrorAs(t, merger.err(), new(*MergeConflictError))
+			}
+		})
+	}
+}
+
+func TestTypesSubset(t *testing.T) {
+	assert.True(t, typesSubset([]string{"integer"}, []string{"integer", "null"}))
+	assert.True(t, typesSubset([]string{"integer", "null"}, []string{"null", "number"}))
+	assert.False(t, typesSubset([]string{"number"}, []string{"integer"}))
+	assert.False(t, typesSubset([]string{"integer", "null"}, []string{"integer"}))
+}
+
+func TestUnionTypes(t *testing.T) {
+	assert.Equal(t, "string", unionTypes([]string{"string"}, []string{"string"}))
+	assert.Equal(t, []any{"integer", "null"}, unionTypes([]string{"null"}, []string{"integer"}))
+	assert.Equal(t, []any{"null", "number"}, unionTypes([]string{"integer", "null"}, []string{"number"}))
+}
+
+func TestSchemaTypes(t *testing.T) {
+	assert.Nil(t, schemaTypes(nil))
+	assert.Equal(t, []string{"string"}, schemaTypes("string"))
+	assert.Equal(t, []string{"null", "string"}, schemaTypes([]any{"string", "null", "string", 1}))
+}
@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// MetaschemaViolation is a single location in a schema that does not conform
+// to the metaschema of its draft.
+type MetaschemaViolation struct {
+	Ptr     Ptr
+	Message string
+}
+
+// String implements [fmt.Stringer].
+func (v MetaschemaViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Ptr, v.Message)
+}
+
+// ValidateMetaschema validates the schema against the metaschema of the given
+// draft, or the draft in the schema's "$schema" keyword when set, and returns
+// all violations sorted by their JSON pointer.
+//
+// The metaschemas of all supported drafts are embedded, so no network
+// requests are made. The metaschemas allow unknown keywords, which [Schema]
+// drops anyway, so the [Schema.UnknownKeywords] of "# @schema" annotations,
+// such as a misspelled keyword, are reported as violations as well.
+func ValidateMetaschema(schema *Schema, draft int) ([]MetaschemaViolation, error) {
+	metaschemaURL := schema.Schema
+	if metaschemaURL == "" {
+		var err error
+		metaschemaURL, err = getSchemaURL(draft)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	metaschema, err := jsonschema.NewCompiler().Compile(metaschemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("load metaschema %q: %w", metaschemaURL, err)
+	}
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("marshal schema: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal schema: %w", err)
+	}
+
+	var violations []MetaschemaViolation
+	collectUnknownKeywordViolations(nil, schema, &violations)
+
+	var validationErr *jsonschema.ValidationError
+	if err := metaschema.Validate(instance); errors.As(err, &validationErr) {
+		collectMetaschemaViolations(validationErr, &violations)
+	} else if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(violations, func(a, b MetaschemaViolation) int {
+		return cmp.Or(
+			cmp.Compare(a.Ptr.String(), b.Ptr.String()),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+	return slices.CompactFunc(violations, func(a, b MetaschemaViolation) bool {
+		return a.Ptr.Equals(b.Ptr) && a.Message == b.Message
+	}), nil
+}
+
+func collectUnknownKeywordViolations(ptr Ptr, schema *Schema, violations *[]MetaschemaViolation) {
+	for _, keywordPtr := range schema.UnknownKeywords {
+		*violations = append(*violations, MetaschemaViolation{
+			Ptr:     ptr.Add(keywordPtr),
+			Message: fmt.Sprintf("unknown keyword %q", keywordPtr[len(keywordPtr)-1]),
+		})
+	}
+	for subPtr, subSchema := range schema.Subschemas() {
+		collectUnknownKeywordViolations(ptr.Add(subPtr), subSchema, violations)
+	}
+}
+
+func collectMetaschemaViolations(err *jsonschema.ValidationError, violations *[]MetaschemaViolation) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collectMetaschemaViolations(cause, violations)
+		}
+		return
+	}
+	// The error message is prefixed with "at '/some/path': ", which is
+	// redundant as we report the pointer separately.
+	message := err.Error()
+	if _, after, ok := strings.Cut(message, "': "); ok && strings.HasPrefix(message, "at '") {
+		message = after
+	}
+	*violations = append(*violations, MetaschemaViolation{
+		Ptr:     NewPtr(err.InstanceLocation...),
+		Message: message,
+	})
+}
+
+// MetaschemaError is returned when a schema does not conform to the
+// metaschema of its draft.
+type MetaschemaError struct {
+	Violations []MetaschemaViolation
+}
+
+// Error implements [error].
+func (e *MetaschemaError) Error() string {
+	return fmt.Sprintf("schema does not conform to its metaschema: found %d violation(s)", len(e.Violations))
+}
+
+// checkMetaschema validates the schema using [ValidateMetaschema], and
+// returns a [*MetaschemaError] if there were any violations.
+func checkMetaschema(schema *Schema, draft int) error {
+	violations, err := ValidateMetaschema(schema, draft)
+	if err != nil {
+		return fmt.Errorf("validate metaschema: %w", err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &MetaschemaError{Violations: violations}
+}
+
+// validateMetaschemaAndLog validates the schema using [checkMetaschema],
+// logs each violation, and returns an error if there were any.
+func validateMetaschemaAndLog(ctx context.Context, schema *Schema, draft int) error {
+	err := checkMetaschema(schema, draft)
+	var metaschemaErr *MetaschemaError
+	if errors.As(err, &metaschemaErr) {
+		logger := LoggerFromContext(ctx)
+		for _, violation := range metaschemaErr.Violations {
+			logger.Logf("error: %s", violation)
+		}
+	}
+	return err
+}
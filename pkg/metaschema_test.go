@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMetaschema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *Schema
+		draft  int
+		want   []MetaschemaViolation
+	}{
+		{
+			name:   "empty schema",
+			schema: &Schema{},
+			draft:  2020,
+		},
+		{
+			name: "valid schema",
+			schema: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"foo": {Type: "string", Required: []string{"bar"}},
+				},
+			},
+			draft: 2020,
+		},
+		{
+			name: "duplicate required",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo/bar": {Required: []string{"a", "a"}},
+				},
+			},
+			draft: 2020,
+			want: []MetaschemaViolation{
+				{Ptr: NewPtr("properties", "foo/bar", "required"), Message: "items at 0 and 1 are equal"},
+			},
+		},
+		{
+			name: "sorted by pointer",
+			schema: &Schema{
+				AllOf: []*Schema{
+					{Type: []any{"string", "string"}},
+					{Type: "strng"},
+				},
+			},
+			draft: 7,
+			want: []MetaschemaViolation{
+				{Ptr: NewPtr("allOf").Item(0).Prop("type"), Message: "items at 0 and 1 are equal"},
+				{Ptr: NewPtr("allOf").Item(0).Prop("type"), Message: "value must be one of 'array', 'boolean', 'integer', 'null', 'number', 'object', 'string'"},
+				{Ptr: NewPtr("allOf").Item(1).Prop("type"), Message: "got string, want array"},
+				{Ptr: NewPtr("allOf").Item(1).Prop("type"), Message: "value must be one of 'array', 'boolean', 'integer', 'null', 'number', 'object', 'string'"},
+			},
+		},
+		{
+			name: "unknown keywords of annotations",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {
+						AllOf:           []*Schema{{}},
+						UnknownKeywords: []Ptr{NewPtr("allOf").Item(0).Prop("tpye")},
+					},
+				},
+			},
+			draft: 2020,
+			want: []MetaschemaViolation{
+				{Ptr: NewPtr("properties", "foo", "allOf").Item(0).Prop("tpye"), Message: `unknown keyword "tpye"`},
+			},
+		},
+		{
+			name: "uses draft from $schema",
+			schema: &Schema{
+				Schema:   "http://json-schema.org/draft-04/schema#",
+				Required: []string{"a", "a"},
+			},
+			draft: 5, // ignored, as the draft is taken from $schema
+			want: []MetaschemaViolation{
+				{Ptr: NewPtr("required"), Message: "items at 0 and 1 are equal"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateMetaschema(tt.schema, tt.draft)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateMetaschema_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  *Schema
+		draft   int
+		wantErr string
+	}{
+		{
+			name:    "invalid draft",
+			schema:  &Schema{},
+			draft:   5,
+			wantErr: "invalid draft version. Please use one of: 4, 6, 7, 2019, 2020",
+		},
+		{
+			name:    "unknown metaschema",
+			schema:  &Schema{Schema: "http://localhost:0/schema"},
+			draft:   2020,
+			wantErr: `load metaschema "http://localhost:0/schema": `,
+		},
+		{
+			name:    "marshal error",
+			schema:  &Schema{Default: math.NaN()},
+			draft:   2020,
+			wantErr: "marshal schema: json: error calling MarshalJSON for type *pkg.Schema: json: unsupported value: NaN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateMetaschema(tt.schema, tt.draft)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestValidateMetaschemaAndLog(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := ContextWithLogger(t.Context(), NewLogger(&buf))
+	err := validateMetaschemaAndLog(ctx, &Schema{Required: []string{"a", "a"}}, 2020)
+	require.EqualError(t, err, "schema does not conform to its metaschema: found 1 violation(s)")
+	assert.Equal(t, "error: /required: items at 0 and 1 are equal\n", buf.String())
+
+	err = validateMetaschemaAndLog(ctx, &Schema{}, 5)
+	require.EqualError(t, err, "validate metaschema: invalid draft version. Please use one of: 4, 6, 7, 2019, 2020")
+}
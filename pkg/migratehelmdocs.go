@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// migrateHelmDocs returns the lines to insert into the content of a values
+// file, so that the helm-docs type, "@default" and "@section" of each key
+// get the equivalent "# @schema" annotations, which are then used also
+// without the "useHelmDocs" config. The helm-docs comments are left as-is,
+// as helm-docs still reads them.
+//
+// Annotations that a key already has are not added again, and a helm-docs
+// type that matches the type inferred from the value is left out, as is an
+// "@default" that isn't a literal value of the key's type.
+func migrateHelmDocs(ctx context.Context, content []byte) (valuesInsertions, error) {
+	docs, err := decodeYAMLDocuments(content)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling YAML: %w", err)
+	}
+
+	m := helmDocsMigrator{
+		ctx: ctx,
+		annotator: valuesAnnotator{
+			lines:      strings.Split(string(content), "\n"),
+			insertions: valuesInsertions{},
+		},
+	}
+	for _, doc := range docs {
+		if err := m.node(nil, doc.Content[0]); err != nil {
+			return nil, err
+		}
+	}
+	return m.annotator.insertions, nil
+}
+
+// helmDocsMigrator collects the insertions of [migrateHelmDocs].
+type helmDocsMigrator struct {
+	ctx       context.Context
+	annotator valuesAnnotator
+}
+
+// node migrates the keys of the node and its nested mappings and sequences.
+func (m helmDocsMigrator) node(ptr Ptr, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valNode := node.Content[i], node.Content[i+1]
+			if isMergeKey(keyNode) {
+				continue
+			}
+			propPtr := ptr.Prop(keyNode.Value)
+			if err := m.key(propPtr, keyNode, valNode); err != nil {
+				return err
+			}
+			if err := m.node(propPtr, valNode); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, itemNode := range node.Content {
+			if err := m.node(ptr.Item(i), itemNode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// key adds the annotations of the helm-docs comment above the key.
+func (m helmDocsMigrator) key(ptr Ptr, keyNode, valNode *yaml.Node) error {
+	comments, helmDocsComments := getComments(keyNode, valNode, true)
+	helmDocs, err := ParseHelmDocsComment(helmDocsComments)
+	if err != nil {
+		return newNodeError(ptr, keyNode, valNode, fmt.Errorf("parse helm-docs comment: %w", err))
+	}
+	if len(helmDocs.Path) > 0 && !ptr.Equals(NewPtr(helmDocs.Path...)) {
+		return nil
+	}
+
+	existing := map[string]bool{}
+	for name := range splitCommentsByParts(comments) {
+		existing[name] = true
+	}
+	if existing["hidden"] {
+		return nil
+	}
+
+	logger := LoggerFromContext(m.ctx)
+	var annotations []string
+	add := func(name, value string) {
+		if existing[name] {
+			return
+		}
+		if strings.ContainsAny(value, ";\n") {
+			logger.Logf("Warning: %s: skipping %q annotation, as ';' and newlines are not supported in # @schema comments", ptr, name)
+			return
+		}
+		annotations = append(annotations, name+": "+value)
+	}
+
+	valueType := inferredNodeType(resolveAlias(valNode))
+	var annotated Schema
+	_ = processComment(&annotated, comments)
+	schemaType := cmp.Or(annotated.Type, any(valueType))
+
+	if helmDocs.Type != "" {
+		if helmDocs.SchemaType() == "" {
+			logger.Logf("Warning: %s: skipping helm-docs type %q, as it has no JSON schema type", ptr, helmDocs.Type)
+		} else if typ := helmDocsType(helmDocs, valueType); typ != valueType {
+			add("type", typeAnnotation(typ))
+			if annotated.Type == nil {
+				schemaType = typ
+			}
+		}
+	}
+	if def, ok := helmDocs.SchemaDefault(); ok && valueMatchesAnyType(schemaType, def) {
+		b, err := marshalCompactJSON(def)
+		if err != nil {
+			return newNodeError(ptr, keyNode, valNode, fmt.Errorf("helm-docs @default: %w", err))
+		}
+		add("default", string(b))
+	}
+	if helmDocs.Section != "" {
+		add("x-section", helmDocs.Section)
+	}
+
+	if len(annotations) > 0 {
+		m.annotator.insert(keyNode, "# @schema "+strings.Join(annotations, "; "))
+	}
+	return nil
+}
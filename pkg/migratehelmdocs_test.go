@@ -0,0 +1,206 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateHelmDocs(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "type, default and section",
+			values: `# Some comment
+# -- (int) Number of replicas
+# @default -- ` + "`3`" + `
+# @section -- General
+replicaCount: null
+`,
+			want: `# Some comment
+# @schema type: [integer, null]; default: 3; x-section: General
+# -- (int) Number of replicas
+# @default -- ` + "`3`" + `
+# @section -- General
+replicaCount: null
+`,
+		},
+		{
+			name: "type matching the value is left out",
+			values: `# -- (string) Image tag
+tag: latest
+# -- (list) Tolerations
+tolerations: []
+`,
+			want: `# -- (string) Image tag
+tag: latest
+# -- (list) Tolerations
+tolerations: []
+`,
+		},
+		{
+			name: "template type",
+			values: `# -- (tpl/array) Extra env
+extraEnv: []
+`,
+			want: `# @schema type: string
+# -- (tpl/array) Extra env
+extraEnv: []
+`,
+		},
+		{
+			name: "custom type is skipped",
+			values: `# -- (myType) Custom
+custom: {}
+`,
+			want: `# -- (myType) Custom
+custom: {}
+`,
+		},
+		{
+			name: "free-form default is skipped",
+			values: `image:
+  # -- Image tag
+  # @default -- the chart appVersion
+  tag: ""
+`,
+			want: `image:
+  # -- Image tag
+  # @default -- the chart appVersion
+  tag: ""
+`,
+		},
+		{
+			name: "default of another type is skipped",
+			values: `# -- Port
+# @default -- "8080"
+port: 80
+# -- Tag
+# @default -- ` + "`latest`" + `
+tag: null # @schema type: [string, null]
+`,
+			want: `# -- Port
+# @default -- "8080"
+port: 80
+# @schema default: "latest"
+# -- Tag
+# @default -- ` + "`latest`" + `
+tag: null # @schema type: [string, null]
+`,
+		},
+		{
+			name: "default with semicolon is skipped",
+			values: `# -- Command
+# @default -- "a; b"
+command: ""
+`,
+			want: `# -- Command
+# @default -- "a; b"
+command: ""
+`,
+		},
+		{
+			name: "existing annotations are not added again",
+			values: `# @schema type: [integer, null]
+# -- (int) Number of replicas
+# @section -- General
+replicaCount: null # @schema x-section: General
+`,
+			want: `# @schema type: [integer, null]
+# -- (int) Number of replicas
+# @section -- General
+replicaCount: null # @schema x-section: General
+`,
+		},
+		{
+			name: "hidden keys are skipped",
+			values: `# @schema hidden
+# -- (int) Hidden
+hidden: null
+`,
+			want: `# @schema hidden
+# -- (int) Hidden
+hidden: null
+`,
+		},
+		{
+			name: "path",
+			values: `labels:
+  # labels.app -- (string) App name
+  # @section -- Labels
+  app: null
+  # other -- (int) Other
+  other: null
+`,
+			want: `labels:
+  # @schema type: [string, null]; x-section: Labels
+  # labels.app -- (string) App name
+  # @section -- Labels
+  app: null
+  # other -- (int) Other
+  other: null
+`,
+		},
+		{
+			name: "nested in sequences",
+			values: `list:
+  - name: foo
+    # -- (int) Port
+    port: null
+`,
+			want: `list:
+  - name: foo
+    # @schema type: [integer, null]
+    # -- (int) Port
+    port: null
+`,
+		},
+		{
+			name: "multiple documents",
+			values: `# -- (int) A
+a: null
+---
+# -- (int) A
+a: null
+`,
+			want: `# @schema type: [integer, null]
+# -- (int) A
+a: null
+---
+# @schema type: [integer, null]
+# -- (int) A
+a: null
+`,
+		},
+		{
+			name:    "invalid YAML",
+			values:  "a: [",
+			wantErr: "error unmarshalling YAML",
+		},
+		{
+			name: "schema comment in helm-docs comment",
+			values: `# -- A
+# @schema type: integer
+a: null
+`,
+			wantErr: "/a: parse helm-docs comment: '# @schema' comments are not supported in helm-docs comments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insertions, err := migrateHelmDocs(ContextWithLogger(t.Context(), t), []byte(tt.values))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(insertions.apply([]byte(tt.values))))
+		})
+	}
+}
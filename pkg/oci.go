@@ -0,0 +1,435 @@
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ociScheme is the URL scheme used in "$ref: oci://registry/repository:tag" references.
+const ociScheme = "oci"
+
+// Media types and annotations of Helm charts stored as OCI artifacts.
+// See https://helm.sh/docs/topics/registries/#helm-chart-manifest
+const (
+	ociManifestMediaType       = "application/vnd.oci.image.manifest.v1+json"
+	ociHelmChartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	ociRefNameAnnotation       = "org.opencontainers.image.ref.name"
+)
+
+// ociImmutableCacheControl is used when caching blobs and manifests referenced
+// by digest, as their content never changes.
+const ociImmutableCacheControl = "max-age=31536000, immutable"
+
+var ociDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// OCILoader loads the "values.schema.json" file from a Helm chart stored as
+// an OCI artifact, either from a registry using
+// "$ref: oci://registry/repository:tag" (or "@sha256:..." digest),
+// or from a local OCI image layout directory using
+// "$ref: oci:path/to/layout?tag=1.0.0".
+//
+// Registries are accessed over HTTPS, and only support anonymous access.
+// Downloaded blobs, and manifests referenced by digest, are stored in the
+// [HTTPCache], as their content never changes.
+type OCILoader struct {
+	client     *http.Client
+	cache      HTTPCache
+	fileLoader FileLoader
+
+	SizeLimit int64
+	UserAgent string
+}
+
+// NewOCILoader returns a new OCI loader, which reads local OCI image layout
+// directories using the given [FileLoader].
+func NewOCILoader(client *http.Client, cache HTTPCache, fileLoader FileLoader) OCILoader {
+	return OCILoader{
+		client:     client,
+		cache:      cache,
+		fileLoader: fileLoader,
+		SizeLimit:  200 * 1000 * 1000, // same arbitrary limit as in [NewHTTPLoader]
+		UserAgent:  HTTPLoaderDefaultUserAgent,
+	}
+}
+
+var _ Loader = OCILoader{}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest is either an image manifest or an image index.
+type ociManifest struct {
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// Load implements [Loader].
+func (loader OCILoader) Load(ctx context.Context, ref *url.URL) (*Schema, error) {
+	if ref.Scheme != ociScheme {
+		return nil, fmt.Errorf(`oci url in $ref=%q must start with "oci:"`, ref.Redacted())
+	}
+	if ref.User != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: user info not supported", ref.Redacted())
+	}
+
+	var archive []byte
+	var err error
+	if ref.Host == "" {
+		archive, err = loader.loadLayout(ctx, ref)
+	} else {
+		archive, err = loader.loadRegistry(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := loadChartSchema(archive)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: %w", ref.Redacted(), err)
+	}
+	return schema, nil
+}
+
+func (loader OCILoader) loadLayout(ctx context.Context, ref *url.URL) ([]byte, error) {
+	layoutPath, _ := localSchemeRefPath(ref)
+	if layoutPath == "" {
+		return nil, fmt.Errorf("oci url in $ref=%q must contain a path or a host", ref)
+	}
+	query := ref.Query()
+	for key := range query {
+		if key != "tag" {
+			return nil, fmt.Errorf("oci url in $ref=%q: unsupported query parameter %q", ref, key)
+		}
+	}
+	layoutDir := filepath.FromSlash(layoutPath)
+
+	_, b, err := loader.fileLoader.readFile(ctx, filepath.Join(layoutDir, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+	var index ociManifest
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: parse index.json: %w", ref, err)
+	}
+	desc, err := findOCIManifest(index.Manifests, query.Get("tag"))
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: %w", ref, err)
+	}
+
+	b, err = loader.readLayoutBlob(ctx, layoutDir, desc.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: read manifest: %w", ref, err)
+	}
+	layer, err := findOCIChartLayer(b)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: %w", ref, err)
+	}
+	b, err = loader.readLayoutBlob(ctx, layoutDir, layer.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: read chart layer: %w", ref, err)
+	}
+	return b, nil
+}
+
+func (loader OCILoader) readLayoutBlob(ctx context.Context, layoutDir, digest string) ([]byte, error) {
+	if !ociDigestRegexp.MatchString(digest) {
+		return nil, fmt.Errorf("unsupported digest %q", digest)
+	}
+	algorithm, encoded, _ := strings.Cut(digest, ":")
+	_, b, err := loader.fileLoader.readFile(ctx, filepath.Join(layoutDir, "blobs", algorithm, encoded))
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyOCIDigest(digest, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// findOCIManifest returns the manifest with the given tag, or the only
+// manifest when the tag is empty.
+func findOCIManifest(manifests []ociDescriptor, tag string) (ociDescriptor, error) {
+	if len(manifests) == 0 {
+		return ociDescriptor{}, fmt.Errorf("no manifests found")
+	}
+	var tags []string
+	for _, desc := range manifests {
+		if desc.Annotations[ociRefNameAnnotation] == tag || (tag == "" && len(manifests) == 1) {
+			return desc, nil
+		}
+		tags = append(tags, desc.Annotations[ociRefNameAnnotation])
+	}
+	if tag == "" {
+		return ociDescriptor{}, fmt.Errorf(`found multiple manifests, use "?tag=" to select one of: %s`, strings.Join(tags, ", "))
+	}
+	return ociDescriptor{}, fmt.Errorf("no manifest tagged %q, found: %s", tag, strings.Join(tags, ", "))
+}
+
+// findOCIChartLayer parses an OCI image manifest and returns its Helm chart layer.
+func findOCIChartLayer(manifestBytes []byte) (ociDescriptor, error) {
+	var manifest ociManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return ociDescriptor{}, fmt.Errorf("parse manifest: %w", err)
+	}
+	i := slices.IndexFunc(manifest.Layers, func(layer ociDescriptor) bool {
+		return layer.MediaType == ociHelmChartLayerMediaType
+	})
+	if i == -1 {
+		return ociDescriptor{}, fmt.Errorf("manifest has no layer with media type %q, is it a Helm chart?", ociHelmChartLayerMediaType)
+	}
+	return manifest.Layers[i], nil
+}
+
+func verifyOCIDigest(digest string, b []byte) error {
+	sum := sha256.Sum256(b)
+	if got := "sha256:" + hex.EncodeToString(sum[:]); got != digest {
+		return fmt.Errorf("digest mismatch: expected %s, got %s", digest, got)
+	}
+	return nil
+}
+
+// parseOCIReference splits the path of a "oci://registry/repository:tag"
+// or "oci://registry/repository@sha256:..." URL into its repository and its
+// tag or digest.
+func parseOCIReference(ref *url.URL) (repository, reference string, isDigest bool, err error) {
+	if ref.RawQuery != "" {
+		return "", "", false, fmt.Errorf("query parameters not supported")
+	}
+	urlPath := strings.TrimPrefix(ref.Path, "/")
+	if repository, digest, ok := strings.Cut(urlPath, "@"); ok {
+		if !ociDigestRegexp.MatchString(digest) {
+			return "", "", false, fmt.Errorf("unsupported digest %q", digest)
+		}
+		if repository == "" {
+			return "", "", false, fmt.Errorf("must contain a repository")
+		}
+		return repository, digest, true, nil
+	}
+	i := strings.LastIndex(urlPath, ":")
+	if i == -1 || i < strings.LastIndex(urlPath, "/") {
+		return "", "", false, fmt.Errorf("must contain a tag or digest, e.g oci://registry/repository:1.0.0")
+	}
+	if urlPath[:i] == "" || urlPath[i+1:] == "" {
+		return "", "", false, fmt.Errorf("must contain a repository and a tag")
+	}
+	return urlPath[:i], urlPath[i+1:], false, nil
+}
+
+func (loader OCILoader) loadRegistry(ctx context.Context, ref *url.URL) ([]byte, error) {
+	repository, reference, isDigest, err := parseOCIReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: %w", ref.Redacted(), err)
+	}
+
+	session := ociRegistrySession{loader: loader}
+	manifestURL := &url.URL{Scheme: "https", Host: ref.Host, Path: "/v2/" + repository + "/manifests/" + reference}
+	b, err := session.get(ctx, manifestURL, ociManifestMediaType, isDigest)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: get manifest: %w", ref.Redacted(), err)
+	}
+	if isDigest {
+		if err := verifyOCIDigest(reference, b); err != nil {
+			return nil, fmt.Errorf("oci url in $ref=%q: get manifest: %w", ref.Redacted(), err)
+		}
+	}
+	layer, err := findOCIChartLayer(b)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: %w", ref.Redacted(), err)
+	}
+	if !ociDigestRegexp.MatchString(layer.Digest) {
+		return nil, fmt.Errorf("oci url in $ref=%q: chart layer has unsupported digest %q", ref.Redacted(), layer.Digest)
+	}
+
+	blobURL := &url.URL{Scheme: "https", Host: ref.Host, Path: "/v2/" + repository + "/blobs/" + layer.Digest}
+	b, err = session.get(ctx, blobURL, "", true)
+	if err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: get chart layer: %w", ref.Redacted(), err)
+	}
+	if err := verifyOCIDigest(layer.Digest, b); err != nil {
+		return nil, fmt.Errorf("oci url in $ref=%q: get chart layer: %w", ref.Redacted(), err)
+	}
+	return b, nil
+}
+
+// ociRegistrySession reuses the bearer token of an anonymous
+// registry login between requests.
+type ociRegistrySession struct {
+	loader OCILoader
+	token  string
+}
+
+// Flags are only used in testing to achieve better test coverage
+var (
+	failOCILoaderNewRequest      bool
+	failOCILoaderNewTokenRequest bool
+)
+
+// get sends a GET request to the registry, and logs in when the registry
+// responds with "401 Unauthorized". Immutable responses are stored in
+// and loaded from the cache.
+func (s *ociRegistrySession) get(ctx context.Context, u *url.URL, accept string, immutable bool) ([]byte, error) {
+	logger := LoggerFromContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil || failOCILoaderNewRequest {
+		// The [http.NewRequestWithContext] will never fail,
+		// so we have to induce a fake failure via [failOCILoaderNewRequest]
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	logger.Log("Loading", req.URL.Redacted())
+	if immutable && s.loader.cache != nil {
+		cached, err := s.loader.cache.LoadCache(req)
+		if err == nil && !cached.Expired() {
+			logger.Logf("=> got %s from cache", formatSizeBytes(len(cached.Data)))
+			return cached.Data, nil
+		}
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if s.loader.UserAgent != "" {
+		req.Header.Set("User-Agent", s.loader.UserAgent)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer closeIgnoreError(resp.Body)
+
+	if resp.StatusCode == http.StatusUnauthorized && s.token == "" {
+		token, err := s.loader.fetchToken(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, fmt.Errorf("login: %w", err)
+		}
+		s.token = token
+		resp, err = s.do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer closeIgnoreError(resp.Body)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("got non-2xx status code: %s", resp.Status)
+	}
+
+	b, err := s.loader.readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logger.Logf("=> got %s", formatSizeBytes(len(b)))
+
+	if immutable && s.loader.cache != nil {
+		cacheResp := &http.Response{Header: http.Header{"Cache-Control": {ociImmutableCacheControl}}}
+		if _, err := s.loader.cache.SaveCache(req, cacheResp, b); err != nil {
+			logger.Log("Error saving response cache:", err)
+		}
+	}
+	return b, nil
+}
+
+func (s *ociRegistrySession) do(req *http.Request) (*http.Response, error) {
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.loader.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request over HTTP: %w", err)
+	}
+	return resp, nil
+}
+
+func (loader OCILoader) readBody(r io.Reader) ([]byte, error) {
+	if loader.SizeLimit > 0 {
+		r = LimitReaderWithError(r, loader.SizeLimit,
+			fmt.Errorf("aborted request after reading more than %s", formatSizeBytes(int(loader.SizeLimit))))
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return b, nil
+}
+
+var wwwAuthenticateParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// fetchToken requests an anonymous bearer token, using the "realm", "service"
+// and "scope" from the "WWW-Authenticate" response header.
+// See https://distribution.github.io/distribution/spec/auth/token/
+func (loader OCILoader) fetchToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	values := map[string]string{}
+	for _, match := range wwwAuthenticateParamRegexp.FindAllStringSubmatch(params, -1) {
+		values[match[1]] = match[2]
+	}
+	if values["realm"] == "" {
+		return "", fmt.Errorf("missing realm in authentication challenge %q", challenge)
+	}
+	tokenURL, err := url.Parse(values["realm"])
+	if err != nil {
+		return "", fmt.Errorf("parse realm: %w", err)
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if values[key] != "" {
+			query.Set(key, values[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil || failOCILoaderNewTokenRequest {
+		// The [http.NewRequestWithContext] will never fail,
+		// so we have to induce a fake failure via [failOCILoaderNewTokenRequest]
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	if loader.UserAgent != "" {
+		req.Header.Set("User-Agent", loader.UserAgent)
+	}
+	resp, err := loader.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request token over HTTP: %w", err)
+	}
+	defer closeIgnoreError(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("request token: got non-2xx status code: %s", resp.Status)
+	}
+	b, err := loader.readBody(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return "", fmt.Errorf("parse token response: %w", err)
+	}
+	token := cmp.Or(body.Token, body.AccessToken)
+	if token == "" {
+		return "", fmt.Errorf("token response did not contain a token")
+	}
+	return token, nil
+}
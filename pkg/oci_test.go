@@ -0,0 +1,654 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ociTestDigest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func ociTestManifest(t *testing.T, layers ...ociDescriptor) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestMediaType,
+		"config": ociDescriptor{
+			MediaType: "application/vnd.cncf.helm.config.v1+json",
+			Digest:    ociTestDigest([]byte("{}")),
+			Size:      2,
+		},
+		"layers": layers,
+	})
+	require.NoError(t, err)
+	return b
+}
+
+func ociTestChartLayer(chart []byte) ociDescriptor {
+	return ociDescriptor{
+		MediaType: ociHelmChartLayerMediaType,
+		Digest:    ociTestDigest(chart),
+		Size:      int64(len(chart)),
+	}
+}
+
+// ociTestRegistry is a minimal OCI registry that serves a single chart
+// from the "charts/mylib" repository with the "1.0.0" tag.
+type ociTestRegistry struct {
+	Manifest    []byte
+	Blobs       map[string][]byte
+	RequireAuth bool
+	Challenge   string
+	TokenStatus int
+	TokenBody   string
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newOCITestRegistry(t *testing.T, chart []byte) (*ociTestRegistry, *httptest.Server) {
+	t.Helper()
+	reg := &ociTestRegistry{
+		Manifest:    ociTestManifest(t, ociTestChartLayer(chart)),
+		Blobs:       map[string][]byte{ociTestDigest(chart): chart},
+		TokenStatus: http.StatusOK,
+		TokenBody:   `{"token": "secret"}`,
+	}
+	server := httptest.NewTLSServer(reg)
+	t.Cleanup(server.Close)
+	reg.Challenge = fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:charts/mylib:pull"`, server.URL)
+	return reg, server
+}
+
+func (reg *ociTestRegistry) Requests() []string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.requests
+}
+
+// ServeHTTP implements [http.Handler].
+func (reg *ociTestRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mu.Lock()
+	reg.requests = append(reg.requests, r.URL.Path)
+	reg.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		if r.URL.Query().Get("service") != "test" || r.URL.Query().Get("scope") != "repository:charts/mylib:pull" {
+			http.Error(w, "invalid token request", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(reg.TokenStatus)
+		_, _ = w.Write([]byte(reg.TokenBody))
+		return
+	}
+	if reg.RequireAuth && r.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", reg.Challenge)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v2/charts/mylib/manifests/1.0.0",
+		// Serve the manifest on any digest, to test digest verification
+		strings.HasPrefix(r.URL.Path, "/v2/charts/mylib/manifests/sha256:"):
+		if r.Header.Get("Accept") != ociManifestMediaType {
+			http.Error(w, "invalid accept header", http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", ociManifestMediaType)
+		_, _ = w.Write(reg.Manifest)
+	case strings.HasPrefix(r.URL.Path, "/v2/charts/mylib/blobs/"):
+		blob, ok := reg.Blobs[strings.TrimPrefix(r.URL.Path, "/v2/charts/mylib/blobs/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(blob)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestOCILoader_Registry(t *testing.T) {
+	chart := createChartArchive(t, "mylib/values.schema.json", `{"type": "object"}`)
+
+	t.Run("tag", func(t *testing.T) {
+		reg, server := newOCITestRegistry(t, chart)
+		loader := NewOCILoader(server.Client(), NewHTTPMemoryCache(), NewFileLoader(nil, ""))
+		ctx := ContextWithLogger(t.Context(), t)
+		ref := mustParseURL("oci://" + server.Listener.Addr().String() + "/charts/mylib:1.0.0")
+
+		schema, err := loader.Load(ctx, ref)
+		require.NoError(t, err)
+		assert.Equal(t, &Schema{Type: "object"}, schema)
+
+		// Only the blob is cached, as tags can change
+		_, err = loader.Load(ctx, ref)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"/v2/charts/mylib/manifests/1.0.0",
+			"/v2/charts/mylib/blobs/" + ociTestDigest(chart),
+			"/v2/charts/mylib/manifests/1.0.0",
+		}, reg.Requests())
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		reg, server := newOCITestRegistry(t, chart)
+		loader := NewOCILoader(server.Client(), NewHTTPMemoryCache(), NewFileLoader(nil, ""))
+		ctx := ContextWithLogger(t.Context(), t)
+		ref := mustParseURL("oci://" + server.Listener.Addr().String() + "/charts/mylib@" + ociTestDigest(reg.Manifest))
+
+		schema, err := loader.Load(ctx, ref)
+		require.NoError(t, err)
+		assert.Equal(t, &Schema{Type: "object"}, schema)
+
+		// Both manifest and blob are cached, as digests never change
+		_, err = loader.Load(ctx, ref)
+		require.NoError(t, err)
+		assert.Len(t, reg.Requests(), 2)
+	})
+
+	t.Run("anonymous login", func(t *testing.T) {
+		reg, server := newOCITestRegistry(t, chart)
+		reg.RequireAuth = true
+		loader := NewOCILoader(server.Client(), nil, NewFileLoader(nil, ""))
+		ctx := ContextWithLogger(t.Context(), t)
+		ref := mustParseURL("oci://" + server.Listener.Addr().String() + "/charts/mylib:1.0.0")
+
+		schema, err := loader.Load(ctx, ref)
+		require.NoError(t, err)
+		assert.Equal(t, &Schema{Type: "object"}, schema)
+		assert.Equal(t, []string{
+			"/v2/charts/mylib/manifests/1.0.0",
+			"/token",
+			"/v2/charts/mylib/manifests/1.0.0",
+			"/v2/charts/mylib/blobs/" + ociTestDigest(chart),
+		}, reg.Requests())
+	})
+
+	t.Run("cache save error", func(t *testing.T) {
+		_, server := newOCITestRegistry(t, chart)
+		cache := DummyHTTPCache{
+			LoadCacheFunc: func(req *http.Request) (CachedResponse, error) {
+				return CachedResponse{}, fmt.Errorf("dummy load error")
+			},
+			SaveCacheFunc: func(req *http.Request, resp *http.Response, body []byte) (CachedResponse, error) {
+				assert.Equal(t, ociImmutableCacheControl, resp.Header.Get("Cache-Control"))
+				return CachedResponse{}, fmt.Errorf("dummy save error")
+			},
+		}
+		loader := NewOCILoader(server.Client(), cache, NewFileLoader(nil, ""))
+		ctx := ContextWithLogger(t.Context(), t)
+		ref := mustParseURL("oci://" + server.Listener.Addr().String() + "/charts/mylib:1.0.0")
+
+		schema, err := loader.Load(ctx, ref)
+		require.NoError(t, err)
+		assert.Equal(t, &Schema{Type: "object"}, schema)
+	})
+}
+
+func TestOCILoader_Registry_Error(t *testing.T) {
+	chart := createChartArchive(t, "mylib/values.schema.json", `{"type": "object"}`)
+	otherChart := createChartArchive(t, "mylib/Chart.yaml", "name: mylib")
+
+	tests := []struct {
+		name    string
+		ref     string
+		setup   func(reg *ociTestRegistry)
+		wantErr string
+	}{
+		{
+			name:    "query",
+			ref:     "/charts/mylib:1.0.0?foo=bar",
+			wantErr: `query parameters not supported`,
+		},
+		{
+			name:    "manifest not found",
+			ref:     "/charts/mylib:2.0.0",
+			wantErr: `get manifest: got non-2xx status code: 404 Not Found`,
+		},
+		{
+			name: "invalid manifest",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.Manifest = []byte("{")
+			},
+			wantErr: `parse manifest: unexpected end of JSON input`,
+		},
+		{
+			name: "not a chart",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.Manifest = ociTestManifest(t)
+			},
+			wantErr: `manifest has no layer with media type "application/vnd.cncf.helm.chart.content.v1.tar+gzip", is it a Helm chart?`,
+		},
+		{
+			name: "invalid layer digest",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.Manifest = ociTestManifest(t, ociDescriptor{MediaType: ociHelmChartLayerMediaType, Digest: "md5:foo"})
+			},
+			wantErr: `chart layer has unsupported digest "md5:foo"`,
+		},
+		{
+			name:    "manifest digest mismatch",
+			ref:     "/charts/mylib@" + ociTestDigest([]byte("foo")),
+			wantErr: `get manifest: digest mismatch: expected ` + ociTestDigest([]byte("foo")),
+		},
+		{
+			name: "blob not found",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.Blobs = nil
+			},
+			wantErr: `get chart layer: got non-2xx status code: 404 Not Found`,
+		},
+		{
+			name: "blob digest mismatch",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.Blobs[ociTestDigest(chart)] = otherChart
+			},
+			wantErr: `get chart layer: digest mismatch: expected ` + ociTestDigest(chart) + `, got ` + ociTestDigest(otherChart),
+		},
+		{
+			name: "no schema in chart",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.Manifest = ociTestManifest(t, ociTestChartLayer(otherChart))
+				reg.Blobs[ociTestDigest(otherChart)] = otherChart
+			},
+			wantErr: `chart archive does not contain a values.schema.json file`,
+		},
+		{
+			name: "unsupported challenge",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.Challenge = `Basic realm="test"`
+			},
+			wantErr: `get manifest: login: unsupported authentication challenge "Basic realm=\"test\""`,
+		},
+		{
+			name: "missing realm",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.Challenge = `Bearer service="test"`
+			},
+			wantErr: `get manifest: login: missing realm in authentication challenge "Bearer service=\"test\""`,
+		},
+		{
+			name: "invalid realm",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.Challenge = `Bearer realm=":foo"`
+			},
+			wantErr: `get manifest: login: parse realm: parse ":foo": missing protocol scheme`,
+		},
+		{
+			name: "token request error",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.Challenge = `Bearer realm="http://localhost:0/token"`
+			},
+			wantErr: `get manifest: login: request token over HTTP: Get "http://localhost:0/token": dial tcp`,
+		},
+		{
+			name: "token status error",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.TokenStatus = http.StatusForbidden
+			},
+			wantErr: `get manifest: login: request token: got non-2xx status code: 403 Forbidden`,
+		},
+		{
+			name: "invalid token response",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.TokenBody = "{"
+			},
+			wantErr: `get manifest: login: parse token response: unexpected end of JSON input`,
+		},
+		{
+			name: "empty token",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.TokenBody = "{}"
+			},
+			wantErr: `get manifest: login: token response did not contain a token`,
+		},
+		{
+			name: "token rejected",
+			ref:  "/charts/mylib:1.0.0",
+			setup: func(reg *ociTestRegistry) {
+				reg.RequireAuth = true
+				reg.TokenBody = `{"access_token": "wrong"}`
+			},
+			wantErr: `get manifest: got non-2xx status code: 401 Unauthorized`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, server := newOCITestRegistry(t, chart)
+			if tt.setup != nil {
+				tt.setup(reg)
+			}
+			loader := NewOCILoader(server.Client(), nil, NewFileLoader(nil, ""))
+			ctx := ContextWithLogger(t.Context(), t)
+			_, err := loader.Load(ctx, mustParseURL("oci://"+server.Listener.Addr().String()+tt.ref))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOCILoader_Registry_RequestError(t *testing.T) {
+	_, server := newOCITestRegistry(t, nil)
+	ref := mustParseURL("oci://" + server.Listener.Addr().String() + "/charts/mylib:1.0.0")
+	ctx := ContextWithLogger(t.Context(), t)
+
+	t.Run("connection refused", func(t *testing.T) {
+		loader := NewOCILoader(http.DefaultClient, nil, NewFileLoader(nil, ""))
+		_, err := loader.Load(ctx, mustParseURL("oci://localhost:0/charts/mylib:1.0.0"))
+		assert.ErrorContains(t, err, `get manifest: request over HTTP: Get "https://localhost:0/v2/charts/mylib/manifests/1.0.0"`)
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		loader := NewOCILoader(server.Client(), nil, NewFileLoader(nil, ""))
+		loader.SizeLimit = 10
+		_, err := loader.Load(ctx, ref)
+		assert.ErrorContains(t, err, "get manifest: read response: aborted request after reading more than 10B")
+	})
+
+	t.Run("new request", func(t *testing.T) {
+		failOCILoaderNewRequest = true
+		defer func() { failOCILoaderNewRequest = false }()
+		loader := NewOCILoader(server.Client(), nil, NewFileLoader(nil, ""))
+		_, err := loader.Load(ctx, ref)
+		assert.ErrorContains(t, err, "get manifest: create request:")
+	})
+}
+
+func TestOCILoader_FetchToken_NewRequestError(t *testing.T) {
+	failOCILoaderNewTokenRequest = true
+	defer func() { failOCILoaderNewTokenRequest = false }()
+	loader := NewOCILoader(http.DefaultClient, nil, NewFileLoader(nil, ""))
+	_, err := loader.fetchToken(t.Context(), `Bearer realm="https://localhost/token"`)
+	assert.ErrorContains(t, err, "create request:")
+}
+
+func TestOCILoader_FetchToken_UserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "my-user-agent", r.Header.Get("User-Agent"))
+		_, _ = w.Write([]byte(`{"token": "secret"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	loader := NewOCILoader(server.Client(), nil, NewFileLoader(nil, ""))
+	loader.UserAgent = "my-user-agent"
+	token, err := loader.fetchToken(t.Context(), `Bearer realm="`+server.URL+`"`)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", token)
+}
+
+func TestParseOCIReference(t *testing.T) {
+	digest := ociTestDigest([]byte("foo"))
+	tests := []struct {
+		name           string
+		url            string
+		wantRepository string
+		wantReference  string
+		wantIsDigest   bool
+		wantErr        string
+	}{
+		{
+			name:           "tag",
+			url:            "oci://ghcr.io/org/charts/mylib:1.0.0",
+			wantRepository: "org/charts/mylib",
+			wantReference:  "1.0.0",
+		},
+		{
+			name:           "digest",
+			url:            "oci://ghcr.io/org/charts/mylib@" + digest,
+			wantRepository: "org/charts/mylib",
+			wantReference:  digest,
+			wantIsDigest:   true,
+		},
+		{
+			name:           "host with port",
+			url:            "oci://localhost:5000/mylib:1.0.0",
+			wantRepository: "mylib",
+			wantReference:  "1.0.0",
+		},
+		{
+			name:    "query",
+			url:     "oci://ghcr.io/mylib:1.0.0?foo=bar",
+			wantErr: "query parameters not supported",
+		},
+		{
+			name:    "no tag",
+			url:     "oci://ghcr.io/org/mylib",
+			wantErr: "must contain a tag or digest, e.g oci://registry/repository:1.0.0",
+		},
+		{
+			name:    "colon before last slash",
+			url:     "oci://ghcr.io/org:foo/mylib",
+			wantErr: "must contain a tag or digest, e.g oci://registry/repository:1.0.0",
+		},
+		{
+			name:    "empty tag",
+			url:     "oci://ghcr.io/mylib:",
+			wantErr: "must contain a repository and a tag",
+		},
+		{
+			name:    "empty repository",
+			url:     "oci://ghcr.io/:1.0.0",
+			wantErr: "must contain a repository and a tag",
+		},
+		{
+			name:    "invalid digest",
+			url:     "oci://ghcr.io/mylib@sha256:foo",
+			wantErr: `unsupported digest "sha256:foo"`,
+		},
+		{
+			name:    "empty repository with digest",
+			url:     "oci://ghcr.io/@" + digest,
+			wantErr: "must contain a repository",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository, reference, isDigest, err := parseOCIReference(mustParseURL(tt.url))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRepository, repository)
+			assert.Equal(t, tt.wantReference, reference)
+			assert.Equal(t, tt.wantIsDigest, isDigest)
+		})
+	}
+}
+
+// ociTestLayout returns an OCI image layout with one manifest per tag,
+// where each chart has a schema with the tag as its description.
+func ociTestLayout(t *testing.T, dir string, tags ...string) fstest.MapFS {
+	t.Helper()
+	fsys := fstest.MapFS{
+		dir + "/oci-layout": {Data: []byte(`{"imageLayoutVersion": "1.0.0"}`)},
+	}
+	var manifests []ociDescriptor
+	for _, tag := range tags {
+		chart := createChartArchive(t, "mylib/values.schema.json", `{"description": "`+tag+`"}`)
+		manifest := ociTestManifest(t, ociTestChartLayer(chart))
+		fsys[dir+"/blobs/sha256/"+strings.TrimPrefix(ociTestDigest(chart), "sha256:")] = &fstest.MapFile{Data: chart}
+		fsys[dir+"/blobs/sha256/"+strings.TrimPrefix(ociTestDigest(manifest), "sha256:")] = &fstest.MapFile{Data: manifest}
+		manifests = append(manifests, ociDescriptor{
+			MediaType:   ociManifestMediaType,
+			Digest:      ociTestDigest(manifest),
+			Size:        int64(len(manifest)),
+			Annotations: map[string]string{ociRefNameAnnotation: tag},
+		})
+	}
+	index, err := json.Marshal(map[string]any{"schemaVersion": 2, "manifests": manifests})
+	require.NoError(t, err)
+	fsys[dir+"/index.json"] = &fstest.MapFile{Data: index}
+	return fsys
+}
+
+func TestOCILoader_Layout(t *testing.T) {
+	fsys := ociTestLayout(t, "single", "1.0.0")
+	for name, file := range ociTestLayout(t, "multiple", "1.0.0", "2.0.0") {
+		fsys[name] = file
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		want *Schema
+	}{
+		{name: "single", url: "oci:single", want: &Schema{Description: "1.0.0"}},
+		{name: "single with tag", url: "oci:single?tag=1.0.0", want: &Schema{Description: "1.0.0"}},
+		{name: "multiple with tag", url: "oci:multiple?tag=2.0.0", want: &Schema{Description: "2.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewOCILoader(http.DefaultClient, nil, NewFileLoader(fsys, ""))
+			ctx := ContextWithLogger(t.Context(), t)
+			schema, err := loader.Load(ctx, mustParseURL(tt.url))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schema)
+		})
+	}
+}
+
+func TestOCILoader_Layout_Error(t *testing.T) {
+	fsys := ociTestLayout(t, "multiple", "1.0.0", "2.0.0")
+	for name, file := range ociTestLayout(t, "broken", "1.0.0") {
+		fsys[name] = file
+	}
+	fsys["empty/index.json"] = &fstest.MapFile{Data: []byte(`{}`)}
+	fsys["invalid/index.json"] = &fstest.MapFile{Data: []byte(`{`)}
+	fsys["bad-digest/index.json"] = &fstest.MapFile{Data: []byte(`{"manifests": [{"digest": "sha256:../../foo"}]}`)}
+	fsys["missing-blob/index.json"] = &fstest.MapFile{Data: []byte(`{"manifests": [{"digest": "` + ociTestDigest([]byte("foo")) + `"}]}`)}
+
+	// Replace the chart blob of "broken" with other content, so the digest doesn't match
+	var index ociManifest
+	require.NoError(t, json.Unmarshal(fsys["broken/index.json"].Data, &index))
+	manifestBlob := fsys["broken/blobs/sha256/"+strings.TrimPrefix(index.Manifests[0].Digest, "sha256:")].Data
+	layer, err := findOCIChartLayer(manifestBlob)
+	require.NoError(t, err)
+	fsys["broken/blobs/sha256/"+strings.TrimPrefix(layer.Digest, "sha256:")] = &fstest.MapFile{Data: []byte("foo")}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{
+			name:    "invalid scheme",
+			url:     "file:multiple",
+			wantErr: `oci url in $ref="file:multiple" must start with "oci:"`,
+		},
+		{
+			name:    "user info",
+			url:     "oci://user@ghcr.io/mylib:1.0.0",
+			wantErr: `oci url in $ref="oci://user@ghcr.io/mylib:1.0.0": user info not supported`,
+		},
+		{
+			name:    "empty path",
+			url:     "oci:",
+			wantErr: `oci url in $ref="oci:" must contain a path or a host`,
+		},
+		{
+			name:    "unsupported query",
+			url:     "oci:multiple?version=1.0.0",
+			wantErr: `oci url in $ref="oci:multiple?version=1.0.0": unsupported query parameter "version"`,
+		},
+		{
+			name:    "index not found",
+			url:     "oci:does-not-exist",
+			wantErr: `open does-not-exist/index.json: file does not exist`,
+		},
+		{
+			name:    "invalid index",
+			url:     "oci:invalid",
+			wantErr: `oci url in $ref="oci:invalid": parse index.json: unexpected end of JSON input`,
+		},
+		{
+			name:    "no manifests",
+			url:     "oci:empty",
+			wantErr: `oci url in $ref="oci:empty": no manifests found`,
+		},
+		{
+			name:    "multiple manifests",
+			url:     "oci:multiple",
+			wantErr: `oci url in $ref="oci:multiple": found multiple manifests, use "?tag=" to select one of: 1.0.0, 2.0.0`,
+		},
+		{
+			name:    "tag not found",
+			url:     "oci:multiple?tag=3.0.0",
+			wantErr: `oci url in $ref="oci:multiple?tag=3.0.0": no manifest tagged "3.0.0", found: 1.0.0, 2.0.0`,
+		},
+		{
+			name:    "bad digest",
+			url:     "oci:bad-digest",
+			wantErr: `oci url in $ref="oci:bad-digest": read manifest: unsupported digest "sha256:../../foo"`,
+		},
+		{
+			name:    "missing blob",
+			url:     "oci:missing-blob",
+			wantErr: `oci url in $ref="oci:missing-blob": read manifest: open missing-blob/blobs/sha256/` + strings.TrimPrefix(ociTestDigest([]byte("foo")), "sha256:") + `: file does not exist`,
+		},
+		{
+			name:    "chart digest mismatch",
+			url:     "oci:broken",
+			wantErr: `oci url in $ref="oci:broken": read chart layer: digest mismatch: expected ` + layer.Digest + `, got ` + ociTestDigest([]byte("foo")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewOCILoader(http.DefaultClient, nil, NewFileLoader(fsys, ""))
+			ctx := ContextWithLogger(t.Context(), t)
+			_, err := loader.Load(ctx, mustParseURL(tt.url))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOCILoader_Layout_InvalidManifest(t *testing.T) {
+	manifest := []byte("{")
+	fsys := fstest.MapFS{
+		"layout/index.json": {Data: []byte(`{"manifests": [{"digest": "` + ociTestDigest(manifest) + `"}]}`)},
+		"layout/blobs/sha256/" + strings.TrimPrefix(ociTestDigest(manifest), "sha256:"): {Data: manifest},
+	}
+	loader := NewOCILoader(http.DefaultClient, nil, NewFileLoader(fsys, ""))
+	ctx := ContextWithLogger(t.Context(), t)
+	_, err := loader.Load(ctx, &url.URL{Scheme: ociScheme, Opaque: "layout"})
+	assert.EqualError(t, err, `oci url in $ref="oci:layout": parse manifest: unexpected end of JSON input`)
+}
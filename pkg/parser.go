@@ -83,10 +83,19 @@ func mergeSchemas(dest, src *Schema) *Schema {
 	dest.MaxContains = cmp.Or(src.MaxContains, dest.MaxContains)
 	dest.MinContains = cmp.Or(src.MinContains, dest.MinContains)
 	dest.Contains = cmp.Or(src.Contains, dest.Contains)
-	if src.PrefixItems != nil {
+	tupleItems := dest.ItemsMode == ItemsModeTuple && src.ItemsMode == ItemsModeTuple
+	switch {
+	case tupleItems:
+		dest.PrefixItems = mergePrefixItems(dest.PrefixItems, src.PrefixItems)
+	case src.PrefixItems != nil:
 		dest.PrefixItems = src.PrefixItems
 	}
-	dest.Items = mergeSchemas(dest.Items, src.Items)
+	if dest.ItemsMode == ItemsModeUnion && src.ItemsMode == ItemsModeUnion {
+		dest.Items = mergeUnionItems(dest.Items, src.Items)
+	} else {
+		dest.Items = mergeSchemas(dest.Items, src.Items)
+	}
+	dest.ItemsMode = cmp.Or(src.ItemsMode, dest.ItemsMode)
 	dest.AdditionalItems = mergeSchemas(dest.AdditionalItems, src.AdditionalItems)
 	dest.UnevaluatedItems = mergeSchemas(dest.UnevaluatedItems, src.UnevaluatedItems)
 	dest.Required = uniqueStringAppend(dest.Required, src.Required)
@@ -102,8 +111,16 @@ func mergeSchemas(dest, src *Schema) *Schema {
 	dest.DependentSchemas = mergeSchemasMap(dest.DependentSchemas, src.DependentSchemas)
 	dest.Defs = mergeSchemasMap(dest.Defs, src.Defs)
 	dest.Definitions = mergeSchemasMap(dest.Definitions, src.Definitions)
+	dest.XSection = cmp.Or(src.XSection, dest.XSection)
 
 	dest.RequiredByParent = dest.RequiredByParent || src.RequiredByParent
+	dest.InferEnum = dest.InferEnum || src.InferEnum
+	dest.NoInfer = dest.NoInfer || src.NoInfer
+	for _, ptr := range src.UnknownKeywords {
+		if !slices.ContainsFunc(dest.UnknownKeywords, ptr.Equals) {
+			dest.UnknownKeywords = append(dest.UnknownKeywords, ptr)
+		}
+	}
 	return dest
 }
 
@@ -373,49 +390,104 @@ func isValidTypeString(t string) bool {
 	}
 }
 
-func updateRefK8sAlias(schema *Schema, urlTemplate, version string) error {
-	urlFunc := sync.OnceValues(func() (string, error) {
-		if version == "" {
-			return "", fmt.Errorf(`must set k8sSchemaVersion config when using "$ref: $k8s/...". For example pass --k8s-schema-version=v1.33.1 flag`)
-		}
-		tpl, err := template.New("").Parse(urlTemplate)
-		if err != nil {
-			return "", fmt.Errorf("parse k8sSchemaURL template: %w", err)
-		}
-		var buf bytes.Buffer
-		if err := tpl.Execute(&buf, struct{ K8sSchemaVersion string }{K8sSchemaVersion: version}); err != nil {
-			return "", fmt.Errorf("template k8sSchemaURL: %w", err)
-		}
-		return buf.String(), nil
-	})
-	return updateRefK8sAliasRec(nil, schema, urlFunc)
+// RefAlias is a user-defined "$ref: $name/..." alias, as configured in the
+// refAliases config, where "name" is the key in the config.
+type RefAlias struct {
+	// URL is a Go text/template of the URL that the alias expands to,
+	// e.g "https://example.com/schemas/{{ .version }}/".
+	URL string `yaml:"url" koanf:"url"`
+	// Vars are the variables available in the URL template,
+	// e.g "version" is used in the template as "{{ .version }}".
+	Vars map[string]string `yaml:"vars" koanf:"vars"`
+}
+
+// refAliases maps alias names, without the "$" prefix, to functions returning
+// the URL the alias expands to. See [newRefAliases].
+type refAliases map[string]func() (string, error)
+
+// newRefAliases returns the built-in "$k8s" alias, based on the k8sSchemaURL
+// and k8sSchemaVersion config, together with the user-defined aliases from
+// the refAliases config. A user-defined "k8s" alias replaces the built-in one.
+//
+// The URL templates are only executed once the alias is used, so that
+// missing template variables only fail when they are actually needed.
+func newRefAliases(k8sURLTemplate, k8sVersion string, aliases map[string]RefAlias) refAliases {
+	result := refAliases{
+		"k8s": sync.OnceValues(func() (string, error) {
+			if k8sVersion == "" {
+				return "", fmt.Errorf(`must set k8sSchemaVersion config when using "$ref: $k8s/...". For example pass --k8s-schema-version=v1.33.1 flag`)
+			}
+			return executeRefAliasTemplate("k8sSchemaURL", k8sURLTemplate, struct{ K8sSchemaVersion string }{K8sSchemaVersion: k8sVersion})
+		}),
+	}
+	for name, alias := range aliases {
+		name = strings.TrimPrefix(name, "$")
+		result[name] = sync.OnceValues(func() (string, error) {
+			return executeRefAliasTemplate(fmt.Sprintf("refAliases.%s.url", name), alias.URL, alias.Vars)
+		})
+	}
+	return result
 }
 
-func updateRefK8sAliasRec(ptr Ptr, schema *Schema, urlFunc func() (string, error)) error {
+func executeRefAliasTemplate(name, urlTemplate string, data any) (string, error) {
+	tpl, err := template.New("").Option("missingkey=error").Parse(urlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("parse %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// update expands all "$ref: $name/..." aliases in the schema and its
+// subschemas. References starting with "$" that don't match any alias
+// are kept as-is.
+func (aliases refAliases) update(schema *Schema) error {
+	return aliases.updateRec(nil, schema)
+}
+
+func (aliases refAliases) updateRec(ptr Ptr, schema *Schema) error {
 	for path, sub := range schema.Subschemas() {
 		// continue recursively
-		if err := updateRefK8sAliasRec(ptr.Add(path), sub, urlFunc); err != nil {
+		if err := aliases.updateRec(ptr.Add(path), sub); err != nil {
 			return err
 		}
 	}
 
-	withoutFragment, _, _ := strings.Cut(schema.Ref, "#")
-	if withoutFragment == "$k8s" || withoutFragment == "$k8s/" {
-		return fmt.Errorf("%s: invalid $k8s schema alias: must have a path but only got %q", ptr, schema.Ref)
+	ref, err := aliases.expand(schema.Ref)
+	if err != nil {
+		return fmt.Errorf("%s: %w", ptr, err)
 	}
+	schema.Ref = ref
+	return nil
+}
 
-	withoutAlias, ok := strings.CutPrefix(schema.Ref, "$k8s/")
+// expand returns the "$ref: $name/..." with the alias expanded. References
+// starting with "$" that don't match any alias are returned as-is.
+func (aliases refAliases) expand(ref string) (string, error) {
+	withoutDollar, ok := strings.CutPrefix(ref, "$")
 	if !ok {
-		return nil
+		return ref, nil
+	}
+	withoutFragment, _, _ := strings.Cut(withoutDollar, "#")
+	name, pathAfterAlias, _ := strings.Cut(withoutFragment, "/")
+	urlFunc, ok := aliases[name]
+	if !ok {
+		return ref, nil
+	}
+	if pathAfterAlias == "" {
+		return "", fmt.Errorf("invalid $%s schema alias: must have a path but only got %q", name, ref)
 	}
 
 	urlPrefix, err := urlFunc()
 	if err != nil {
-		return fmt.Errorf("%s: %w", ptr, err)
+		return "", err
 	}
 
-	schema.Ref = fmt.Sprintf("%s/%s", strings.TrimSuffix(urlPrefix, "/"), withoutAlias)
-	return nil
+	withoutAlias := strings.TrimPrefix(withoutDollar, name+"/")
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(urlPrefix, "/"), withoutAlias), nil
 }
 
 // addMissingGlobalProperty adds /properties/global in case
@@ -153,6 +153,12 @@ func TestMergeSchemas(t *testing.T) {
 			src:  &Schema{Type: "object", Title: "My Title", Description: "My description", ReadOnly: true, Default: "default value", Const: "const value", ID: "http://example.com/schema", Ref: "schema/product.json", Schema: "https://my-schema", Comment: "New comment", Examples: []any{"bar"}},
 			want: &Schema{Type: "object", Title: "My Title", Description: "My description", ReadOnly: true, Default: "default value", Const: "const value", ID: "http://example.com/schema", Ref: "schema/product.json", Schema: "https://my-schema", Comment: "New comment", Examples: []any{"bar"}},
 		},
+		{
+			name: "x-section",
+			dest: &Schema{XSection: "Old"},
+			src:  &Schema{XSection: "New"},
+			want: &Schema{XSection: "New"},
+		},
 		{
 			name: "vocabulary",
 			dest: &Schema{Vocabulary: map[string]bool{"a": true, "b": false, "c": true}},
@@ -894,7 +900,7 @@ func TestUpdateRefK8sAlias(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := updateRefK8sAlias(tt.schema, tt.urlTemplate, tt.version)
+			err := newRefAliases(tt.urlTemplate, tt.version, nil).update(tt.schema)
 			if tt.wantErr != "" {
 				require.ErrorContains(t, err, tt.wantErr)
 				return
@@ -905,6 +911,48 @@ func TestUpdateRefK8sAlias(t *testing.T) {
 	}
 }
 
+func TestUpdateRefAliases(t *testing.T) {
+	aliases := map[string]RefAlias{
+		"crd": {
+			URL:  "https://schemas.example.com/crds/{{ .version }}/",
+			Vars: map[string]string{"version": "v2"},
+		},
+		"$schemastore": {URL: "https://json.schemastore.org"},
+		"k8s":          {URL: "https://mirror.example.com/k8s/"},
+		"missing":      {URL: "https://example.com/{{ .version }}"},
+		"invalid":      {URL: "https://example.com/{{"},
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "template vars", ref: "$crd/certificate.json", want: "https://schemas.example.com/crds/v2/certificate.json"},
+		{name: "dollar prefix in config", ref: "$schemastore/chart.json#/properties/name", want: "https://json.schemastore.org/chart.json#/properties/name"},
+		{name: "overrides built-in k8s", ref: "$k8s/pod.json", want: "https://mirror.example.com/k8s/pod.json"},
+		{name: "unknown alias is kept", ref: "$argo/app.json", want: "$argo/app.json"},
+		{name: "alias must be followed by slash", ref: "$crdfoo/bar.json", want: "$crdfoo/bar.json"},
+		{name: "missing path", ref: "$crd", wantErr: `/items: invalid $crd schema alias: must have a path but only got "$crd"`},
+		{name: "missing var", ref: "$missing/foo.json", wantErr: `/items: template refAliases.missing.url: template: :1:23: executing "" at <.version>: map has no entry for key "version"`},
+		{name: "invalid template", ref: "$invalid/foo.json", wantErr: "/items: parse refAliases.invalid.url template: template: :1: unclosed action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &Schema{Items: &Schema{Ref: tt.ref}}
+			err := newRefAliases(DefaultConfig.K8sSchemaURL, "v1.33.1", aliases).update(schema)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schema.Items.Ref)
+		})
+	}
+}
+
 func TestAddMissingGlobalProperty(t *testing.T) {
 	tests := []struct {
 		name   string
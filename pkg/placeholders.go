@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultPlaceholderTypes maps key name patterns commonly found in Helm charts
+// to the type of their values, as used by the "placeholders" config.
+var defaultPlaceholderTypes = map[string]string{
+	"*Annotations": "string",
+	"*Labels":      "string",
+	"annotations":  "string",
+	"labels":       "string",
+}
+
+// placeholderRules holds the types used for empty maps, empty arrays, and null
+// values, which are treated as placeholders when using the "placeholders"
+// config, instead of only allowing the empty value.
+type placeholderRules struct {
+	types map[string]string
+	// patterns are the keys in types, with the most specific pattern first.
+	patterns []string
+}
+
+// newPlaceholderRules returns the [defaultPlaceholderTypes] with the overrides
+// from the "placeholderTypes" config applied, where an empty type removes a
+// built-in entry.
+func newPlaceholderRules(overrides map[string]string) (*placeholderRules, error) {
+	types := maps.Clone(defaultPlaceholderTypes)
+	for pattern, typ := range overrides {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("placeholderTypes: invalid key pattern %q: %w", pattern, err)
+		}
+		if typ == "" {
+			delete(types, pattern)
+			continue
+		}
+		if !isValidTypeString(typ) {
+			return nil, fmt.Errorf("placeholderTypes: invalid type %q for %q, must be one of: array, boolean, integer, null, number, object, string", typ, pattern)
+		}
+		types[pattern] = typ
+	}
+
+	// Longer patterns are more specific, e.g "*PodAnnotations" over "*Annotations"
+	patterns := slices.SortedFunc(maps.Keys(types), func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a, b))
+	})
+	return &placeholderRules{types: types, patterns: patterns}, nil
+}
+
+// typeOf returns the type for the key name, from either an exact match or
+// else the most specific matching pattern.
+func (r *placeholderRules) typeOf(key string) (string, bool) {
+	if typ, ok := r.types[key]; ok {
+		return typ, true
+	}
+	for _, pattern := range r.patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return r.types[pattern], true
+		}
+	}
+	return "", false
+}
+
+// apply updates the schema of an empty map, empty array, or null value,
+// before the annotations are applied, so annotations take precedence:
+//
+//   - empty maps get "additionalProperties" using the type from the rules,
+//     or allowing any value
+//   - empty arrays get "items" using the type from the rules,
+//     or allowing any item
+//   - null values have their type removed, to be widened by [placeholderRules.widenNull]
+//
+// Returns true when the value is null.
+func (r *placeholderRules) apply(keyNode, valNode *yaml.Node, schema *Schema) bool {
+	if r == nil {
+		return false
+	}
+	var typ string
+	if keyNode != nil {
+		typ, _ = r.typeOf(keyNode.Value)
+	}
+
+	switch {
+	case valNode.Kind == yaml.MappingNode && len(valNode.Content) == 0:
+		schema.AdditionalProperties = placeholderSchema(typ)
+	case valNode.Kind == yaml.SequenceNode && len(valNode.Content) == 0:
+		schema.Items = placeholderSchema(typ)
+	case valNode.Kind == yaml.ScalarNode && valNode.ShortTag() == "!!null":
+		schema.Type = nil
+		return true
+	}
+	return false
+}
+
+// widenNull sets the type of a null value after its annotations are applied,
+// to either the annotated type or else the type from the rules, or "string",
+// together with "null". Values with a "$ref" get their type from the reference.
+func (r *placeholderRules) widenNull(keyNode *yaml.Node, schema *Schema) {
+	if schema.Type != nil && schema.Type != "null" {
+		schema.Type = appendNullType(schema.Type)
+		return
+	}
+	if schema.Ref != "" {
+		schema.Type = nil
+		return
+	}
+	typ := "string"
+	if keyNode != nil {
+		if ruleType, ok := r.typeOf(keyNode.Value); ok {
+			typ = ruleType
+		}
+	}
+	schema.Type = appendNullType(typ)
+}
+
+// placeholderSchema returns a schema allowing values of the type,
+// or any value when the type is empty.
+func placeholderSchema(typ string) *Schema {
+	if typ == "" {
+		return &Schema{}
+	}
+	return &Schema{Type: typ}
+}
@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestBuildJSONSchema_Placeholders(t *testing.T) {
+	config := &Config{Values: []string{"../testdata/placeholders/values.yaml"}, Draft: 2020, Indent: 4}
+
+	t.Run("disabled", func(t *testing.T) {
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), config)
+		require.NoError(t, err)
+		assert.Equal(t, &Schema{Type: "object", Properties: map[string]*Schema{}}, schema.Properties["podAnnotations"])
+		assert.Equal(t, &Schema{Type: "array"}, schema.Properties["extraEnv"])
+		assert.Equal(t, &Schema{Type: "null"}, schema.Properties["existingSecret"])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		config := *config
+		config.Placeholders = true
+		config.PlaceholderTypes = map[string]string{
+			"*Labels": "",
+			"ports":   "integer",
+		}
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		require.NoError(t, err)
+
+		tests := map[string]*Schema{
+			"podAnnotations": {Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: &Schema{Type: "string"}},
+			"podLabels":      {Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: &Schema{}},
+			"extraEnv":       {Type: "array", Items: &Schema{}},
+			"extraArgs":      {Type: "array", Items: &Schema{Type: "string"}},
+			"config":         {Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: &Schema{}},
+			"strictConfig":   {Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: SchemaFalse()},
+			"existingSecret": {Type: []any{"string", "null"}},
+			"replicas":       {Type: []any{"integer", "null"}},
+			"schedule":       {Type: []any{"string", "null"}},
+			"tolerations":    {Ref: "https://example.com/tolerations.json", RefReferrer: schema.Properties["tolerations"].RefReferrer},
+		}
+		for name, want := range tests {
+			assert.Equal(t, want, schema.Properties[name], name)
+		}
+
+		nested := schema.Properties["nested"]
+		assert.Equal(t, &Schema{Type: "string"}, nested.Properties["annotations"].AdditionalProperties)
+		assert.Equal(t, &Schema{Type: "integer"}, nested.Properties["ports"].Items)
+	})
+
+	t.Run("merged with non-empty values", func(t *testing.T) {
+		dir := t.TempDir()
+		valuesPath := filepath.Join(dir, "values.yaml")
+		prodPath := filepath.Join(dir, "values-prod.yaml")
+		require.NoError(t, os.WriteFile(valuesPath, []byte("podAnnotations: {}\nextraEnv: []\n"), 0o644))
+		require.NoError(t, os.WriteFile(prodPath, []byte("podAnnotations: {foo: bar}\nextraEnv: [{name: FOO}]\n"), 0o644))
+
+		schema, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &Config{
+			Values:       []string{valuesPath, prodPath},
+			Draft:        2020,
+			Indent:       4,
+			Placeholders: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &Schema{
+			Type:                 "object",
+			Properties:           map[string]*Schema{"foo": {Type: "string"}},
+			AdditionalProperties: &Schema{Type: "string"},
+		}, schema.Properties["podAnnotations"])
+		assert.Equal(t, &Schema{
+			Type:  "array",
+			Items: &Schema{Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}},
+		}, schema.Properties["extraEnv"])
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		config := *config
+		config.Placeholders = true
+		config.PlaceholderTypes = map[string]string{"*Annotations": "str"}
+		_, err := buildJSONSchema(ContextWithLogger(t.Context(), t), &config)
+		assert.EqualError(t, err, `placeholderTypes: invalid type "str" for "*Annotations", must be one of: array, boolean, integer, null, number, object, string`)
+	})
+}
+
+func TestNewPlaceholderRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		key       string
+		want      string
+		wantOK    bool
+		wantErr   string
+	}{
+		{name: "default pattern", key: "podAnnotations", want: "string", wantOK: true},
+		{name: "default name", key: "labels", want: "string", wantOK: true},
+		{name: "no match", key: "config"},
+		{name: "patterns are case sensitive", key: "PODANNOTATIONS"},
+		{
+			name:      "removed default",
+			overrides: map[string]string{"*Annotations": ""},
+			key:       "podAnnotations",
+		},
+		{
+			name:      "exact name over pattern",
+			overrides: map[string]string{"podAnnotations": "object"},
+			key:       "podAnnotations",
+			want:      "object",
+			wantOK:    true,
+		},
+		{
+			name:      "longer pattern over shorter pattern",
+			overrides: map[string]string{"*PodAnnotations": "object", "*Annotations": "integer"},
+			key:       "extraPodAnnotations",
+			want:      "object",
+			wantOK:    true,
+		},
+		{
+			name:      "character class",
+			overrides: map[string]string{"replica[s]": "integer"},
+			key:       "replicas",
+			want:      "integer",
+			wantOK:    true,
+		},
+		{
+			name:      "invalid pattern",
+			overrides: map[string]string{"[": "string"},
+			wantErr:   `placeholderTypes: invalid key pattern "[": syntax error in pattern`,
+		},
+		{
+			name:      "invalid type",
+			overrides: map[string]string{"foo": "map"},
+			wantErr:   `placeholderTypes: invalid type "map" for "foo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := newPlaceholderRules(tt.overrides)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			got, ok := rules.typeOf(tt.key)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
+
+func TestPlaceholderRules_Nil(t *testing.T) {
+	var rules *placeholderRules
+	schema := &Schema{Type: "null"}
+	assert.False(t, rules.apply(nil, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}, schema))
+	assert.Equal(t, &Schema{Type: "null"}, schema)
+}
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -147,10 +148,24 @@ type Schema struct {
 	// but the field is kept in this struct to allow bundled schemas to use them.
 	Definitions map[string]*Schema `json:"definitions,omitempty" yaml:"definitions,omitempty"`
 
+	// XSection is the "x-section" extension keyword, which holds the
+	// helm-docs "@section" that the property is listed under.
+	XSection string `json:"x-section,omitempty" yaml:"x-section,omitempty"`
+
 	SkipProperties   bool `json:"-" yaml:"-"`
 	MergeProperties  bool `json:"-" yaml:"-"`
 	Hidden           bool `json:"-" yaml:"-"`
+	InferEnum        bool `json:"-" yaml:"-"`
+	NoInfer          bool `json:"-" yaml:"-"`
 	RequiredByParent bool `json:"-" yaml:"-"`
+	// ItemsMode is the "itemsMode" annotation while parsing the values file,
+	// and then the items mode of arrays using [ItemsModeTuple] or
+	// [ItemsModeUnion] until the values files are merged.
+	ItemsMode string `json:"-" yaml:"-"`
+	// UnknownKeywords are the pointers to the keywords in the subschemas of
+	// "# @schema" annotations that are dropped as they aren't keywords of
+	// [Schema]. They are reported by [ValidateMetaschema].
+	UnknownKeywords []Ptr `json:"-" yaml:"-"`
 }
 
 func (s *Schema) IsZero() bool {
@@ -220,7 +235,8 @@ func (s *Schema) IsZero() bool {
 		len(s.Definitions) > 0,
 		len(s.DependentRequired) > 0,
 		s.Dependencies != nil,
-		len(s.DependentSchemas) > 0:
+		len(s.DependentSchemas) > 0,
+		len(s.XSection) > 0:
 		return false
 	default:
 		return true
@@ -356,20 +372,88 @@ func getSchemaURL(draft int) (string, error) {
 	}
 }
 
+// NodeError is an error from parsing a node in a values file, with the
+// location of the node so it can be reported as a diagnostic.
+type NodeError struct {
+	// File is the values file, or empty when unknown.
+	File string
+	// Document is the 1-based index of the YAML document in the values file,
+	// or 0 when the file only has a single document.
+	Document int
+	Line     int
+	Column   int
+	Ptr      Ptr
+	Err      error
+}
+
+// Error implements [error].
+func (e *NodeError) Error() string {
+	if e.Document > 0 {
+		return fmt.Sprintf("document %d: %s: %s", e.Document, e.Ptr, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Ptr, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// newNodeError returns a [NodeError] located at the key node,
+// or at the value node for array items that have no key.
+func newNodeError(ptr Ptr, keyNode, valNode *yaml.Node, err error) *NodeError {
+	node := keyNode
+	if node == nil {
+		node = valNode
+	}
+	return &NodeError{Line: node.Line, Column: node.Column, Ptr: ptr, Err: err}
+}
+
+// parseNode parses a single key of a values file, without support for the
+// "anchorRefs" config. See [nodeParser] for parsing a whole values file.
 func parseNode(ptr Ptr, keyNode, valNode *yaml.Node, useHelmDocs bool) (*Schema, error) {
+	return newNodeParser(nil, useHelmDocs, false).parse(ptr, keyNode, valNode)
+}
+
+func (p *nodeParser) parse(ptr Ptr, keyNode, valNode *yaml.Node) (*Schema, error) {
+	if valNode.Kind == yaml.AliasNode {
+		return p.parseAlias(ptr, keyNode, valNode)
+	}
+	if p.anchorRefs && p.aliased[valNode] {
+		return p.parseAnchor(ptr, keyNode, valNode)
+	}
+	if valNode.Anchor != "" {
+		p.visiting[valNode] = true
+		defer delete(p.visiting, valNode)
+	}
+	return p.parseValue(ptr, keyNode, valNode)
+}
+
+// parseValue parses the node, where valNode is not an alias.
+func (p *nodeParser) parseValue(ptr Ptr, keyNode, valNode *yaml.Node) (*Schema, error) {
 	schema := &Schema{}
 
 	var orderedMapProperties []*Schema
+	var itemsMode string
 
 	switch valNode.Kind {
 	case yaml.MappingNode:
-		orderedMapProperties = make([]*Schema, 0, len(valNode.Content)/2)
-		properties := make(map[string]*Schema, len(valNode.Content)/2)
+		if p.mappings[valNode] {
+			return nil, newNodeError(ptr, keyNode, valNode, errors.New(`merge key "<<" refers to a mapping that contains it`))
+		}
+		p.mappings[valNode] = true
+		defer delete(p.mappings, valNode)
+
+		pairs, err := mappingPairs(valNode)
+		if err != nil {
+			return nil, newNodeError(ptr, keyNode, valNode, err)
+		}
+		orderedMapProperties = make([]*Schema, 0, len(pairs))
+		properties := make(map[string]*Schema, len(pairs))
 		required := []string{}
-		for i := 0; i < len(valNode.Content); i += 2 {
-			childKeyNode := valNode.Content[i]
-			childValNode := valNode.Content[i+1]
-			childSchema, err := parseNode(ptr.Prop(childKeyNode.Value), childKeyNode, childValNode, useHelmDocs)
+		for _, pair := range pairs {
+			childKeyNode := pair.Key
+			childSchema, err := p.parse(ptr.Prop(childKeyNode.Value), childKeyNode, pair.Value)
 			if err != nil {
 				return nil, err
 			}
@@ -397,42 +481,42 @@ func parseNode(ptr Ptr, keyNode, valNode *yaml.Node, useHelmDocs bool) (*Schema,
 	case yaml.SequenceNode:
 		schema.Type = "array"
 
-		mergedItemSchema := &Schema{}
-		hasItems := false
+		itemsMode = p.itemsModeOf(keyNode, valNode)
+		if itemsMode == ItemsModeTuple && p.draft != 0 && p.draft < 2020 {
+			return nil, newNodeError(ptr, keyNode, valNode, fmt.Errorf("itemsMode %q requires draft 2020, as prefixItems is not supported by draft %d", ItemsModeTuple, p.draft))
+		}
 
+		// Hidden items are nil, as they still take up their position in tuples
+		items := make([]*Schema, len(valNode.Content))
 		for i, itemNode := range valNode.Content {
-			itemSchema, err := parseNode(ptr.Item(i), nil, itemNode, useHelmDocs)
+			itemSchema, err := p.parse(ptr.Item(i), nil, itemNode)
 			if err != nil {
 				return nil, err
 			}
 			if itemSchema != nil && !itemSchema.Hidden {
-				mergedItemSchema = mergeSchemas(mergedItemSchema, itemSchema)
-				hasItems = true
+				items[i] = itemSchema
 			}
 		}
-
-		if hasItems {
-			schema.Items = mergedItemSchema
-		}
+		applyItems(schema, items, itemsMode)
 
 	case yaml.ScalarNode:
 		schema.Type = getScalarType(valNode.ShortTag())
 	}
 
-	schemaComments, helmDocsComments := getComments(keyNode, valNode, useHelmDocs)
+	isNullPlaceholder := p.placeholders.apply(keyNode, valNode, schema)
 
-	if useHelmDocs {
-		helmDocs, err := ParseHelmDocsComment(helmDocsComments)
-		if err != nil {
-			return nil, fmt.Errorf("%s: parse helm-docs comment: %w", ptr, err)
-		}
-		if len(helmDocs.Path) == 0 || ptr.Equals(NewPtr(helmDocs.Path...)) {
-			schema.Description = helmDocs.Description
-		}
+	if err := p.processComments(ptr, keyNode, valNode, schema); err != nil {
+		return nil, err
 	}
 
-	if err := processComment(schema, schemaComments); err != nil {
-		return nil, fmt.Errorf("%s: parse @schema comments: %w", ptr, err)
+	if isNullPlaceholder {
+		p.placeholders.widenNull(keyNode, schema)
+	}
+	// Already applied by itemsModeOf above, and only kept on arrays using
+	// tuple or union items, to merge their items across values files
+	schema.ItemsMode = ""
+	if itemsMode != ItemsModeMerge {
+		schema.ItemsMode = itemsMode
 	}
 
 	if schema.SkipProperties && schema.IsType("object") {
@@ -449,6 +533,67 @@ func parseNode(ptr Ptr, keyNode, valNode *yaml.Node, useHelmDocs bool) (*Schema,
 	return schema, nil
 }
 
+// itemsModeOf returns the "itemsMode" annotation of the array, or else the
+// items mode from the config. Any errors in the annotations are instead
+// reported when processing them in [nodeParser.processComments].
+func (p *nodeParser) itemsModeOf(keyNode, valNode *yaml.Node) string {
+	schemaComments, _ := getComments(keyNode, valNode, p.useHelmDocs)
+	var annotated Schema
+	_ = processComment(&annotated, schemaComments)
+	return cmp.Or(annotated.ItemsMode, p.itemsMode)
+}
+
+// processComments applies the helm-docs and "# @schema" comments of the node
+// to the schema.
+func (p *nodeParser) processComments(ptr Ptr, keyNode, valNode *yaml.Node, schema *Schema) error {
+	schemaComments, helmDocsComments := getComments(keyNode, valNode, p.useHelmDocs)
+
+	var helmDocsDefault any
+	var hasHelmDocsDefault bool
+	if p.useHelmDocs {
+		helmDocs, err := ParseHelmDocsComment(helmDocsComments)
+		if err != nil {
+			return newNodeError(ptr, keyNode, valNode, fmt.Errorf("parse helm-docs comment: %w", err))
+		}
+		if len(helmDocs.Path) == 0 || ptr.Equals(NewPtr(helmDocs.Path...)) {
+			schema.Description = helmDocs.Description
+			schema.Type = helmDocsType(helmDocs, schema.Type)
+			helmDocsDefault, hasHelmDocsDefault = helmDocs.SchemaDefault()
+			schema.XSection = helmDocs.Section
+		}
+	}
+
+	if err := processComment(schema, schemaComments); err != nil {
+		return newNodeError(ptr, keyNode, valNode, fmt.Errorf("parse @schema comments: %w", err))
+	}
+	// Checked here, and not only when expanding the aliases once the values
+	// file is parsed, so the error includes the location in the values file
+	if _, err := p.refAliases.expand(schema.Ref); err != nil {
+		return newNodeError(ptr, keyNode, valNode, fmt.Errorf("parse @schema comments: $ref: %w", err))
+	}
+
+	// Applied last, so it can be checked against the annotated type
+	if hasHelmDocsDefault && schema.Default == nil && valueMatchesAnyType(schema.Type, helmDocsDefault) {
+		schema.Default = helmDocsDefault
+	}
+	return nil
+}
+
+// helmDocsType returns the type of the helm-docs comment, or else the type
+// inferred from the value. Null values stay allowed, such as for the common
+// "# -- (string)" above "existingSecret: null".
+func helmDocsType(helmDocs HelmDocsComment, inferred any) any {
+	typ := helmDocs.SchemaType()
+	switch {
+	case typ == "":
+		return inferred
+	case inferred == "null":
+		return []any{typ, "null"}
+	default:
+		return typ
+	}
+}
+
 func (schema *Schema) Subschemas() iter.Seq2[Ptr, *Schema] {
 	return func(yield func(Ptr, *Schema) bool) {
 		for index, subSchema := range schema.AllOf {
@@ -560,25 +705,40 @@ func (schema *Schema) Subschemas() iter.Seq2[Ptr, *Schema] {
 }
 
 func (s *Schema) ParseRef() (*url.URL, error) {
-	if s == nil || s.Ref == "" {
+	if s == nil {
 		return nil, nil
 	}
-	ref, err := url.Parse(s.Ref)
+	return parseRefWithReferrer(s.Ref, s.RefReferrer)
+}
+
+// ParseDynamicRef is the "$dynamicRef" equivalent of [Schema.ParseRef].
+func (s *Schema) ParseDynamicRef() (*url.URL, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return parseRefWithReferrer(s.DynamicRef, s.DynamicRefReferrer)
+}
+
+func parseRefWithReferrer(refString string, referrer Referrer) (*url.URL, error) {
+	if refString == "" {
+		return nil, nil
+	}
+	ref, err := url.Parse(refString)
 	if err != nil {
 		return nil, err
 	}
-	if s.RefReferrer.IsZero() {
+	if referrer.IsZero() {
 		return ref, nil
 	}
 	if ref.Scheme != "" && ref.Scheme != "file" {
 		// Only have custom logic when $ref is a local file
-		return ref, nil
+		return localSchemeRefWithReferrer(ref, referrer), nil
 	}
 	refFile, err := ParseRefFileURL(ref)
 	if err != nil {
 		return nil, err
 	}
-	return s.RefReferrer.Join(refFile), nil
+	return referrer.Join(refFile), nil
 }
 
 func (s *Schema) SetReferrer(ref Referrer) {
@@ -163,6 +163,7 @@ func TestSchemaIsZero(t *testing.T) {
 		{name: "DependentSchemas", schema: &Schema{DependentSchemas: exampleMap}},
 		{name: "Defs", schema: &Schema{Defs: exampleMap}},
 		{name: "Definitions", schema: &Schema{Definitions: exampleMap}},
+		{name: "XSection", schema: &Schema{XSection: exampleString}},
 	}
 
 	for _, tt := range tests {
@@ -704,6 +705,100 @@ func TestParseNode(t *testing.T) {
 	}
 }
 
+func TestParseNode_HelmDocs(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      string
+		useHelmDocs bool
+		want        *Schema
+	}{
+		{
+			name: "type",
+			values: `# -- (int) Replicas
+key: null`,
+			useHelmDocs: true,
+			want:        &Schema{Type: []any{"integer", "null"}, Description: "Replicas"},
+		},
+		{
+			name: "template type",
+			values: `# -- (tpl/array) Extra env
+key: []`,
+			useHelmDocs: true,
+			want:        &Schema{Type: "string", Description: "Extra env"},
+		},
+		{
+			name: "custom type is ignored",
+			values: `# -- (myType) Custom
+key: foo`,
+			useHelmDocs: true,
+			want:        &Schema{Type: "string", Description: "Custom"},
+		},
+		{
+			name: "default and section",
+			values: `# -- Tag
+# @default -- ` + "`latest`" + `
+# @section -- Image
+key: ""`,
+			useHelmDocs: true,
+			want:        &Schema{Type: "string", Description: "Tag", Default: "latest", XSection: "Image"},
+		},
+		{
+			name: "default of another type",
+			values: `# -- Port
+# @default -- See values.yaml
+key: 80`,
+			useHelmDocs: true,
+			want:        &Schema{Type: "integer", Description: "Port"},
+		},
+		{
+			name: "default checked against the annotated type",
+			values: `# @schema type: [string, null]
+# -- Tag
+# @default -- ` + "`latest`" + `
+key: null`,
+			useHelmDocs: true,
+			want:        &Schema{Type: []any{"string", "null"}, Description: "Tag", Default: "latest"},
+		},
+		{
+			name: "schema annotations take precedence",
+			values: `# @schema type: [integer, null]; default: 2; x-section: Other
+# -- (int) Replicas
+# @default -- 1
+# @section -- General
+key: 1`,
+			useHelmDocs: true,
+			want:        &Schema{Type: []any{"integer", "null"}, Description: "Replicas", Default: 2, XSection: "Other"},
+		},
+		{
+			name: "path of other key",
+			values: `# other -- (int) Other
+# @section -- Other
+key: foo`,
+			useHelmDocs: true,
+			want:        &Schema{Type: "string"},
+		},
+		{
+			name: "without use-helm-docs",
+			values: `# -- (int) Replicas
+# @section -- General
+key: foo`,
+			want: &Schema{Type: "string"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(tt.values), &doc))
+			root := doc.Content[0]
+
+			schema, err := parseNode(NewPtr(), nil, root, tt.useHelmDocs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schema.Properties["key"])
+		})
+	}
+}
+
 func TestParseNode_Error(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -755,6 +850,43 @@ func TestParseNode_Error(t *testing.T) {
 	}
 }
 
+func TestParseNode_NodeError(t *testing.T) {
+	var node yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte("foo:\n  bar: 1 # @schema hidden: foo\nlist:\n  - 1 # @schema hidden: foo\n"), &node))
+	root := node.Content[0]
+
+	tests := []struct {
+		name    string
+		index   int
+		wantErr *NodeError
+	}{
+		{
+			name:    "map key",
+			index:   0,
+			wantErr: &NodeError{Line: 2, Column: 3, Ptr: NewPtr("foo", "bar")},
+		},
+		{
+			name:    "array item",
+			index:   2,
+			wantErr: &NodeError{Line: 4, Column: 5, Ptr: NewPtr("list").Item(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyNode := root.Content[tt.index]
+			_, err := parseNode(NewPtr(keyNode.Value), keyNode, root.Content[tt.index+1], false)
+			var nodeErr *NodeError
+			require.ErrorAs(t, err, &nodeErr)
+			assert.Equal(t, tt.wantErr.Line, nodeErr.Line)
+			assert.Equal(t, tt.wantErr.Column, nodeErr.Column)
+			assert.Equal(t, tt.wantErr.Ptr, nodeErr.Ptr)
+			assert.ErrorContains(t, err, tt.wantErr.Ptr.String()+": parse @schema comments: hidden: invalid boolean")
+			assert.ErrorContains(t, errors.Unwrap(err), "parse @schema comments: hidden: invalid boolean")
+		})
+	}
+}
+
 func TestSchemaSubschemas_order(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -1142,6 +1274,70 @@ func TestSchemaParseRef(t *testing.T) {
 			},
 			want: mustParseURL("http://example.com/schema.json"),
 		},
+
+		{
+			name: "crd when no referrer",
+			schema: &Schema{
+				Ref: "crd:crds/foo.yaml?version=v1",
+			},
+			want: mustParseURL("crd:crds/foo.yaml?version=v1"),
+		},
+		{
+			name: "crd when file referrer",
+			schema: &Schema{
+				Ref:         "crd:../crds/foo.yaml?version=v1",
+				RefReferrer: ReferrerDir("/some/abs/path/"),
+			},
+			want: mustParseURL("crd:///some/abs/crds/foo.yaml?version=v1"),
+		},
+		{
+			name: "crd with slashes when file referrer",
+			schema: &Schema{
+				Ref:         "crd://crds/foo.yaml",
+				RefReferrer: ReferrerDir("/some/abs/path/"),
+			},
+			want: mustParseURL("crd:///some/abs/path/crds/foo.yaml"),
+		},
+		{
+			name: "crd abs path when file referrer",
+			schema: &Schema{
+				Ref:         "crd:///crds/foo.yaml",
+				RefReferrer: ReferrerDir("/some/abs/path/"),
+			},
+			want: mustParseURL("crd:///crds/foo.yaml"),
+		},
+		{
+			name: "chart when file referrer",
+			schema: &Schema{
+				Ref:         "chart:charts/mylib-1.0.0.tgz",
+				RefReferrer: ReferrerDir("/some/abs/path/"),
+			},
+			want: mustParseURL("chart:///some/abs/path/charts/mylib-1.0.0.tgz"),
+		},
+		{
+			name: "oci layout when file referrer",
+			schema: &Schema{
+				Ref:         "oci:layout?tag=1.0.0",
+				RefReferrer: ReferrerDir("/some/abs/path/"),
+			},
+			want: mustParseURL("oci:///some/abs/path/layout?tag=1.0.0"),
+		},
+		{
+			name: "oci registry when file referrer",
+			schema: &Schema{
+				Ref:         "oci://ghcr.io/charts/mylib:1.0.0",
+				RefReferrer: ReferrerDir("/some/abs/path/"),
+			},
+			want: mustParseURL("oci://ghcr.io/charts/mylib:1.0.0"),
+		},
+		{
+			name: "crd when http referrer",
+			schema: &Schema{
+				Ref:         "crd:crds/foo.yaml",
+				RefReferrer: ReferrerURL(mustParseURL("http://example.com/")),
+			},
+			want: mustParseURL("crd:crds/foo.yaml"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -1165,6 +1361,19 @@ func TestSchemaParseRef(t *testing.T) {
 					assert.Equal(t, want.String(), ref.String())
 				})
 			}
+
+			t.Run("dynamic ref", func(t *testing.T) {
+				var schema *Schema
+				if tt.schema != nil {
+					schema = &Schema{
+						DynamicRef:         tt.schema.Ref,
+						DynamicRefReferrer: tt.schema.RefReferrer,
+					}
+				}
+				ref, err := schema.ParseDynamicRef()
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, ref)
+			})
 		})
 	}
 }
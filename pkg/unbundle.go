@@ -0,0 +1,244 @@
+package pkg
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// UnbundledSchema is a schema that was removed from the root "$defs"
+// by [UnbundleSchema].
+type UnbundledSchema struct {
+	// DefName is the name the schema had inside the root "$defs".
+	DefName string
+	// FileName is the relative file name the schema should be written to,
+	// which is also what all "$ref" to this schema now point to.
+	FileName string
+	Schema   *Schema
+}
+
+// UnbundleSchema is the inverse of [BundleSchema]. It removes every "$defs"
+// entry that has an "$id" from the root schema and returns them together with
+// the file name they should be written to. All "$ref" that pointed to those
+// entries, either by their "$id" or by a "#/$defs/..." pointer, are rewritten
+// to relative file paths so the returned schemas can be stored side by side
+// in the same directory.
+//
+// The "$id" of each unbundled schema is removed, as it is assigned again
+// from its file path when bundling.
+//
+// This function will update the schema in-place.
+func UnbundleSchema(schema *Schema) ([]UnbundledSchema, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("nil schema")
+	}
+
+	var result []UnbundledSchema
+	fileNames := map[string]string{} // $defs name => file name
+	usedFileNames := map[string]string{}
+	for name, def := range iterMapOrdered(schema.Defs) {
+		if def.ID == "" {
+			continue
+		}
+		fileName, err := unbundledFileName(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", NewPtr("$defs", name), err)
+		}
+		if other, ok := usedFileNames[fileName]; ok {
+			return nil, fmt.Errorf("%s: file name %q is already used by %s", NewPtr("$defs", name), fileName, NewPtr("$defs", other))
+		}
+		usedFileNames[fileName] = name
+		fileNames[name] = fileName
+		result = append(result, UnbundledSchema{DefName: name, FileName: fileName, Schema: def})
+	}
+
+	// Rewrite refs before deleting anything, as the rewrite needs
+	// the "$id" of each of the "$defs" to find what to point to.
+	if err := unbundleChangeRefsRec(nil, schema, schema.Defs, fileNames); err != nil {
+		return nil, err
+	}
+
+	for _, unbundled := range result {
+		unbundled.Schema.ID = ""
+		delete(schema.Defs, unbundled.DefName)
+	}
+	if len(schema.Defs) == 0 {
+		schema.Defs = nil
+	}
+	return result, nil
+}
+
+// bundledNameSuffixRegexp matches the "_2" suffix that [generateBundledName]
+// adds after the file extension on name collisions, e.g "foo.json_2".
+var bundledNameSuffixRegexp = regexp.MustCompile(`^(.+?)(\.[^._]+)_(\d+)$`)
+
+// unbundledFileName turns a "$defs" name into a file name, moving any
+// collision suffix added by [generateBundledName] to before the file
+// extension and ensuring the file has a JSON or YAML file extension.
+func unbundledFileName(name string) (string, error) {
+	switch {
+	case name == "", name == ".", name == "..", strings.ContainsAny(name, `/\`):
+		return "", fmt.Errorf("cannot use %q as a file name", name)
+	}
+	fileName := bundledNameSuffixRegexp.ReplaceAllString(name, "${1}_${3}${2}")
+	switch path.Ext(fileName) {
+	case ".json", ".yml", ".yaml":
+	default:
+		fileName += ".json"
+	}
+	if _, err := filepath.Localize(fileName); err != nil {
+		return "", fmt.Errorf("cannot use %q as a file name: %w", name, err)
+	}
+	return fileName, nil
+}
+
+func unbundleChangeRefsRec(ptr Ptr, schema *Schema, defs map[string]*Schema, fileNames map[string]string) error {
+	for subPath, subSchema := range schema.Subschemas() {
+		if err := unbundleChangeRefsRec(ptr.Add(subPath), subSchema, defs, fileNames); err != nil {
+			return err
+		}
+	}
+
+	if schema.Ref == "" {
+		return nil
+	}
+
+	if after, ok := strings.CutPrefix(schema.Ref, "#"); ok {
+		refPtr := ParsePtr(after)
+		if len(refPtr) < 2 || refPtr[0] != "$defs" {
+			return nil
+		}
+		fileName, ok := fileNames[refPtr[1]]
+		if !ok {
+			return nil
+		}
+		schema.Ref = unbundledRef(fileName, refPtr[2:].String())
+		return nil
+	}
+
+	ref, err := url.Parse(schema.Ref)
+	if err != nil {
+		return fmt.Errorf("%s: parse $ref as URL: %w", ptr.Prop("$ref"), err)
+	}
+	name, ok := findDefNameByRef(defs, ref)
+	if !ok {
+		return nil
+	}
+	fileName, ok := fileNames[name]
+	if !ok {
+		return nil
+	}
+	schema.Ref = unbundledRef(fileName, ref.Fragment)
+	return nil
+}
+
+func unbundledRef(fileName, fragment string) string {
+	if fragment == "" || fragment == "/" {
+		return fileName
+	}
+	return fmt.Sprintf("%s#%s", fileName, fragment)
+}
+
+// verifyUnbundled bundles the files written by [UnbundleSchema] in dir back
+// together and checks that the result is semantically equal to the original
+// bundled schema.
+//
+// The comparison is done after removing all "$id" with [BundleRemoveIDs],
+// as the "$id" of the re-bundled schemas are derived from their new file
+// paths and not from their original "$id".
+//
+// The want schema is modified in-place during the comparison.
+func verifyUnbundled(ctx context.Context, want *Schema, rootFile, dir string, fileNames map[string]string) error {
+	// The re-bundled "$defs" are named after their file names
+	renameDefs(want, fileNames)
+	if err := BundleRemoveIDs(want); err != nil {
+		return fmt.Errorf("remove $id from original schema: %w", err)
+	}
+	RemoveUnusedDefs(want)
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return fmt.Errorf("open output directory: %w", err)
+	}
+	defer closeIgnoreError(root)
+
+	loader := NewCacheLoader(NewFileLoader((*RootFS)(root), dir))
+	content, err := fs.ReadFile((*RootFS)(root), rootFile)
+	if err != nil {
+		return fmt.Errorf("read unbundled root schema: %w", err)
+	}
+	var got Schema
+	if err := json.Unmarshal(content, &got); err != nil {
+		return fmt.Errorf("parse unbundled root schema: %w", err)
+	}
+	got.SetReferrer(ReferrerDir(dir))
+	// Don't log the files loaded during verification, which were just written
+	if err := bundleWithLoader(ContextWithLogger(ctx, NewLogger(io.Discard)), loader, &got, dir, true); err != nil {
+		return fmt.Errorf("re-bundle: %w", err)
+	}
+
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		return fmt.Errorf("encode original schema: %w", err)
+	}
+	gotJSON, err := json.Marshal(&got)
+	if err != nil {
+		return fmt.Errorf("encode re-bundled schema: %w", err)
+	}
+	if !bytes.Equal(wantJSON, gotJSON) {
+		return fmt.Errorf("re-bundling the unbundled files does not produce the original schema")
+	}
+	return nil
+}
+
+// renameDefs renames the root "$defs" entries and updates any "#/$defs/..."
+// references pointing to them.
+func renameDefs(schema *Schema, renames map[string]string) {
+	if len(schema.Defs) == 0 {
+		return
+	}
+	renamed := make(map[string]*Schema, len(schema.Defs))
+	for name, def := range schema.Defs {
+		renamed[cmp.Or(renames[name], name)] = def
+	}
+	schema.Defs = renamed
+	renameDefRefsRec(schema, renames)
+}
+
+func renameDefRefsRec(schema *Schema, renames map[string]string) {
+	for _, subSchema := range schema.Subschemas() {
+		renameDefRefsRec(subSchema, renames)
+	}
+	refPtr := ParsePtr(schema.Ref)
+	if !strings.HasPrefix(schema.Ref, "#/") || len(refPtr) < 2 || refPtr[0] != "$defs" {
+		return
+	}
+	if newName, ok := renames[refPtr[1]]; ok {
+		schema.Ref = "#" + NewPtr("$defs", newName).Add(refPtr[2:]).String()
+	}
+}
+
+// cloneSchemaJSON returns a deep copy of the schema by encoding it as JSON
+// and decoding it again. Fields that are not part of the JSON output, such as
+// [Schema.RefReferrer], are not copied.
+func cloneSchemaJSON(schema *Schema) (*Schema, error) {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var clone Schema
+	if err := json.Unmarshal(b, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
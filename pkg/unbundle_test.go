@@ -0,0 +1,291 @@
+package pkg
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnbundleSchema(t *testing.T) {
+	tests := []struct {
+		name      string
+		schema    *Schema
+		want      *Schema
+		wantFiles []UnbundledSchema
+	}{
+		{
+			name:   "no defs",
+			schema: &Schema{Type: "object"},
+			want:   &Schema{Type: "object"},
+		},
+		{
+			name: "ref by id",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "https://example.com/foo.json#/properties/bar"},
+				},
+				Defs: map[string]*Schema{
+					"foo.json": {ID: "https://example.com/foo.json", Type: "object"},
+				},
+			},
+			want: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "foo.json#/properties/bar"},
+				},
+			},
+			wantFiles: []UnbundledSchema{
+				{DefName: "foo.json", FileName: "foo.json", Schema: &Schema{Type: "object"}},
+			},
+		},
+		{
+			name: "ref by pointer",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "#/$defs/foo.json/items"},
+					"bar": {Ref: "#/$defs/bar.json"},
+				},
+				Defs: map[string]*Schema{
+					"foo.json": {ID: "foo.json"},
+					"bar.json": {Type: "string"},
+				},
+			},
+			want: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "foo.json#/items"},
+					"bar": {Ref: "#/$defs/bar.json"},
+				},
+				Defs: map[string]*Schema{
+					"bar.json": {Type: "string"},
+				},
+			},
+			wantFiles: []UnbundledSchema{
+				{DefName: "foo.json", FileName: "foo.json", Schema: &Schema{}},
+			},
+		},
+		{
+			name: "refs between defs",
+			schema: &Schema{
+				Ref: "a.json",
+				Defs: map[string]*Schema{
+					"a.json": {ID: "dir/a.json", Items: &Schema{Ref: "dir/b.yaml"}},
+					"b.yaml": {ID: "dir/b.yaml"},
+				},
+			},
+			want: &Schema{
+				Ref: "a.json",
+			},
+			wantFiles: []UnbundledSchema{
+				{DefName: "a.json", FileName: "a.json", Schema: &Schema{Items: &Schema{Ref: "b.yaml"}}},
+				{DefName: "b.yaml", FileName: "b.yaml", Schema: &Schema{}},
+			},
+		},
+		{
+			name: "name collision suffix",
+			schema: &Schema{
+				Defs: map[string]*Schema{
+					"foo.json_2": {ID: "dir/foo.json"},
+					"noext":      {ID: "https://example.com/noext"},
+				},
+			},
+			want: &Schema{},
+			wantFiles: []UnbundledSchema{
+				{DefName: "foo.json_2", FileName: "foo_2.json", Schema: &Schema{}},
+				{DefName: "noext", FileName: "noext.json", Schema: &Schema{}},
+			},
+		},
+		{
+			name: "unrelated refs are kept",
+			schema: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "#/properties/bar"},
+					"bar": {Ref: "https://example.com/other.json"},
+				},
+			},
+			want: &Schema{
+				Properties: map[string]*Schema{
+					"foo": {Ref: "#/properties/bar"},
+					"bar": {Ref: "https://example.com/other.json"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := UnbundleSchema(tt.schema)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tt.schema)
+			assert.Equal(t, tt.wantFiles, files)
+		})
+	}
+}
+
+func TestUnbundleSchema_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  *Schema
+		wantErr string
+	}{
+		{
+			name:    "nil schema",
+			schema:  nil,
+			wantErr: "nil schema",
+		},
+		{
+			name: "file name collision",
+			schema: &Schema{
+				Defs: map[string]*Schema{
+					"foo":      {ID: "foo"},
+					"foo.json": {ID: "dir/foo.json"},
+				},
+			},
+			wantErr: `/$defs/foo.json: file name "foo.json" is already used by /$defs/foo`,
+		},
+		{
+			name: "invalid file name",
+			schema: &Schema{
+				Defs: map[string]*Schema{
+					"..": {ID: "foo/.."},
+				},
+			},
+			wantErr: `/$defs/..: cannot use ".." as a file name`,
+		},
+		{
+			name: "invalid ref",
+			schema: &Schema{
+				Ref: "::",
+				Defs: map[string]*Schema{
+					"foo.json": {ID: "foo.json"},
+				},
+			},
+			wantErr: "/$ref: parse $ref as URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnbundleSchema(tt.schema)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnbundledFileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "foo.json", want: "foo.json"},
+		{name: "foo.schema.json", want: "foo.schema.json"},
+		{name: "foo.yaml", want: "foo.yaml"},
+		{name: "foo.yml", want: "foo.yml"},
+		{name: "foo", want: "foo.json"},
+		{name: "foo.json_2", want: "foo_2.json"},
+		{name: "foo.schema.json_12", want: "foo.schema_12.json"},
+		{name: "foo_2", want: "foo_2.json"},
+		{name: "", wantErr: `cannot use "" as a file name`},
+		{name: "a/b", wantErr: `cannot use "a/b" as a file name`},
+		{name: `a\b`, wantErr: `cannot use "a\\b" as a file name`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unbundledFileName(tt.name)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRenameDefs(t *testing.T) {
+	schema := &Schema{
+		Properties: map[string]*Schema{
+			"foo":   {Ref: "#/$defs/foo.json_2/items"},
+			"bar":   {Ref: "#/$defs/bar.json"},
+			"other": {Ref: "#/properties/foo"},
+		},
+		Defs: map[string]*Schema{
+			"foo.json_2": {},
+			"bar.json":   {},
+		},
+	}
+	renameDefs(schema, map[string]string{"foo.json_2": "foo_2.json"})
+
+	assert.Equal(t, &Schema{
+		Properties: map[string]*Schema{
+			"foo":   {Ref: "#/$defs/foo_2.json/items"},
+			"bar":   {Ref: "#/$defs/bar.json"},
+			"other": {Ref: "#/properties/foo"},
+		},
+		Defs: map[string]*Schema{
+			"foo_2.json": {},
+			"bar.json":   {},
+		},
+	}, schema)
+}
+
+func TestVerifyUnbundled_Mismatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, writeUnbundledFile(io.Discard, dir+"/root.json", &Schema{Type: "object"}, "  "))
+
+	ctx := ContextWithLogger(t.Context(), t)
+	err := verifyUnbundled(ctx, &Schema{Type: "string"}, "root.json", dir, nil)
+	assert.ErrorContains(t, err, "re-bundling the unbundled files does not produce the original schema")
+}
+
+func TestVerifyUnbundled_Errors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, writeUnbundledFile(io.Discard, dir+"/ref.json", &Schema{Ref: "missing.json"}, "  "))
+
+	tests := []struct {
+		name     string
+		want     *Schema
+		dir      string
+		rootFile string
+		wantErr  string
+	}{
+		{
+			name:     "missing dir",
+			want:     &Schema{},
+			dir:      dir + "/does-not-exist",
+			rootFile: "root.json",
+			wantErr:  "open output directory",
+		},
+		{
+			name:     "missing root",
+			want:     &Schema{},
+			dir:      dir,
+			rootFile: "root.json",
+			wantErr:  "read unbundled root schema",
+		},
+		{
+			name:     "missing ref",
+			want:     &Schema{},
+			dir:      dir,
+			rootFile: "ref.json",
+			wantErr:  "re-bundle: bundle schemas: /$ref:",
+		},
+		{
+			name:     "invalid original",
+			want:     &Schema{Ref: "missing.json"},
+			dir:      dir,
+			rootFile: "ref.json",
+			wantErr:  "remove $id from original schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ContextWithLogger(t.Context(), t)
+			err := verifyUnbundled(ctx, tt.want, tt.rootFile, tt.dir, nil)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,526 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// valuesWriter renders the values.yaml file of [ValuesFromSchema], and the
+// annotations of [annotateValues].
+type valuesWriter struct {
+	ctx    context.Context
+	root   *Schema
+	indent int
+	// descriptions adds "description" to the annotations, instead of writing
+	// it as a helm-docs comment.
+	descriptions bool
+}
+
+// ValuesFromSchema returns a starter values.yaml file for the properties of
+// the schema, which is the inverse of generating a schema from values:
+//
+//   - values are taken from "default", "const", "enum" or "examples", or else
+//     a placeholder for the type, such as "" for strings and {} for objects
+//   - required keys are written as-is, while optional keys are commented out
+//   - each key's "description" is written as a helm-docs "# --" comment, and
+//     also as a quoted "description" annotation if it has multiple lines
+//   - other keywords are written as "# @schema" annotations, so generating
+//     a schema from the values file reproduces the schema
+//
+// Keywords without a "# @schema" annotation, such as "format", are left out.
+//
+// The schema should be bundled using [BundleSchema] and [BundleRemoveIDs], so
+// that all "$ref" are local JSON pointers. Local references are inlined, which
+// also means that recursive references are only followed once, while other
+// references are kept as "$ref" annotations. The generated schema therefore
+// has no "$defs", and repeats the inlined subschemas instead.
+func ValuesFromSchema(ctx context.Context, schema *Schema, indent int) ([]byte, error) {
+	if indent <= 0 {
+		return nil, errors.New("indentation must be a positive number")
+	}
+	w := valuesWriter{ctx: ctx, root: schema, indent: indent}
+	root, refs, err := w.flatten(nil, schema, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(root.Properties) == 0 {
+		return nil, errors.New("schema has no properties to generate values from")
+	}
+	lines, err := w.properties(nil, root, 0, refs)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// properties returns the lines of all properties of the object schema,
+// separated by empty lines.
+//
+// The empty lines are needed as only the last comment group above a key is
+// read when generating a schema, which would otherwise pick up the comments
+// of a commented-out key.
+func (w valuesWriter) properties(ptr Ptr, parent *Schema, depth int, refs []string) ([]string, error) {
+	var lines []string
+	for _, name := range slices.Sorted(maps.Keys(parent.Properties)) {
+		prop := parent.Properties[name]
+		if prop.Kind() == SchemaKindFalse {
+			// Disallowed keys can't have a value
+			continue
+		}
+		propPtr := ptr.Prop(name)
+		schema, propRefs, err := w.flatten(propPtr, prop, refs)
+		if err != nil {
+			return nil, err
+		}
+		block, err := w.key(propPtr, name, schema, slices.Contains(parent.Required, name), depth, propRefs)
+		if err != nil {
+			return nil, err
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+	}
+	return lines, nil
+}
+
+// key returns the lines of a single key, including its comments, and
+// commented out unless it is required.
+func (w valuesWriter) key(ptr Ptr, name string, schema *Schema, required bool, depth int, refs []string) ([]string, error) {
+	pad := strings.Repeat(" ", depth*w.indent)
+	value, nested := valueFromSchema(schema)
+
+	annotations, err := w.annotations(ptr, schema, inferredValueType(value, nested), required, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	if len(annotations) > 0 {
+		lines = append(lines, pad+"# @schema "+strings.Join(annotations, "; "))
+	}
+	if schema.Description != "" {
+		for i, line := range strings.Split(schema.Description, "\n") {
+			if i == 0 {
+				lines = append(lines, pad+"# -- "+line)
+			} else {
+				lines = append(lines, strings.TrimRight(pad+"# "+line, " "))
+			}
+		}
+	}
+
+	if nested {
+		keyLines, err := w.encode(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ptr, err)
+		}
+		lines = append(lines, pad+keyLines[0]+":")
+		children, err := w.properties(ptr, schema, depth+1, refs)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, children...)
+	} else {
+		valueLines, err := w.encode(map[string]any{name: value})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ptr, err)
+		}
+		for _, line := range valueLines {
+			lines = append(lines, pad+line)
+		}
+	}
+
+	if !required {
+		// Comment out after the indentation, so removing the "# " restores
+		// the key including its comments.
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = pad + "# " + strings.TrimPrefix(line, pad)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// annotations returns the "# @schema" annotations that reproduce the schema
+// when generating a schema from a value of the inferred type.
+func (w valuesWriter) annotations(ptr Ptr, schema *Schema, inferredType string, required bool, refs []string) ([]string, error) {
+	var annotations []string
+	add := func(name, value string) {
+		if strings.ContainsAny(value, ";\n") {
+			LoggerFromContext(w.ctx).Logf("Warning: %s: skipping %q annotation, as ';' and newlines are not supported in # @schema comments", ptr, name)
+			return
+		}
+		if value == "" {
+			annotations = append(annotations, name)
+			return
+		}
+		annotations = append(annotations, name+": "+value)
+	}
+	addJSON := func(name string, value any) error {
+		b, err := marshalCompactJSON(value)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", ptr, name, err)
+		}
+		add(name, string(b))
+		return nil
+	}
+	addFloat := func(name string, value *float64) {
+		if value != nil {
+			add(name, strconv.FormatFloat(*value, 'f', -1, 64))
+		}
+	}
+	addUint := func(name string, value *uint64) {
+		if value != nil {
+			add(name, strconv.FormatUint(*value, 10))
+		}
+	}
+	addInlined := func(name string, value any) error {
+		if err := w.inlineRefs(ptr.Prop(name), value, refs); err != nil {
+			return err
+		}
+		return addJSON(name, value)
+	}
+
+	if typ := typeAnnotation(schema.Type); typ != "" && typ != inferredType {
+		add("type", typ)
+	}
+	if schema.Ref != "" {
+		add("$ref", schema.Ref)
+	}
+	var errs []error
+	if schema.Enum != nil {
+		errs = append(errs, addJSON("enum", schema.Enum))
+	}
+	if schema.Const != nil {
+		errs = append(errs, addJSON("const", schema.Const))
+	}
+	if schema.Default != nil {
+		errs = append(errs, addJSON("default", schema.Default))
+	}
+	if schema.Examples != nil {
+		errs = append(errs, addJSON("examples", schema.Examples))
+	}
+	if schema.Title != "" {
+		add("title", schema.Title)
+	}
+	switch {
+	case strings.Contains(schema.Description, "\n") || strings.HasPrefix(schema.Description, `"`):
+		// Helm-docs comments join the lines with spaces, so keep the
+		// newlines in a quoted annotation, which takes precedence.
+		add("description", strconv.Quote(schema.Description))
+	case w.descriptions && schema.Description != "":
+		add("description", schema.Description)
+	}
+	if schema.XSection != "" {
+		add("x-section", schema.XSection)
+	}
+	if schema.Pattern != "" {
+		add("pattern", schema.Pattern)
+	}
+	addUint("minLength", schema.MinLength)
+	addUint("maxLength", schema.MaxLength)
+	addFloat("multipleOf", schema.MultipleOf)
+	addFloat("minimum", schema.Minimum)
+	addFloat("maximum", schema.Maximum)
+	addUint("minItems", schema.MinItems)
+	addUint("maxItems", schema.MaxItems)
+	if schema.UniqueItems {
+		add("uniqueItems", "")
+	}
+	if schema.Items != nil && schema.Items.Kind() == SchemaKindObject {
+		items, itemsRefs, err := w.flatten(ptr.Prop("items"), schema.Items, refs)
+		if err != nil {
+			return nil, err
+		}
+		if typ := typeAnnotation(items.Type); typ != "" {
+			add("item", typ)
+		}
+		if items.Enum != nil {
+			errs = append(errs, addJSON("itemEnum", items.Enum))
+		}
+		if items.Pattern != "" {
+			add("itemPattern", items.Pattern)
+		}
+		if items.Ref != "" {
+			add("itemRef", items.Ref)
+		}
+		if items.Required != nil {
+			errs = append(errs, addJSON("itemRequired", items.Required))
+		}
+		if items.Properties != nil {
+			if err := w.inlineRefs(ptr.Prop("items", "properties"), items.Properties, itemsRefs); err != nil {
+				return nil, err
+			}
+			errs = append(errs, addJSON("itemProperties", items.Properties))
+		}
+	}
+	addUint("minProperties", schema.MinProperties)
+	addUint("maxProperties", schema.MaxProperties)
+	if schema.PatternProperties != nil {
+		errs = append(errs, addInlined("patternProperties", schema.PatternProperties))
+	}
+	if schema.AdditionalProperties != nil {
+		errs = append(errs, addInlined("additionalProperties", schema.AdditionalProperties))
+	}
+	if schema.UnevaluatedProperties != nil {
+		errs = append(errs, addInlined("unevaluatedProperties", schema.UnevaluatedProperties))
+	}
+	if schema.AnyOf != nil {
+		errs = append(errs, addInlined("anyOf", schema.AnyOf))
+	}
+	if schema.OneOf != nil {
+		errs = append(errs, addInlined("oneOf", schema.OneOf))
+	}
+	if schema.Not != nil {
+		errs = append(errs, addInlined("not", schema.Not))
+	}
+	if schema.ReadOnly {
+		add("readOnly", "")
+	}
+	if schema.Deprecated {
+		add("deprecated", "")
+	}
+	if required {
+		add("required", "")
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// flatten returns a copy of the schema with its local "$ref" and "allOf"
+// merged into it, together with the references followed so far. References
+// that are already being followed are left out, to not recurse forever, and
+// non-local references are kept as-is.
+func (w valuesWriter) flatten(ptr Ptr, schema *Schema, refs []string) (*Schema, []string, error) {
+	if schema == nil {
+		return &Schema{}, refs, nil
+	}
+	if schema.Kind().IsBool() {
+		return schema, refs, nil
+	}
+
+	merged := &Schema{}
+	if strings.HasPrefix(schema.Ref, "#") && !slices.Contains(refs, schema.Ref) {
+		target, err := w.resolveRef(schema.Ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", ptr.Prop("$ref"), err)
+		}
+		resolved, resolvedRefs, err := w.flatten(ptr, target, append(slices.Clone(refs), schema.Ref))
+		if err != nil {
+			return nil, nil, err
+		}
+		refs = resolvedRefs
+		if !resolved.Kind().IsBool() {
+			merged = resolved
+		}
+	}
+	for i, subSchema := range schema.AllOf {
+		resolved, resolvedRefs, err := w.flatten(ptr.Prop("allOf").Item(i), subSchema, refs)
+		if err != nil {
+			return nil, nil, err
+		}
+		refs = resolvedRefs
+		if !resolved.Kind().IsBool() {
+			merged = mergeSchemas(merged, resolved)
+		}
+	}
+
+	self, err := cloneSchemaJSON(schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: clone schema: %w", ptr, err)
+	}
+	merged = mergeSchemas(merged, self)
+	merged.Ref = ""
+	if !strings.HasPrefix(schema.Ref, "#") {
+		merged.Ref = schema.Ref
+	}
+	merged.AllOf = nil
+	return merged, refs, nil
+}
+
+// inlineRefs replaces the subschemas inside a value, such as a map of
+// properties or a list of "anyOf", with their flattened copies, so they no
+// longer point into the "$defs" that are not part of the values file.
+func (w valuesWriter) inlineRefs(ptr Ptr, value any, refs []string) error {
+	switch value := value.(type) {
+	case *Schema:
+		if value.Kind().IsBool() {
+			return nil
+		}
+		flat, flatRefs, err := w.flatten(ptr, value, refs)
+		if err != nil {
+			return err
+		}
+		for path, subSchema := range flat.Subschemas() {
+			if err := w.inlineRefs(ptr.Add(path), subSchema, flatRefs); err != nil {
+				return err
+			}
+		}
+		*value = *flat
+	case map[string]*Schema:
+		for name, subSchema := range value {
+			if err := w.inlineRefs(ptr.Prop(name), subSchema, refs); err != nil {
+				return err
+			}
+		}
+	case []*Schema:
+		for i, subSchema := range value {
+			if err := w.inlineRefs(ptr.Item(i), subSchema, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRef returns the subschema of the root schema that a local "$ref"
+// points to, either by JSON pointer or by "$anchor".
+func (w valuesWriter) resolveRef(ref string) (*Schema, error) {
+	fragment := strings.TrimPrefix(ref, "#")
+	if isAnchorFragment(fragment) {
+		_, schema, ok := findAnchor(w.root, fragment)
+		if !ok {
+			return nil, fmt.Errorf("no $anchor or $dynamicAnchor named %q found in $ref=%q", fragment, ref)
+		}
+		return schema, nil
+	}
+	ptr := ParsePtr(fragment)
+	resolved := ptr.Resolve(w.root)
+	if len(resolved) == 0 || !resolved[len(resolved)-1].Ptr.Equals(ptr) {
+		return nil, fmt.Errorf("no schema found at $ref=%q", ref)
+	}
+	return resolved[len(resolved)-1].Schema, nil
+}
+
+// encode returns the value as YAML lines, using the writer's indentation.
+func (w valuesWriter) encode(value any) ([]string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(w.indent)
+	if err := enc.Encode(value); err != nil {
+		return nil, fmt.Errorf("encode value: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode value: %w", err)
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"), nil
+}
+
+// valueFromSchema returns the value to write for a key, or nested=true if
+// the key should instead be written as a map of its properties.
+func valueFromSchema(schema *Schema) (value any, nested bool) {
+	switch {
+	case schema.Kind().IsBool():
+		return nil, false
+	case schema.Default != nil:
+		return schema.Default, false
+	case schema.Const != nil:
+		return schema.Const, false
+	case len(schema.Enum) > 0:
+		return schema.Enum[0], false
+	case len(schema.Examples) > 0:
+		return schema.Examples[0], false
+	case len(schema.Properties) > 0:
+		return nil, true
+	}
+
+	var types []string
+	switch typ := schema.Type.(type) {
+	case string:
+		types = []string{typ}
+	case []any:
+		for _, t := range typ {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	if len(types) == 0 || slices.Contains(types, "null") {
+		return nil, false
+	}
+	switch types[0] {
+	case "string":
+		return "", false
+	case "integer", "number":
+		return 0, false
+	case "boolean":
+		return false, false
+	case "array":
+		return []any{}, false
+	case "object":
+		return map[string]any{}, false
+	default:
+		return nil, false
+	}
+}
+
+// inferredValueType returns the type that generating a schema infers from
+// the value, which then doesn't need a "type" annotation.
+func inferredValueType(value any, nested bool) string {
+	if nested {
+		return "object"
+	}
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int:
+		return "integer"
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return "integer"
+		}
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return ""
+	}
+}
+
+// typeAnnotation returns the "type" keyword as used in the "type" and "item"
+// annotations, such as "string" or "[string, null]".
+func typeAnnotation(typ any) string {
+	switch typ := typ.(type) {
+	case string:
+		return typ
+	case []any:
+		types := make([]string, len(typ))
+		for i, t := range typ {
+			types[i] = fmt.Sprint(t)
+		}
+		return "[" + strings.Join(types, ", ") + "]"
+	default:
+		return ""
+	}
+}
+
+// marshalCompactJSON returns the value as single-line JSON, without escaping
+// HTML characters, which is also valid YAML as used in annotations.
+func marshalCompactJSON(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
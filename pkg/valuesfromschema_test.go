@@ -0,0 +1,506 @@
+package pkg
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuesFromSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		indent  int
+		want    string
+		wantErr string
+	}{
+		{
+			name: "values from keywords",
+			schema: `{
+				"required": ["a", "b", "c", "d", "e", "f"],
+				"properties": {
+					"a": {"type": "string", "default": "foo"},
+					"b": {"const": 3},
+					"c": {"type": "string", "enum": ["x", "y"]},
+					"d": {"type": "string", "examples": ["nginx", "httpd"]},
+					"e": {"type": "boolean", "default": false},
+					"f": {"default": {"foo": ["bar"]}}
+				}
+			}`,
+			want: `# @schema default: "foo"; required
+a: foo
+
+# @schema const: 3; required
+b: 3
+
+# @schema enum: ["x","y"]; required
+c: x
+
+# @schema examples: ["nginx","httpd"]; required
+d: nginx
+
+# @schema default: false; required
+e: false
+
+# @schema default: {"foo":["bar"]}; required
+f:
+  foo:
+    - bar
+`,
+		},
+		{
+			name: "placeholders by type",
+			schema: `{
+				"required": ["a", "b", "c", "d", "e", "f", "g", "h"],
+				"properties": {
+					"a": {"type": "string"},
+					"b": {"type": "integer"},
+					"c": {"type": "number"},
+					"d": {"type": "boolean"},
+					"e": {"type": "array"},
+					"f": {"type": "object"},
+					"g": {"type": ["string", "null"]},
+					"h": {}
+				}
+			}`,
+			want: `# @schema required
+a: ""
+
+# @schema required
+b: 0
+
+# @schema type: number; required
+c: 0
+
+# @schema required
+d: false
+
+# @schema required
+e: []
+
+# @schema required
+f: {}
+
+# @schema type: [string, null]; required
+g: null
+
+# @schema required
+h: null
+`,
+		},
+		{
+			name: "optional keys are commented out",
+			schema: `{
+				"required": ["image"],
+				"properties": {
+					"image": {
+						"type": "object",
+						"properties": {
+							"tag": {"type": "string", "description": "Image tag"}
+						}
+					},
+					"resources": {
+						"type": "object",
+						"required": ["limits"],
+						"properties": {
+							"limits": {"type": "object", "description": "Resource limits"},
+							"requests": {"type": "object"}
+						}
+					}
+				}
+			}`,
+			want: `# @schema required
+image:
+  # # -- Image tag
+  # tag: ""
+
+# resources:
+#   # @schema required
+#   # -- Resource limits
+#   limits: {}
+
+#   # requests: {}
+`,
+		},
+		{
+			name: "descriptions and annotations",
+			schema: `{
+				"required": ["a", "b", "c"],
+				"properties": {
+					"a": {
+						"type": "string",
+						"title": "A",
+						"description": "First line.\n\nSecond line.",
+						"x-section": "General",
+						"pattern": "^[a-z]+$",
+						"minLength": 1,
+						"maxLength": 10,
+						"readOnly": true,
+						"deprecated": true
+					},
+					"b": {
+						"type": "number",
+						"multipleOf": 0.5,
+						"minimum": -1.5,
+						"maximum": 100
+					},
+					"c": {
+						"type": "array",
+						"minItems": 1,
+						"maxItems": 3,
+						"uniqueItems": true,
+						"items": {"type": "string", "enum": ["x", "y"], "pattern": "^x"}
+					}
+				}
+			}`,
+			want: `# @schema title: A; description: "First line.\n\nSecond line."; x-section: General; pattern: ^[a-z]+$; minLength: 1; maxLength: 10; readOnly; deprecated; required
+# -- First line.
+#
+# Second line.
+a: ""
+
+# @schema type: number; multipleOf: 0.5; minimum: -1.5; maximum: 100; required
+b: 0
+
+# @schema minItems: 1; maxItems: 3; uniqueItems; item: string; itemEnum: ["x","y"]; itemPattern: ^x; required
+c: []
+`,
+		},
+		{
+			name: "object annotations",
+			schema: `{
+				"required": ["a", "b"],
+				"properties": {
+					"a": {
+						"type": "object",
+						"minProperties": 1,
+						"maxProperties": 2,
+						"patternProperties": {"^x-": {"$ref": "#/$defs/str"}},
+						"additionalProperties": false
+					},
+					"b": {
+						"type": "object",
+						"unevaluatedProperties": false,
+						"anyOf": [{"$ref": "#/$defs/str"}, {"type": "integer"}],
+						"oneOf": [{"type": "object"}],
+						"not": {"type": "null"}
+					}
+				},
+				"$defs": {
+					"str": {"type": "string"}
+				}
+			}`,
+			want: `# @schema minProperties: 1; maxProperties: 2; patternProperties: {"^x-":{"type":"string"}}; additionalProperties: false; required
+a: {}
+
+# @schema unevaluatedProperties: false; anyOf: [{"type":"string"},{"type":"integer"}]; oneOf: [{"type":"object"}]; not: {"type":"null"}; required
+b: {}
+`,
+		},
+		{
+			name: "items with properties",
+			schema: `{
+				"required": ["env"],
+				"properties": {
+					"env": {
+						"type": "array",
+						"items": {"$ref": "#/$defs/env"}
+					}
+				},
+				"$defs": {
+					"env": {
+						"type": "object",
+						"required": ["name"],
+						"properties": {
+							"name": {"type": "string"},
+							"value": {"$ref": "#/$defs/str"}
+						}
+					},
+					"str": {"type": "string"}
+				}
+			}`,
+			want: `# @schema item: object; itemRequired: ["name"]; itemProperties: {"name":{"type":"string"},"value":{"type":"string"}}; required
+env: []
+`,
+		},
+		{
+			name: "ref and allOf are merged",
+			schema: `{
+				"$ref": "#/$defs/root",
+				"properties": {
+					"b": {
+						"allOf": [
+							{"$ref": "#/$defs/str"},
+							{"minLength": 1}
+						],
+						"description": "B"
+					}
+				},
+				"$defs": {
+					"root": {
+						"required": ["a", "b"],
+						"properties": {
+							"a": {"$ref": "#/$defs/str", "default": "foo"}
+						}
+					},
+					"str": {"type": "string", "description": "A string"}
+				}
+			}`,
+			want: `# @schema default: "foo"; required
+# -- A string
+a: foo
+
+# @schema minLength: 1; required
+# -- B
+b: ""
+`,
+		},
+		{
+			name: "anchor ref",
+			schema: `{
+				"required": ["a"],
+				"properties": {
+					"a": {"$ref": "#str"}
+				},
+				"$defs": {
+					"str": {"$anchor": "str", "type": "string"}
+				}
+			}`,
+			want: `# @schema required
+a: ""
+`,
+		},
+		{
+			name: "recursive ref is followed once",
+			schema: `{
+				"required": ["node"],
+				"properties": {
+					"node": {"$ref": "#/$defs/node"}
+				},
+				"$defs": {
+					"node": {
+						"type": "object",
+						"required": ["child"],
+						"properties": {
+							"child": {"$ref": "#/$defs/node"}
+						}
+					}
+				}
+			}`,
+			want: `# @schema required
+node:
+  # @schema required
+  child: null
+`,
+		},
+		{
+			name: "bool schemas",
+			schema: `{
+				"required": ["a", "b"],
+				"properties": {
+					"a": true,
+					"b": false
+				}
+			}`,
+			want: `# @schema required
+a: null
+`,
+		},
+		{
+			name: "quoted keys",
+			schema: `{
+				"required": ["a: b", "kubernetes.io/name"],
+				"properties": {
+					"a: b": {"type": "string"},
+					"kubernetes.io/name": {"type": "string"}
+				}
+			}`,
+			want: `# @schema required
+'a: b': ""
+
+# @schema required
+kubernetes.io/name: ""
+`,
+		},
+		{
+			name: "semicolon in annotation is skipped",
+			schema: `{
+				"required": ["a"],
+				"properties": {
+					"a": {"type": "string", "pattern": "^a;b$", "minLength": 1}
+				}
+			}`,
+			want: `# @schema minLength: 1; required
+a: ""
+`,
+		},
+		{
+			name: "non-local ref is kept",
+			schema: `{
+				"required": ["a"],
+				"properties": {
+					"a": {"$ref": "https://example.com/schema.json", "description": "A"},
+					"b": {"type": "object", "anyOf": [{"$ref": "foo.json"}]},
+					"c": {"type": "array", "items": {"$ref": "foo.json"}}
+				}
+			}`,
+			want: `# @schema $ref: https://example.com/schema.json; required
+# -- A
+a: null
+
+# # @schema anyOf: [{"$ref":"foo.json"}]
+# b: {}
+
+# # @schema itemRef: foo.json
+# c: []
+`,
+		},
+		{
+			name: "custom indent",
+			schema: `{
+				"required": ["a"],
+				"properties": {
+					"a": {"type": "object", "properties": {"b": {"type": "array", "default": [1]}}}
+				}
+			}`,
+			indent: 4,
+			want: `# @schema required
+a:
+    # # @schema default: [1]
+    # b:
+    #     - 1
+`,
+		},
+		{
+			name:    "no properties",
+			schema:  `{"type": "object"}`,
+			wantErr: "schema has no properties to generate values from",
+		},
+		{
+			name:    "invalid indent",
+			schema:  `{"properties": {"a": {}}}`,
+			indent:  -2,
+			wantErr: "indentation must be a positive number",
+		},
+		{
+			name:    "missing ref",
+			schema:  `{"properties": {"a": {"$ref": "#/$defs/foo"}}}`,
+			wantErr: `/a/$ref: no schema found at $ref="#/$defs/foo"`,
+		},
+		{
+			name:    "missing anchor",
+			schema:  `{"properties": {"a": {"$ref": "#foo"}}}`,
+			wantErr: `/a/$ref: no $anchor or $dynamicAnchor named "foo" found in $ref="#foo"`,
+		},
+		{
+			name:    "missing ref in annotation",
+			schema:  `{"properties": {"a": {"anyOf": [{"$ref": "#/$defs/foo"}]}}}`,
+			wantErr: `/a/anyOf/0/$ref: no schema found at $ref="#/$defs/foo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema Schema
+			require.NoError(t, json.Unmarshal([]byte(tt.schema), &schema))
+
+			indent := tt.indent
+			if indent == 0 {
+				indent = 2
+			}
+			got, err := ValuesFromSchema(ContextWithLogger(t.Context(), t), &schema, indent)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestValuesFromSchema_RoundTrip(t *testing.T) {
+	properties := `{
+		"image": {
+			"type": "object",
+			"required": ["pullPolicy", "repository", "tag"],
+			"properties": {
+				"pullPolicy": {
+					"description": "Image pull policy",
+					"type": "string",
+					"enum": ["IfNotPresent", "Always", "Never"]
+				},
+				"repository": {
+					"description": "Image repository",
+					"examples": ["nginx"],
+					"type": "string",
+					"minLength": 1
+				},
+				"tag": {
+					"description": "Image tag.\n\nDefaults to the chart appVersion.",
+					"type": "string"
+				}
+			}
+		},
+		"nodeSelector": {
+			"type": "object",
+			"additionalProperties": {"type": "string"}
+		},
+		"port": {
+			"type": ["integer", "null"]
+		},
+		"replicaCount": {
+			"description": "Number of replicas",
+			"default": 1,
+			"type": "integer",
+			"minimum": 1
+		},
+		"tolerations": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["key"],
+				"properties": {
+					"key": {"type": "string"}
+				}
+			}
+		}
+	}`
+	// References into "$defs" are inlined, so compare with the inlined schema
+	var schema Schema
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"required": ["image", "nodeSelector", "port", "replicaCount", "tolerations"],
+		"properties": `+strings.Replace(properties, `"port": {
+			"type": ["integer", "null"]
+		}`, `"port": {"$ref": "#/$defs/port"}`, 1)+`,
+		"$defs": {
+			"port": {"type": ["integer", "null"]}
+		}
+	}`), &schema))
+	require.Equal(t, "#/$defs/port", schema.Properties["port"].Ref)
+
+	ctx := ContextWithLogger(t.Context(), t)
+	values, err := ValuesFromSchema(ctx, &schema, 2)
+	require.NoError(t, err)
+
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesFile, values, 0600))
+
+	generated, err := buildJSONSchema(ctx, &Config{
+		Values:      []string{valuesFile},
+		Draft:       2020,
+		Indent:      4,
+		UseHelmDocs: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"image", "nodeSelector", "port", "replicaCount", "tolerations"}, generated.Required)
+	got, err := json.Marshal(generated.Properties)
+	require.NoError(t, err)
+	assert.JSONEq(t, properties, string(got))
+}
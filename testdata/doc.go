@@ -9,6 +9,7 @@ package testdata
 //go:generate go run .. --values basic.yaml --output basic.schema.json
 //go:generate go run .. --values full.yaml --output full.schema.json --schema-root.id https://example.com/schema --schema-root.ref schema/product.json --schema-root.title "Helm Values Schema" --schema-root.description "Schema for Helm values" --schema-root.additional-properties=true
 //go:generate go run .. --values k8sRef.yaml --output k8sRef.schema.json --k8s-schema-version v1.33.1
+//go:generate go run .. --config refAliases.config.yaml --values refAliases.yaml --output refAliases.schema.json
 //go:generate go run .. --values meta.yaml --output meta.schema.json
 //go:generate go run .. --values noAdditionalProperties.yaml --output noAdditionalProperties.schema.json --no-additional-properties=true
 //go:generate go run .. --values ref.yaml --output ref-draft2020.schema.json --draft 2020
@@ -20,6 +21,8 @@ package testdata
 //go:generate go run .. --use-helm-docs --values helm-docs/values.yaml --output helm-docs/values.schema.json
 
 //go:generate go run .. --bundle=false --values bundle/simple.yaml --output bundle/simple-disabled.schema.json
+//go:generate go run .. --bundle=true --values bundle/chart.yaml --output bundle/chart.schema.json
+//go:generate go run .. --bundle=true --values bundle/crd.yaml --output bundle/crd.schema.json
 //go:generate go run .. --bundle=true --values bundle/fragment.yaml --output bundle/fragment-without-id.schema.json --bundle-without-id=true
 //go:generate go run .. --bundle=true --values bundle/fragment.yaml --output bundle/fragment.schema.json
 //go:generate go run .. --bundle=true --values bundle/multiple-values-1.yaml,bundle/multiple-values-2.yaml --schema-root.ref bundle/simple-subschema.schema.json --output bundle/multiple-values-without-id.schema.json --bundle-without-id=true